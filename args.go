@@ -30,6 +30,12 @@ const (
 	ArgActionType = "action-type"
 	// ArgCommandWait is a wait for a droplet to be created argument.
 	ArgCommandWait = "wait"
+	// ArgWaitFor is a wait for droplet readiness argument.
+	ArgWaitFor = "wait-for"
+	// ArgWaitTimeout is a how long to wait argument.
+	ArgWaitTimeout = "wait-timeout"
+	// ArgPrivate is a use the private interface argument.
+	ArgPrivate = "private"
 	// ArgDomainName is a domain name argument.
 	ArgDomainName = "domain-name"
 	// ArgDropletID is a droplet id argument.
@@ -86,6 +92,12 @@ const (
 	ArgUserData = "user-data"
 	// ArgUserDataFile is a user data file location argument.
 	ArgUserDataFile = "user-data-file"
+	// ArgUserDataTemplate is a user data template location argument.
+	ArgUserDataTemplate = "user-data-template"
+	// ArgVar is a template variable argument.
+	ArgVar = "var"
+	// ArgVarFile is a template variable file location argument.
+	ArgVarFile = "var-file"
 	// ArgImageName name is an image name argument.
 	ArgImageName = "image-name"
 	// ArgKey is a key argument.
@@ -96,6 +108,10 @@ const (
 	ArgKeyPublicKey = "public-key"
 	// ArgKeyPublicKeyFile is a public key file argument.
 	ArgKeyPublicKeyFile = "public-key-file"
+	// ArgKeyType is a key type argument.
+	ArgKeyType = "type"
+	// ArgKeyBits is a key size argument.
+	ArgKeyBits = "bits"
 	// ArgSSHUser is a SSH user argument.
 	ArgSSHUser = "ssh-user"
 	// ArgFormat is columns to include in output argment.
@@ -104,6 +120,64 @@ const (
 	ArgNoHeader = "no-header"
 	// ArgPollTime is how long before the next poll argument.
 	ArgPollTime = "poll-timeout"
+	// ArgAllInProgress is a select all in progress actions argument.
+	ArgAllInProgress = "all-in-progress"
+	// ArgGroupBy is a group report rows argument.
+	ArgGroupBy = "group-by"
+	// ArgAddress is an address type argument.
+	ArgAddress = "address"
+	// ArgOutFile is a file to write output to argument.
+	ArgOutFile = "out-file"
+	// ArgManifestFile is a manifest file argument.
+	ArgManifestFile = "file"
+	// ArgPrune is a remove unlisted resources argument.
+	ArgPrune = "prune"
+	// ArgTagName is a tag name argument.
+	ArgTagName = "tag"
+	// ArgNameTemplate is a name template argument.
+	ArgNameTemplate = "name-template"
+	// ArgMatch is a name pattern argument.
+	ArgMatch = "match"
+	// ArgKeep is a number of resources to keep argument.
+	ArgKeep = "keep"
+	// ArgOlderThan is a minimum age argument.
+	ArgOlderThan = "older-than"
+	// ArgCleanup is a delete intermediate resources argument.
+	ArgCleanup = "cleanup"
+	// ArgForce is a skip confirmation argument.
+	ArgForce = "force"
+	// ArgParallel is a number of concurrent operations argument.
+	ArgParallel = "parallel"
+	// ArgFailFast is a stop after the first failure argument.
+	ArgFailFast = "fail-fast"
+	// ArgRecursive is a copy directories argument.
+	ArgRecursive = "recursive"
+	// ArgLocalForward is a local port forward argument.
+	ArgLocalForward = "local-forward"
+	// ArgRemoteForward is a remote port forward argument.
+	ArgRemoteForward = "remote-forward"
+	// ArgSOCKS is a SOCKS proxy port argument.
+	ArgSOCKS = "socks"
+	// ArgBackground is a keep tunnels up without a shell argument.
+	ArgBackground = "background"
+	// ArgSSHInternal is a use the internal ssh client argument.
+	ArgSSHInternal = "ssh-internal"
+	// ArgIdentityFile is an extra private ssh key argument.
+	ArgIdentityFile = "identity-file"
+	// ArgKnownHostsFile is a known_hosts file argument.
+	ArgKnownHostsFile = "known-hosts-file"
+	// ArgAcceptNewHostKeys is a trust hosts seen for the first time argument.
+	ArgAcceptNewHostKeys = "accept-new-host-keys"
+	// ArgIPv6Address is a use the IPv6 interface argument.
+	ArgIPv6Address = "ipv6"
+	// ArgJump is a bastion host argument.
+	ArgJump = "jump"
+	// ArgSkipExisting is a leave existing resources argument.
+	ArgSkipExisting = "skip-existing"
+	// ArgRecord is a session recording file argument.
+	ArgRecord = "record"
+	// ArgDryRun is a show what would happen argument.
+	ArgDryRun = "dry-run"
 
 	// ArgOutput is an output type argument.
 	ArgOutput = "output"
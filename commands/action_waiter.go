@@ -0,0 +1,305 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/pkg/term"
+)
+
+const (
+	actionInProgress = "in-progress"
+	actionErrored    = "errored"
+)
+
+var (
+	// actionSleep pauses between polls for action status.
+	actionSleep = time.Sleep
+
+	// maxActionPollInterval caps the backoff between polls.
+	maxActionPollInterval = 30 * time.Second
+
+	// progressIsTerminal returns true if progress can be redrawn in place.
+	progressIsTerminal = func() bool {
+		return term.IsTerminal(os.Stderr.Fd())
+	}
+
+	// spinnerInterval is how often the terminal spinner is redrawn.
+	spinnerInterval = 100 * time.Millisecond
+
+	spinnerFrames = []string{"|", "/", "-", "\\"}
+)
+
+// actionWaitOpt adds the flags used by actionWaiter to a command.
+func actionWaitOpt() cmdOption {
+	return func(c *Command) {
+		AddBoolFlag(c, doit.ArgCommandWait, false, "Wait for action to complete")
+		AddIntFlag(c, doit.ArgWaitTimeout, 0, "Seconds to wait for action to complete, 0 waits forever")
+	}
+}
+
+// actionWaiter polls actions until they are no longer in progress.
+type actionWaiter struct {
+	as       do.ActionsService
+	interval time.Duration
+	timeout  time.Duration
+	progress actionProgress
+}
+
+func newActionWaiter(c *CmdConfig, pollTime int) (*actionWaiter, error) {
+	timeout, err := c.Doit.GetInt(c.NS, doit.ArgWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if pollTime < 1 {
+		pollTime = 1
+	}
+
+	var progress actionProgress = &ndjsonProgress{out: progressOut, seen: map[int]string{}}
+	if progressIsTerminal() {
+		progress = &spinnerProgress{out: progressOut}
+	}
+
+	return &actionWaiter{
+		as:       c.Actions(),
+		interval: time.Duration(pollTime) * time.Second,
+		timeout:  time.Duration(timeout) * time.Second,
+		progress: progress,
+	}, nil
+}
+
+// waitIfRequested waits for actions if the command's --wait flag is set.
+// Otherwise the actions are returned unchanged.
+func waitIfRequested(c *CmdConfig, actions do.Actions) (do.Actions, error) {
+	wait, err := c.Doit.GetBool(c.NS, doit.ArgCommandWait)
+	if err != nil || !wait {
+		return actions, err
+	}
+
+	w, err := newActionWaiter(c, 5)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []int
+	for _, a := range actions {
+		ids = append(ids, a.ID)
+	}
+
+	return w.wait(ids...)
+}
+
+// actionErroredErr is returned by wait when an action finished with an
+// error, as opposed to the wait failing or timing out.
+type actionErroredErr struct {
+	actions []string
+}
+
+func (e *actionErroredErr) Error() string {
+	return fmt.Sprintf("action errored: %s", strings.Join(e.actions, ", "))
+}
+
+// wait polls the actions until none are in progress. The time between
+// polls doubles up to maxActionPollInterval. An error is returned if the
+// timeout passes or any action errored.
+func (w *actionWaiter) wait(ids ...int) (do.Actions, error) {
+	start := time.Now()
+	interval := w.interval
+	actions := make(do.Actions, len(ids))
+
+	for {
+		pending := 0
+		for i, id := range ids {
+			if actions[i].Action != nil && actions[i].Status != actionInProgress {
+				continue
+			}
+
+			a, err := w.as.Get(id)
+			if err != nil {
+				return nil, err
+			}
+
+			actions[i] = *a
+			if a.Status == actionInProgress {
+				pending++
+			}
+		}
+
+		w.progress.update(actions, since(start))
+
+		if pending == 0 {
+			break
+		}
+
+		if w.timeout > 0 && time.Since(start) >= w.timeout {
+			w.progress.done()
+			return nil, fmt.Errorf("timed out after %s waiting for %d of %d actions to complete",
+				since(start), pending, len(ids))
+		}
+
+		actionSleep(interval)
+
+		interval *= 2
+		if interval > maxActionPollInterval {
+			interval = maxActionPollInterval
+		}
+	}
+
+	w.progress.done()
+
+	var errored []string
+	for _, a := range actions {
+		if a.Status == actionErrored {
+			errored = append(errored, describeAction(a))
+		}
+	}
+
+	if len(errored) > 0 {
+		return nil, &actionErroredErr{actions: errored}
+	}
+
+	return actions, nil
+}
+
+func describeAction(a do.Action) string {
+	return fmt.Sprintf("%d (%s on %s %d)", a.ID, a.Type, a.ResourceType, a.ResourceID)
+}
+
+// actionProgress reports the state of actions being waited on.
+type actionProgress interface {
+	update(actions do.Actions, elapsed time.Duration)
+	done()
+}
+
+// spinnerProgress redraws a single status line on a terminal. The line is
+// redrawn every spinnerInterval so the spinner keeps moving between polls.
+type spinnerProgress struct {
+	out io.Writer
+
+	mu      sync.Mutex
+	frame   int
+	pending int
+	total   int
+	started time.Time
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+var _ actionProgress = &spinnerProgress{}
+
+func (p *spinnerProgress) update(actions do.Actions, elapsed time.Duration) {
+	pending := 0
+	for _, a := range actions {
+		if a.Status == actionInProgress {
+			pending++
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.pending, p.total = pending, len(actions)
+	p.started = time.Now().Add(-elapsed)
+	p.draw(elapsed)
+
+	if p.stop == nil {
+		p.stop, p.stopped = make(chan struct{}), make(chan struct{})
+		go p.spin()
+	}
+}
+
+// spin redraws the status line until done is called.
+func (p *spinnerProgress) spin() {
+	defer close(p.stopped)
+
+	ticker := time.NewTicker(spinnerInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.mu.Lock()
+			p.draw(since(p.started))
+			p.mu.Unlock()
+		}
+	}
+}
+
+// draw writes the status line. p.mu must be held.
+func (p *spinnerProgress) draw(elapsed time.Duration) {
+	fmt.Fprintf(p.out, "\r\x1b[K%s waiting for %d of %d actions (%s)",
+		spinnerFrames[p.frame%len(spinnerFrames)], p.pending, p.total, elapsed)
+	p.frame++
+}
+
+func (p *spinnerProgress) done() {
+	if p.stop != nil {
+		close(p.stop)
+		<-p.stopped
+		p.stop = nil
+	}
+
+	fmt.Fprint(p.out, "\r\x1b[K")
+}
+
+// ndjsonProgress writes a JSON object per line each time an action's
+// status changes, for consumption by other programs.
+type ndjsonProgress struct {
+	out  io.Writer
+	seen map[int]string
+}
+
+var _ actionProgress = &ndjsonProgress{}
+
+type actionEvent struct {
+	ID           int    `json:"id"`
+	Status       string `json:"status"`
+	Type         string `json:"type"`
+	ResourceType string `json:"resource_type"`
+	ResourceID   int    `json:"resource_id"`
+	Elapsed      string `json:"elapsed"`
+}
+
+func (p *ndjsonProgress) update(actions do.Actions, elapsed time.Duration) {
+	enc := json.NewEncoder(p.out)
+	for _, a := range actions {
+		if p.seen[a.ID] == a.Status {
+			continue
+		}
+		p.seen[a.ID] = a.Status
+
+		enc.Encode(&actionEvent{
+			ID:           a.ID,
+			Status:       a.Status,
+			Type:         a.Type,
+			ResourceType: a.ResourceType,
+			ResourceID:   a.ResourceID,
+			Elapsed:      elapsed.String(),
+		})
+	}
+}
+
+func (p *ndjsonProgress) done() {}
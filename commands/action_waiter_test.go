@@ -0,0 +1,175 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func testActionWithStatus(id int, status string) *do.Action {
+	return &do.Action{Action: &godo.Action{
+		ID: id, Status: status, Type: "power_off", ResourceType: "droplet", ResourceID: 1,
+	}}
+}
+
+func withActionWaiterStubs(terminal bool, fn func(out *bytes.Buffer, slept *[]time.Duration)) {
+	ogSleep, ogTerminal, ogOut := actionSleep, progressIsTerminal, progressOut
+	defer func() {
+		actionSleep, progressIsTerminal, progressOut = ogSleep, ogTerminal, ogOut
+	}()
+
+	var out bytes.Buffer
+	var slept []time.Duration
+
+	actionSleep = func(d time.Duration) { slept = append(slept, d) }
+	progressIsTerminal = func() bool { return terminal }
+	progressOut = &out
+
+	fn(&out, &slept)
+}
+
+func TestActionWait_Multiple(t *testing.T) {
+	withActionWaiterStubs(false, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "in-progress"), nil).Once()
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "in-progress"), nil).Once()
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "completed"), nil).Once()
+			tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil).Once()
+
+			config.Args = append(config.Args, "1", "2")
+			config.Doit.Set(config.NS, doit.ArgPollTime, 1)
+
+			err := RunCmdActionWait(config)
+			assert.NoError(t, err)
+			assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *slept)
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			assert.Len(t, lines, 3)
+			assert.Contains(t, lines[0], `"id":1,"status":"in-progress"`)
+			assert.Contains(t, lines[1], `"id":2,"status":"completed"`)
+			assert.Contains(t, lines[2], `"id":1,"status":"completed"`)
+		})
+	})
+}
+
+func TestActionWait_AllInProgress(t *testing.T) {
+	withActionWaiterStubs(true, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			list := do.Actions{*testActionWithStatus(3, "in-progress"), *testActionWithStatus(4, "completed")}
+			tm.actions.On("List").Return(list, nil)
+			tm.actions.On("Get", 3).Return(testActionWithStatus(3, "completed"), nil)
+
+			config.Doit.Set(config.NS, doit.ArgAllInProgress, true)
+
+			err := RunCmdActionWait(config)
+			assert.NoError(t, err)
+			assert.Contains(t, out.String(), "waiting for 0 of 1 actions")
+		})
+	})
+}
+
+func TestSpinnerProgress_RedrawsBetweenUpdates(t *testing.T) {
+	ogInterval := spinnerInterval
+	defer func() { spinnerInterval = ogInterval }()
+	spinnerInterval = time.Millisecond
+
+	var out bytes.Buffer
+	p := &spinnerProgress{out: &out}
+
+	p.update(do.Actions{*testActionWithStatus(1, "in-progress")}, 0)
+	time.Sleep(50 * time.Millisecond)
+	p.done()
+
+	assert.True(t, strings.Count(out.String(), "waiting for 1 of 1 actions") > 1)
+	assert.True(t, strings.HasSuffix(out.String(), "\r\x1b[K"))
+
+	drawn := out.Len()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, drawn, out.Len())
+}
+
+func TestActionWait_Errored(t *testing.T) {
+	withActionWaiterStubs(false, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "errored"), nil)
+
+			config.Args = append(config.Args, "1")
+
+			err := RunCmdActionWait(config)
+			assert.EqualError(t, err, "action errored: 1 (power_off on droplet 1)")
+		})
+	})
+}
+
+func TestActionWait_Timeout(t *testing.T) {
+	withActionWaiterStubs(false, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "in-progress"), nil)
+
+			w, err := newActionWaiter(config, 1)
+			assert.NoError(t, err)
+			w.timeout = time.Nanosecond
+
+			_, err = w.wait(1)
+			assert.Error(t, err)
+			assert.Empty(t, *slept)
+		})
+	})
+}
+
+func TestActionWait_Backoff(t *testing.T) {
+	withActionWaiterStubs(false, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "in-progress"), nil).Times(6)
+			tm.actions.On("Get", 1).Return(testActionWithStatus(1, "completed"), nil).Once()
+
+			w, err := newActionWaiter(config, 5)
+			assert.NoError(t, err)
+
+			_, err = w.wait(1)
+			assert.NoError(t, err)
+
+			expected := []time.Duration{5, 10, 20, 30, 30, 30}
+			for i := range expected {
+				expected[i] *= time.Second
+			}
+			assert.Equal(t, expected, *slept)
+		})
+	})
+}
+
+func TestDropletActionPowerOnWait(t *testing.T) {
+	withActionWaiterStubs(false, func(out *bytes.Buffer, slept *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.dropletActions.On("PowerOn", 1).Return(testActionWithStatus(5, "in-progress"), nil)
+			tm.dropletActions.On("PowerOn", 3).Return(testActionWithStatus(6, "in-progress"), nil)
+			tm.actions.On("Get", 5).Return(testActionWithStatus(5, "completed"), nil)
+			tm.actions.On("Get", 6).Return(testActionWithStatus(6, "completed"), nil)
+
+			config.Args = append(config.Args, "1", "3")
+			config.Doit.Set(config.NS, doit.ArgCommandWait, true)
+
+			err := RunDropletActionPowerOn(config)
+			assert.NoError(t, err)
+		})
+	})
+}
@@ -14,6 +14,7 @@ limitations under the License.
 package commands
 
 import (
+	"fmt"
 	"sort"
 	"strconv"
 	"time"
@@ -47,9 +48,11 @@ func Actions() *Command {
 	AddStringFlag(cmdActionList, doit.ArgActionStatus, "", "Action status")
 	AddStringFlag(cmdActionList, doit.ArgActionType, "", "Action type")
 
-	cmdActionWait := CmdBuilder(cmd, RunCmdActionWait, "wait ACTIONID", "wait for action to complete", Writer,
+	cmdActionWait := CmdBuilder(cmd, RunCmdActionWait, "wait [ACTIONID ...]", "wait for actions to complete", Writer,
 		aliasOpt("w"), displayerType(&action{}), docCategories("action"))
-	AddIntFlag(cmdActionWait, doit.ArgPollTime, 5, "Re-poll time in seconds")
+	AddIntFlag(cmdActionWait, doit.ArgPollTime, 5, "Initial re-poll time in seconds")
+	AddIntFlag(cmdActionWait, doit.ArgWaitTimeout, 0, "Seconds to wait for actions to complete, 0 waits forever")
+	AddBoolFlag(cmdActionWait, doit.ArgAllInProgress, false, "Wait for all actions which are in progress")
 
 	return cmd
 }
@@ -193,48 +196,52 @@ func RunCmdActionGet(c *CmdConfig) error {
 	return c.Display(&action{actions: do.Actions{*a}})
 }
 
-// RunCmdActionWait waits for an action to complete or error.
+// RunCmdActionWait waits for actions to complete or error.
 func RunCmdActionWait(c *CmdConfig) error {
-	if len(c.Args) != 1 {
-		return doit.NewMissingArgsErr(c.NS)
-	}
-
-	id, err := strconv.Atoi(c.Args[0])
+	allInProgress, err := c.Doit.GetBool(c.NS, doit.ArgAllInProgress)
 	if err != nil {
 		return err
 	}
 
+	var ids []int
+	for _, arg := range c.Args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return fmt.Errorf("invalid action id %q", arg)
+		}
+
+		ids = append(ids, id)
+	}
+
+	if allInProgress {
+		actions, err := c.Actions().List()
+		if err != nil {
+			return err
+		}
+
+		for _, a := range actions {
+			if a.Status == actionInProgress {
+				ids = append(ids, a.ID)
+			}
+		}
+	} else if len(ids) == 0 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
 	pollTime, err := c.Doit.GetInt(c.NS, doit.ArgPollTime)
 	if err != nil {
 		return err
 	}
 
-	a, err := actionWait(c, id, pollTime)
+	w, err := newActionWaiter(c, pollTime)
 	if err != nil {
 		return err
 	}
 
-	return c.Display(&action{actions: do.Actions{*a}})
-}
-
-func actionWait(c *CmdConfig, actionID, pollTime int) (*do.Action, error) {
-	as := c.Actions()
-
-	var a *do.Action
-	var err error
-
-	for {
-		a, err = as.Get(actionID)
-		if err != nil {
-			return nil, err
-		}
-
-		if a.Status != "in-progress" {
-			break
-		}
-
-		time.Sleep(time.Duration(pollTime) * time.Second)
+	actions, err := w.wait(ids...)
+	if err != nil {
+		return err
 	}
 
-	return a, nil
+	return c.Display(&action{actions: actions})
 }
@@ -15,6 +15,7 @@ package commands
 
 import (
 	"bytes"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -58,6 +59,13 @@ func (d *displayer) Display() error {
 		}
 
 		return displayText(d.item, d.out, cols)
+	case "csv":
+		cols, err := handleColumns(d.ns, d.config)
+		if err != nil {
+			return err
+		}
+
+		return displayCSV(d.item, d.out, cols)
 	default:
 		return fmt.Errorf("unknown output type")
 	}
@@ -133,3 +141,42 @@ func displayText(item Displayable, out io.Writer, includeCols []string) error {
 
 	return w.Flush()
 }
+
+func displayCSV(item Displayable, out io.Writer, includeCols []string) error {
+	w := csv.NewWriter(out)
+
+	cols := item.Cols()
+	if len(includeCols) > 0 && includeCols[0] != "" {
+		cols = includeCols
+	}
+
+	if !hc.hideHeader {
+		headers := []string{}
+		for _, k := range cols {
+			col := item.ColMap()[k]
+			if col == "" {
+				return fmt.Errorf("unknown column %q", k)
+			}
+
+			headers = append(headers, col)
+		}
+
+		if err := w.Write(headers); err != nil {
+			return err
+		}
+	}
+
+	for _, r := range item.KV() {
+		record := []string{}
+		for _, col := range cols {
+			record = append(record, fmt.Sprint(r[col]))
+		}
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -16,6 +16,7 @@ package commands
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"sort"
 	"testing"
 
@@ -23,6 +24,7 @@ import (
 	"github.com/bryanl/doit/do"
 	domocks "github.com/bryanl/doit/do/mocks"
 	"github.com/bryanl/doit/pkg/runner"
+	"github.com/bryanl/doit/pkg/ssh"
 	"github.com/digitalocean/godo"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
@@ -141,6 +143,7 @@ type tcMocks struct {
 	domains           domocks.DomainsService
 	actions           domocks.ActionsService
 	account           domocks.AccountService
+	tags              domocks.TagsService
 }
 
 func withTestClient(t *testing.T, tFn testFn) {
@@ -171,6 +174,7 @@ func withTestClient(t *testing.T, tFn testFn) {
 		Domains:           func() do.DomainsService { return &tm.domains },
 		Actions:           func() do.ActionsService { return &tm.actions },
 		Account:           func() do.AccountService { return &tm.account },
+		Tags:              func() do.TagsService { return &tm.tags },
 	}
 
 	tFn(config, tm)
@@ -187,10 +191,33 @@ func withTestClient(t *testing.T, tFn testFn) {
 	assert.True(t, tm.regions.AssertExpectations(t))
 	assert.True(t, tm.sizes.AssertExpectations(t))
 	assert.True(t, tm.keys.AssertExpectations(t))
+	assert.True(t, tm.tags.AssertExpectations(t))
+}
+
+// withTempFile gives fn the path of a temporary file holding contents.
+func withTempFile(t *testing.T, contents string, fn func(path string)) {
+	f, err := ioutil.TempFile("", "doit")
+	assert.NoError(t, err)
+	defer os.Remove(f.Name())
+
+	_, err = f.WriteString(contents)
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	fn(f.Name())
+}
+
+// withTempDir gives fn a temporary directory which is removed afterwards.
+func withTempDir(t *testing.T, fn func(dir string)) {
+	dir, err := ioutil.TempDir("", "doit")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn(dir)
 }
 
 type TestConfig struct {
-	SSHFn func(user, host, keyPath string, port int) runner.Runner
+	SSHFn func(user, host, keyPath string, port int, opts ssh.Options) runner.Runner
 	v     *viper.Viper
 }
 
@@ -198,7 +225,7 @@ var _ doit.Config = &TestConfig{}
 
 func NewTestConfig() *TestConfig {
 	return &TestConfig{
-		SSHFn: func(u, h, kp string, p int) runner.Runner {
+		SSHFn: func(u, h, kp string, p int, o ssh.Options) runner.Runner {
 			return &doit.MockRunner{}
 		},
 		v: viper.New(),
@@ -211,8 +238,8 @@ func (c *TestConfig) GetGodoClient(trace bool) *godo.Client {
 	return &godo.Client{}
 }
 
-func (c *TestConfig) SSH(user, host, keyPath string, port int) runner.Runner {
-	return c.SSHFn(user, host, keyPath, port)
+func (c *TestConfig) SSH(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
+	return c.SSHFn(user, host, keyPath, port, opts)
 }
 
 func (c *TestConfig) Set(ns, key string, val interface{}) {
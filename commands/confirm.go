@@ -0,0 +1,140 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/resolver"
+	"github.com/bryanl/doit/pkg/term"
+)
+
+var (
+	// confirmIn is where answers to confirmation prompts are read from.
+	confirmIn io.Reader = os.Stdin
+
+	// isInteractive returns true if the user can be prompted for confirmation.
+	isInteractive = func() bool {
+		return term.IsTerminal(os.Stdin.Fd())
+	}
+)
+
+// describeFn lists the resources an operation will be performed on.
+type describeFn func() ([]string, error)
+
+// destructiveOpt adds the flags used by confirmDestructive to a command.
+func destructiveOpt() cmdOption {
+	return func(c *Command) {
+		AddBoolFlag(c, doit.ArgForce, false, "Don't ask for confirmation")
+		AddBoolFlag(c, doit.ArgDryRun, false, "Show what would be done without doing it")
+	}
+}
+
+// confirmDestructive decides if a destructive operation should proceed. With
+// --dry-run the resources are listed and false is returned. With --force
+// true is returned without asking. Otherwise the user is prompted if there
+// is a terminal attached, and the operation is refused if there is not.
+func confirmDestructive(c *CmdConfig, operation, resourceType string, describe describeFn) (bool, error) {
+	dryRun, err := c.Doit.GetBool(c.NS, doit.ArgDryRun)
+	if err != nil {
+		return false, err
+	}
+
+	force, err := c.Doit.GetBool(c.NS, doit.ArgForce)
+	if err != nil {
+		return false, err
+	}
+
+	if force && !dryRun {
+		return true, nil
+	}
+
+	if !dryRun && !isInteractive() {
+		return false, fmt.Errorf("refusing to %s %s without --%s when not running interactively",
+			operation, resourceType, doit.ArgForce)
+	}
+
+	resources, err := describe()
+	if err != nil {
+		return false, err
+	}
+
+	if dryRun {
+		fmt.Fprintf(c.Out, "would %s %s:\n", operation, resourceType)
+		for _, r := range resources {
+			fmt.Fprintf(c.Out, "  %s\n", r)
+		}
+
+		return false, nil
+	}
+
+	fmt.Fprintf(progressOut, "about to %s %s:\n", operation, resourceType)
+	for _, r := range resources {
+		fmt.Fprintf(progressOut, "  %s\n", r)
+	}
+	fmt.Fprint(progressOut, "are you sure? [y/N] ")
+
+	answer, err := bufio.NewReader(confirmIn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true, nil
+	default:
+		fmt.Fprintln(progressOut, "aborted")
+		return false, nil
+	}
+}
+
+// staticDescription describes resources which need no lookup.
+func staticDescription(resources ...string) describeFn {
+	return func() ([]string, error) {
+		return resources, nil
+	}
+}
+
+// describeDroplets describes droplets, loading any that were selected by id
+// alone.
+func describeDroplets(r *resolver.Resolver, droplets do.Droplets) describeFn {
+	return func() ([]string, error) {
+		loaded, err := r.LoadDroplets(droplets)
+		if err != nil {
+			return nil, err
+		}
+
+		var out []string
+		for i := range loaded {
+			out = append(out, describeDroplet(&loaded[i]))
+		}
+
+		return out, nil
+	}
+}
+
+func describeDroplet(d *do.Droplet) string {
+	ip, _ := d.PublicIPv4()
+	if ip == "" {
+		return fmt.Sprintf("%s (%d)", d.Name, d.ID)
+	}
+
+	return fmt.Sprintf("%s (%d, %s)", d.Name, d.ID, ip)
+}
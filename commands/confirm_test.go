@@ -0,0 +1,91 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/stretchr/testify/assert"
+)
+
+func withConfirmation(interactive bool, answer string, fn func()) {
+	ogInteractive, ogIn, ogOut := isInteractive, confirmIn, progressOut
+	defer func() {
+		isInteractive, confirmIn, progressOut = ogInteractive, ogIn, ogOut
+	}()
+
+	isInteractive = func() bool { return interactive }
+	confirmIn = strings.NewReader(answer)
+	progressOut = ioutil.Discard
+
+	fn()
+}
+
+func TestConfirmDestructive_DryRun(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+
+		var out bytes.Buffer
+		config.Out = &out
+		config.Args = append(config.Args, testDroplet.Name)
+		config.Doit.Set(config.NS, doit.ArgDryRun, true)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
+
+		err := RunDropletDelete(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "would delete droplets:\n  a-droplet (1, 8.8.8.8)\n", out.String())
+	})
+}
+
+func TestConfirmDestructive_NotInteractive(t *testing.T) {
+	withConfirmation(false, "", func() {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			config.Args = append(config.Args, "1")
+
+			err := RunDropletDelete(config)
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestConfirmDestructive_Accepted(t *testing.T) {
+	withConfirmation(true, "y\n", func() {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.droplets.On("Delete", 1).Return(nil)
+
+			config.Args = append(config.Args, "1")
+
+			err := RunDropletDelete(config)
+			assert.NoError(t, err)
+		})
+	})
+}
+
+func TestConfirmDestructive_Declined(t *testing.T) {
+	withConfirmation(true, "\n", func() {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, "1")
+
+			err := RunDropletActionPowerOff(config)
+			assert.NoError(t, err)
+		})
+	})
+}
@@ -0,0 +1,228 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+)
+
+const (
+	costByRegion = "region"
+	costBySize   = "size"
+	costByTag    = "tag"
+	costByPrefix = "prefix"
+
+	// backupSurcharge is the fraction of a droplet's price charged for backups.
+	backupSurcharge = 0.2
+
+	untaggedGroup = "(untagged)"
+	totalGroup    = "total"
+)
+
+// prefixSuffixRE matches the numbering at the end of a droplet name.
+var prefixSuffixRE = regexp.MustCompile(`[-_.]?\d+$`)
+
+// costLine is the projected monthly spend for a group of droplets.
+type costLine struct {
+	Group    string  `json:"group"`
+	Droplets int     `json:"droplets"`
+	Monthly  float64 `json:"monthly"`
+	Backups  float64 `json:"backups"`
+	Total    float64 `json:"total"`
+}
+
+func (l *costLine) add(monthly, backups float64) {
+	l.Droplets++
+	l.Monthly += monthly
+	l.Backups += backups
+	l.Total += monthly + backups
+}
+
+// Cost creates the cost command.
+func Cost() *Command {
+	cmd := CmdBuilder(nil, RunCost, "cost", "projected monthly cost of droplets", Writer,
+		displayerType(&costReport{}), docCategories("compute"))
+	AddStringFlag(cmd, doit.ArgGroupBy, costByRegion,
+		fmt.Sprintf("Group costs by %s, %s, %s or %s", costByRegion, costBySize, costByTag, costByPrefix))
+
+	return cmd
+}
+
+// RunCost reports the projected monthly cost of droplets by joining the
+// droplet list with the size list. Backups add a surcharge to a droplet's
+// price. When grouping by tag, droplets with several tags are counted in
+// each of their groups, but only once in the total.
+func RunCost(c *CmdConfig) error {
+	groupBy, err := c.Doit.GetString(c.NS, doit.ArgGroupBy)
+	if err != nil {
+		return err
+	}
+
+	switch groupBy {
+	case costByRegion, costBySize, costByTag, costByPrefix:
+	default:
+		return fmt.Errorf("unknown %s value %q", doit.ArgGroupBy, groupBy)
+	}
+
+	droplets, err := c.Droplets().List()
+	if err != nil {
+		return err
+	}
+
+	sizes, err := c.Sizes().List()
+	if err != nil {
+		return err
+	}
+
+	prices := map[string]float64{}
+	for _, s := range sizes {
+		prices[s.Slug] = s.PriceMonthly
+	}
+
+	var tags map[int][]string
+	if groupBy == costByTag {
+		tags, err = dropletTags(c.Tags(), c.Droplets())
+		if err != nil {
+			return err
+		}
+	}
+
+	groups := map[string]*costLine{}
+	total := costLine{Group: totalGroup}
+
+	for _, d := range droplets {
+		monthly, ok := dropletPrice(d, prices)
+		if !ok {
+			fmt.Fprintf(progressOut, "no price found for size %q of droplet %s\n", d.SizeSlug, d.Name)
+		}
+
+		var backups float64
+		if backupsEnabled(d) {
+			backups = monthly * backupSurcharge
+		}
+
+		total.add(monthly, backups)
+
+		for _, g := range costGroups(groupBy, d, tags) {
+			l, ok := groups[g]
+			if !ok {
+				l = &costLine{Group: g}
+				groups[g] = l
+			}
+
+			l.add(monthly, backups)
+		}
+	}
+
+	report := &costReport{total: total}
+	for _, l := range groups {
+		report.lines = append(report.lines, *l)
+	}
+	sort.Sort(costLinesByGroup(report.lines))
+
+	return c.Display(report)
+}
+
+// dropletPrice returns the monthly price of a droplet's size.
+func dropletPrice(d do.Droplet, prices map[string]float64) (float64, bool) {
+	slug := d.SizeSlug
+	if slug == "" && d.Size != nil {
+		slug = d.Size.Slug
+	}
+
+	if p, ok := prices[slug]; ok {
+		return p, true
+	}
+
+	if d.Size != nil && d.Size.PriceMonthly > 0 {
+		return d.Size.PriceMonthly, true
+	}
+
+	return 0, false
+}
+
+// backupsEnabled reports whether a droplet is paying for backups. A droplet
+// with backups turned on has none taken yet when it is new, so its features
+// are checked before its backups.
+func backupsEnabled(d do.Droplet) bool {
+	for _, f := range d.Features {
+		if f == "backups" {
+			return true
+		}
+	}
+
+	return len(d.BackupIDs) > 0
+}
+
+// costGroups returns the groups a droplet's cost belongs to.
+func costGroups(groupBy string, d do.Droplet, tags map[int][]string) []string {
+	switch groupBy {
+	case costBySize:
+		if d.SizeSlug == "" && d.Size != nil {
+			return []string{d.Size.Slug}
+		}
+		return []string{d.SizeSlug}
+	case costByTag:
+		if t := tags[d.ID]; len(t) > 0 {
+			return t
+		}
+		return []string{untaggedGroup}
+	case costByPrefix:
+		return []string{prefixSuffixRE.ReplaceAllString(d.Name, "")}
+	default:
+		if d.Region == nil {
+			return []string{""}
+		}
+		return []string{d.Region.Slug}
+	}
+}
+
+// dropletTags maps droplet ids to the names of their tags.
+func dropletTags(ts do.TagsService, ds do.DropletsService) (map[int][]string, error) {
+	list, err := ts.List()
+	if err != nil {
+		return nil, err
+	}
+
+	out := map[int][]string{}
+	for _, t := range list {
+		droplets, err := ds.ListByTag(t.Name)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, d := range droplets {
+			out[d.ID] = append(out[d.ID], t.Name)
+		}
+	}
+
+	return out, nil
+}
+
+type costLinesByGroup []costLine
+
+func (l costLinesByGroup) Len() int {
+	return len(l)
+}
+func (l costLinesByGroup) Swap(i, j int) {
+	l[i], l[j] = l[j], l[i]
+}
+func (l costLinesByGroup) Less(i, j int) bool {
+	return l[i].Group < l[j].Group
+}
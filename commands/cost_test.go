@@ -0,0 +1,151 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testCostDroplets = do.Droplets{
+		{Droplet: &godo.Droplet{ID: 1, Name: "web-1", SizeSlug: "512mb", Region: &godo.Region{Slug: "nyc1"}}},
+		{Droplet: &godo.Droplet{ID: 2, Name: "web-2", SizeSlug: "1gb", Region: &godo.Region{Slug: "nyc1"}, BackupIDs: []int{7}}},
+		{Droplet: &godo.Droplet{ID: 3, Name: "db01", SizeSlug: "1gb", Region: &godo.Region{Slug: "sfo1"}, Features: []string{"backups"}}},
+	}
+
+	testCostSizes = do.Sizes{
+		{Size: &godo.Size{Slug: "512mb", PriceMonthly: 5}},
+		{Size: &godo.Size{Slug: "1gb", PriceMonthly: 10}},
+	}
+)
+
+func TestCostCommand(t *testing.T) {
+	cmd := Cost()
+	assert.NotNil(t, cmd)
+}
+
+func runCostReport(t *testing.T, groupBy string, setup func(tm *tcMocks)) *costReport {
+	var report *costReport
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testCostDroplets, nil)
+		tm.sizes.On("List").Return(testCostSizes, nil)
+		if setup != nil {
+			setup(tm)
+		}
+
+		var out bytes.Buffer
+		config.Out = &out
+		config.Doit.Set(config.NS, doit.ArgGroupBy, groupBy)
+		config.Doit.Set(doit.NSRoot, "output", "json")
+
+		err := RunCost(config)
+		assert.NoError(t, err)
+
+		report = &costReport{}
+		assert.NoError(t, decodeCostReport(out.Bytes(), report))
+	})
+
+	return report
+}
+
+func TestCost_ByRegion(t *testing.T) {
+	report := runCostReport(t, costByRegion, nil)
+
+	assert.Equal(t, []costLine{
+		{Group: "nyc1", Droplets: 2, Monthly: 15, Backups: 2, Total: 17},
+		{Group: "sfo1", Droplets: 1, Monthly: 10, Backups: 2, Total: 12},
+	}, report.lines)
+	assert.Equal(t, costLine{Group: "total", Droplets: 3, Monthly: 25, Backups: 4, Total: 29}, report.total)
+}
+
+func TestCost_ByPrefix(t *testing.T) {
+	report := runCostReport(t, costByPrefix, nil)
+
+	assert.Equal(t, []string{"db", "web"}, costGroupNames(report))
+}
+
+func TestCost_ByTag(t *testing.T) {
+	report := runCostReport(t, costByTag, func(tm *tcMocks) {
+		tags := do.Tags{{Tag: &godo.Tag{Name: "web"}}, {Tag: &godo.Tag{Name: "prod"}}}
+		tm.tags.On("List").Return(tags, nil)
+		tm.droplets.On("ListByTag", "web").Return(testCostDroplets[:2], nil)
+		tm.droplets.On("ListByTag", "prod").Return(testCostDroplets[1:2], nil)
+	})
+
+	assert.Equal(t, []string{"(untagged)", "prod", "web"}, costGroupNames(report))
+	assert.Equal(t, 3, report.total.Droplets)
+}
+
+func TestCost_CSV(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testCostDroplets, nil)
+		tm.sizes.On("List").Return(testCostSizes, nil)
+
+		var out bytes.Buffer
+		config.Out = &out
+		config.Doit.Set(config.NS, doit.ArgGroupBy, costBySize)
+		config.Doit.Set(doit.NSRoot, "output", "csv")
+
+		err := RunCost(config)
+		assert.NoError(t, err)
+
+		expected := "Group,Droplets,Monthly,Backups,Total\n" +
+			"1gb,2,20.00,4.00,24.00\n" +
+			"512mb,1,5.00,0.00,5.00\n" +
+			"total,3,25.00,4.00,29.00\n"
+		assert.Equal(t, expected, out.String())
+	})
+}
+
+func TestCost_UnknownGroup(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Out = ioutil.Discard
+		config.Doit.Set(config.NS, doit.ArgGroupBy, "colour")
+
+		err := RunCost(config)
+		assert.Error(t, err)
+	})
+}
+
+func decodeCostReport(b []byte, report *costReport) error {
+	var decoded struct {
+		Groups []costLine `json:"groups"`
+		Total  costLine   `json:"total"`
+	}
+
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		return err
+	}
+
+	report.lines, report.total = decoded.Groups, decoded.Total
+	return nil
+}
+
+func costGroupNames(report *costReport) []string {
+	var names []string
+	for _, l := range report.lines {
+		names = append(names, l.Group)
+	}
+
+	return names
+}
@@ -21,8 +21,10 @@ import (
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/resolver"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
@@ -55,7 +57,7 @@ func init() {
 	viper.SetConfigType("yaml")
 
 	DoitCmd.PersistentFlags().StringVarP(&Token, "access-token", "t", "", "DigitalOcean API V2 Access Token")
-	DoitCmd.PersistentFlags().StringVarP(&Output, "output", "o", "text", "output formt [text|json]")
+	DoitCmd.PersistentFlags().StringVarP(&Output, "output", "o", "text", "output formt [text|json|csv]")
 	DoitCmd.PersistentFlags().BoolVarP(&Verbose, "verbose", "v", false, "verbose output")
 	DoitCmd.PersistentFlags().BoolVarP(&Trace, "trace", "", false, "verbose output")
 }
@@ -86,8 +88,10 @@ func Init() *Command {
 // AddCommands adds sub commands to the base command.
 func addCommands() {
 	DoitCmd.AddCommand(Account())
+	DoitCmd.AddCommand(Apply())
 	DoitCmd.AddCommand(Auth())
 	DoitCmd.AddCommand(computeCmd())
+	DoitCmd.AddCommand(Plan())
 	DoitCmd.AddCommand(Version())
 }
 
@@ -101,6 +105,7 @@ func computeCmd() *Command {
 	}
 
 	cmd.AddCommand(Actions())
+	cmd.AddCommand(Cost())
 	cmd.AddCommand(DropletAction())
 	cmd.AddCommand(Droplet())
 	cmd.AddCommand(Domain())
@@ -112,7 +117,9 @@ func computeCmd() *Command {
 	cmd.AddCommand(Region())
 	cmd.AddCommand(Size())
 	cmd.AddCommand(SSHKeys())
+	cmd.AddCommand(SCP())
 	cmd.AddCommand(SSH())
+	cmd.AddCommand(SSHExec())
 
 	return cmd
 }
@@ -162,8 +169,13 @@ func requiredKey(key string) string {
 
 // AddStringFlag adds a string flag to a command.
 func AddStringFlag(cmd *Command, name, dflt, desc string, opts ...flagOpt) {
+	AddStringFlagP(cmd, name, "", dflt, desc, opts...)
+}
+
+// AddStringFlagP adds a string flag with a one letter shorthand to a command.
+func AddStringFlagP(cmd *Command, name, shorthand, dflt, desc string, opts ...flagOpt) {
 	fn := flagName(cmd, name)
-	cmd.Flags().String(name, dflt, desc)
+	cmd.Flags().StringP(name, shorthand, dflt, desc)
 
 	for _, o := range opts {
 		o(cmd, name, fn)
@@ -185,8 +197,13 @@ func AddIntFlag(cmd *Command, name string, def int, desc string, opts ...flagOpt
 
 // AddBoolFlag adds a boolean flag to a command.
 func AddBoolFlag(cmd *Command, name string, def bool, desc string, opts ...flagOpt) {
+	AddBoolFlagP(cmd, name, "", def, desc, opts...)
+}
+
+// AddBoolFlagP adds a boolean flag with a one letter shorthand to a command.
+func AddBoolFlagP(cmd *Command, name, shorthand string, def bool, desc string, opts ...flagOpt) {
 	fn := flagName(cmd, name)
-	cmd.Flags().Bool(name, def, desc)
+	cmd.Flags().BoolP(name, shorthand, def, desc)
 	viper.BindPFlag(fn, cmd.Flags().Lookup(name))
 
 	for _, o := range opts {
@@ -246,6 +263,7 @@ type CmdConfig struct {
 	Domains           func() do.DomainsService
 	Actions           func() do.ActionsService
 	Account           func() do.AccountService
+	Tags              func() do.TagsService
 }
 
 // NewCmdConfig creates an instance of a CmdConfig.
@@ -270,6 +288,7 @@ func NewCmdConfig(ns string, dc doit.Config, out io.Writer, args []string) *CmdC
 		Domains:           func() do.DomainsService { return do.NewDomainsService(godoClient) },
 		Actions:           func() do.ActionsService { return do.NewActionsService(godoClient) },
 		Account:           func() do.AccountService { return do.NewAccountService(godoClient) },
+		Tags:              func() do.TagsService { return do.NewTagsService(godoClient) },
 	}
 }
 
@@ -285,6 +304,26 @@ func (c *CmdConfig) Display(d Displayable) error {
 	return dc.Display()
 }
 
+// Resolver returns a resolver which finds resources using the command's
+// services.
+func (c *CmdConfig) Resolver() *resolver.Resolver {
+	return resolver.New(c.Droplets(), c.Images(), c.Keys(), c.FloatingIPs(), c.Domains())
+}
+
+// bindFlags binds a command's flags under the namespace they are read from.
+// Flags added to a command built without a parent are bound under the root
+// namespace, which is wrong once the command is added to a parent other
+// than the root.
+func bindFlags(cmd *cobra.Command, ns string) {
+	if ns == fmt.Sprintf("%s.%s", doit.NSRoot, cmd.Name()) {
+		return
+	}
+
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		viper.BindPFlag(fmt.Sprintf("%s.%s", ns, f.Name), f)
+	})
+}
+
 // CmdBuilder builds a new command.
 func CmdBuilder(parent *Command, cr CmdRunner, cliText, desc string, out io.Writer, options ...cmdOption) *Command {
 	cc := &cobra.Command{
@@ -292,8 +331,13 @@ func CmdBuilder(parent *Command, cr CmdRunner, cliText, desc string, out io.Writ
 		Short: desc,
 		Long:  desc,
 		Run: func(cmd *cobra.Command, args []string) {
+			ns := cmdNS(cmd)
+			if parent == nil {
+				bindFlags(cmd, ns)
+			}
+
 			c := NewCmdConfig(
-				cmdNS(cmd),
+				ns,
 				doit.DoitConfig,
 				out,
 				args,
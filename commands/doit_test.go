@@ -0,0 +1,40 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCmdBuilder_FlagsWithoutParent(t *testing.T) {
+	var user string
+	cmd := CmdBuilder(nil, func(c *CmdConfig) error {
+		var err error
+		user, err = c.Doit.GetString(c.NS, "flag-test-user")
+		return err
+	}, "flag-test", "flag test", ioutil.Discard)
+	AddStringFlag(cmd, "flag-test-user", "root", "user")
+
+	parent := &Command{Command: &cobra.Command{Use: "flag-test-parent"}}
+	parent.AddCommand(cmd)
+
+	assert.NoError(t, cmd.Flags().Set("flag-test-user", "core"))
+	cmd.Run(cmd.Command, nil)
+
+	assert.Equal(t, "core", user)
+}
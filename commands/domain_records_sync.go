@@ -0,0 +1,291 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"gopkg.in/yaml.v2"
+)
+
+// recordsFile lists the records a domain should have.
+type recordsFile struct {
+	Records []manifestRecord `yaml:"records"`
+}
+
+// syncRecord is a record in a records sync diff.
+type syncRecord struct {
+	ID       int    `json:"id,omitempty"`
+	Type     string `json:"type"`
+	Name     string `json:"name"`
+	Data     string `json:"data"`
+	Priority int    `json:"priority,omitempty"`
+	Port     int    `json:"port,omitempty"`
+	Weight   int    `json:"weight,omitempty"`
+}
+
+func (r syncRecord) String() string {
+	return recordSummary(r.request())
+}
+
+func (r syncRecord) request() *godo.DomainRecordEditRequest {
+	return &godo.DomainRecordEditRequest{
+		Type:     r.Type,
+		Name:     r.Name,
+		Data:     r.Data,
+		Priority: r.Priority,
+		Port:     r.Port,
+		Weight:   r.Weight,
+	}
+}
+
+// syncUpdate is a record whose priority, port or weight changes.
+type syncUpdate struct {
+	From syncRecord `json:"from"`
+	To   syncRecord `json:"to"`
+}
+
+// recordsDiff is how a domain's records differ from a records file.
+type recordsDiff struct {
+	Create []syncRecord `json:"create"`
+	Update []syncUpdate `json:"update"`
+	Delete []syncRecord `json:"delete"`
+}
+
+// RunRecordSync makes a domain's records match a records file. Records are
+// identified by type, name and data: records in the file which the domain
+// doesn't have are created and records whose priority, port or weight
+// differ are updated. Records which aren't in the file are only deleted
+// with --prune. With JSON output the diff is written as JSON.
+func RunRecordSync(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+	domain := c.Args[0]
+
+	path, err := c.Doit.GetString(c.NS, doit.ArgManifestFile)
+	if err != nil {
+		return err
+	}
+
+	prune, err := c.Doit.GetBool(c.NS, doit.ArgPrune)
+	if err != nil {
+		return err
+	}
+
+	output, err := c.Doit.GetString(doit.NSRoot, doit.ArgOutput)
+	if err != nil {
+		return err
+	}
+
+	records, err := loadRecordsFile(path)
+	if err != nil {
+		return err
+	}
+
+	live, err := c.Domains().Records(domain)
+	if err != nil {
+		return err
+	}
+
+	diff, err := diffRecords(domain, records, live, prune)
+	if err != nil {
+		return err
+	}
+
+	p := diff.plan(domain)
+	state := &applyState{c: c}
+	if output != "json" {
+		if !prune {
+			for _, r := range unmanagedRecords(domain, records, live) {
+				p.add(opNote, fmt.Sprintf("record %s %s %s", domain, r.Type, r.Name),
+					fmt.Sprintf("%s isn't in %s, use --%s to delete it", r.Data, path, doit.ArgPrune), nil)
+			}
+		}
+
+		return runPlan(c, p, state, "sync", "records in "+domain, "no changes, "+domain+" matches "+path)
+	}
+
+	if err := writeJSON(diff, c.Out); err != nil {
+		return err
+	}
+
+	dryRun, err := c.Doit.GetBool(c.NS, doit.ArgDryRun)
+	if err != nil || dryRun || len(p.pending()) == 0 {
+		return err
+	}
+
+	return applyPlan(c, p, state, "sync", "records in "+domain, progressOut)
+}
+
+// loadRecordsFile reads and checks a records file.
+func loadRecordsFile(path string) ([]manifestRecord, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a records file is required, use --%s", doit.ArgManifestFile)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var f recordsFile
+	if err := yaml.Unmarshal(b, &f); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+
+	for i, r := range f.Records {
+		what := fmt.Sprintf("record %d in %s", i+1, path)
+		if r.Type == "" || r.Name == "" || r.Data == "" {
+			return nil, fmt.Errorf("%s needs a type, name and data", what)
+		}
+		if r.Droplet != "" {
+			return nil, fmt.Errorf("%s can't use a droplet, only manifests can", what)
+		}
+	}
+
+	return f.Records, nil
+}
+
+// managedRecord reports whether a live record is managed by DigitalOcean
+// rather than the records file.
+func managedRecord(r do.DomainRecord) bool {
+	recordType := strings.ToUpper(r.Type)
+	return recordType == "SOA" || (recordType == "NS" && r.Name == "@")
+}
+
+// diffRecords compares a records file with the domain's records.
+func diffRecords(domain string, records []manifestRecord, live do.DomainRecords, prune bool) (*recordsDiff, error) {
+	byKey := map[string][]do.DomainRecord{}
+	for _, r := range live {
+		k := zoneRecordKey(domain, r.Type, r.Name, r.Data)
+		byKey[k] = append(byKey[k], r)
+	}
+
+	diff := &recordsDiff{Create: []syncRecord{}, Update: []syncUpdate{}, Delete: []syncRecord{}}
+	wanted := map[string]bool{}
+	for _, r := range records {
+		k := zoneRecordKey(domain, r.Type, r.Name, r.Data)
+		if wanted[k] {
+			return nil, fmt.Errorf("record %s %s %s is listed twice", strings.ToUpper(r.Type), r.Name, r.Data)
+		}
+		wanted[k] = true
+
+		req := &godo.DomainRecordEditRequest{
+			Type:     r.Type,
+			Name:     r.Name,
+			Data:     r.Data,
+			Priority: r.Priority,
+			Port:     r.Port,
+			Weight:   r.Weight,
+		}
+		if err := validateRecord(req); err != nil {
+			return nil, fmt.Errorf("record %s %s: %v", strings.ToUpper(r.Type), r.Name, err)
+		}
+
+		want := syncRecord{
+			Type:     req.Type,
+			Name:     req.Name,
+			Data:     req.Data,
+			Priority: req.Priority,
+			Port:     req.Port,
+			Weight:   req.Weight,
+		}
+
+		matches := byKey[k]
+		if len(matches) == 0 {
+			diff.Create = append(diff.Create, want)
+			continue
+		}
+
+		l := matches[0]
+		if l.Priority == r.Priority && l.Port == r.Port && l.Weight == r.Weight {
+			continue
+		}
+
+		want.ID = l.ID
+		diff.Update = append(diff.Update, syncUpdate{From: liveSyncRecord(l), To: want})
+	}
+
+	if !prune {
+		return diff, nil
+	}
+
+	seen := map[string]bool{}
+	for _, r := range live {
+		k := zoneRecordKey(domain, r.Type, r.Name, r.Data)
+		if managedRecord(r) || (wanted[k] && !seen[k]) {
+			seen[k] = true
+			continue
+		}
+
+		diff.Delete = append(diff.Delete, liveSyncRecord(r))
+	}
+
+	return diff, nil
+}
+
+// unmanagedRecords are the live records which aren't in the records file.
+func unmanagedRecords(domain string, records []manifestRecord, live do.DomainRecords) []syncRecord {
+	diff, _ := diffRecords(domain, records, live, true)
+	return diff.Delete
+}
+
+func liveSyncRecord(r do.DomainRecord) syncRecord {
+	return syncRecord{
+		ID:       r.ID,
+		Type:     strings.ToUpper(r.Type),
+		Name:     r.Name,
+		Data:     r.Data,
+		Priority: r.Priority,
+		Port:     r.Port,
+		Weight:   r.Weight,
+	}
+}
+
+// plan turns the diff into changes: creates, then updates, then deletes.
+func (d *recordsDiff) plan(domain string) *infraPlan {
+	p := &infraPlan{}
+
+	for _, r := range d.Create {
+		r := r
+		p.add(opCreate, fmt.Sprintf("record %s %s %s", domain, r.Type, r.Name), r.String(), func(s *applyState) error {
+			_, err := s.c.Domains().CreateRecord(domain, r.request())
+			return err
+		})
+	}
+
+	for _, u := range d.Update {
+		u := u
+		p.add(opUpdate, fmt.Sprintf("record %s %s %s", domain, u.To.Type, u.To.Name),
+			fmt.Sprintf("%s -> %s", u.From, u.To), func(s *applyState) error {
+				_, err := s.c.Domains().EditRecord(domain, u.To.ID, u.To.request())
+				return err
+			})
+	}
+
+	for _, r := range d.Delete {
+		id := r.ID
+		p.add(opDelete, fmt.Sprintf("record %s %s %s", domain, r.Type, r.Name), r.String(), func(s *applyState) error {
+			return s.c.Domains().DeleteRecord(domain, id)
+		})
+	}
+
+	return p
+}
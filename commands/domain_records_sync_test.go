@@ -0,0 +1,179 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+const testRecordsFile = `records:
+  - type: A
+    name: "@"
+    data: 10.0.0.1
+  - type: cname
+    name: blog
+    data: example.com.
+  - type: MX
+    name: "@"
+    data: mail.example.com.
+    priority: 20
+  - type: A
+    name: api
+    data: 10.0.0.5
+`
+
+func TestRecordSync_Prune(t *testing.T) {
+	withTempFile(t, testRecordsFile, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(testZoneRecords, nil)
+			tm.domains.On("CreateRecord", "example.com", &godo.DomainRecordEditRequest{
+				Type: "A", Name: "api", Data: "10.0.0.5",
+			}).Return(&testRecord, nil)
+			tm.domains.On("EditRecord", "example.com", 6, &godo.DomainRecordEditRequest{
+				Type: "MX", Name: "@", Data: "mail.example.com.", Priority: 20,
+			}).Return(&testRecord, nil)
+			for _, id := range []int{4, 7, 8, 9} {
+				tm.domains.On("DeleteRecord", "example.com", id).Return(nil)
+			}
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com")
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+			config.Doit.Set(config.NS, doit.ArgPrune, true)
+			config.Doit.Set(config.NS, doit.ArgForce, true)
+
+			err := RunRecordSync(config)
+			assert.NoError(t, err)
+
+			expected := "+ record example.com A api: 10.0.0.5\n" +
+				"~ record example.com MX @: 10 mail.example.com -> 20 mail.example.com.\n" +
+				"- record example.com AAAA www: 2001:db8::1\n" +
+				"- record example.com SRV _sip._tcp: 10 5 5060 sip.example.com\n" +
+				`- record example.com TXT @: v=spf1 include:"_spf.example.com" ~all` + "\n" +
+				"- record example.com NS dev: ns1.other.net\n" +
+				"\n1 to create, 1 to update, 4 to delete\n" +
+				"applied 6 changes\n"
+			assert.Equal(t, expected, out.String())
+		})
+	})
+}
+
+func TestRecordSync_NoPrune(t *testing.T) {
+	live := do.DomainRecords{
+		zoneRecord(3, "A", "@", "10.0.0.1", 0, 0, 0),
+		zoneRecord(4, "A", "old", "10.0.0.9", 0, 0, 0),
+	}
+
+	withTempFile(t, "records:\n  - {type: A, name: \"@\", data: 10.0.0.1}\n", func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(live, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com")
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+
+			err := RunRecordSync(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "no changes, example.com matches "+path+"\n", out.String())
+		})
+	})
+}
+
+func TestRecordSync_JSON(t *testing.T) {
+	withTempFile(t, testRecordsFile, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(testZoneRecords[:6], nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com")
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+			config.Doit.Set(config.NS, doit.ArgPrune, true)
+			config.Doit.Set(config.NS, doit.ArgDryRun, true)
+			config.Doit.Set(doit.NSRoot, doit.ArgOutput, "json")
+
+			err := RunRecordSync(config)
+			assert.NoError(t, err)
+
+			expected := `{
+  "create": [
+    {
+      "type": "A",
+      "name": "api",
+      "data": "10.0.0.5"
+    }
+  ],
+  "update": [
+    {
+      "from": {
+        "id": 6,
+        "type": "MX",
+        "name": "@",
+        "data": "mail.example.com",
+        "priority": 10
+      },
+      "to": {
+        "id": 6,
+        "type": "MX",
+        "name": "@",
+        "data": "mail.example.com.",
+        "priority": 20
+      }
+    }
+  ],
+  "delete": [
+    {
+      "id": 4,
+      "type": "AAAA",
+      "name": "www",
+      "data": "2001:db8::1"
+    }
+  ]
+}`
+			assert.Equal(t, expected, out.String())
+		})
+	})
+}
+
+func TestLoadRecordsFile_Invalid(t *testing.T) {
+	cases := []struct {
+		file string
+		err  string
+	}{
+		{"records:\n  - {type: A, name: www}\n", "record 1 in %s needs a type, name and data"},
+		{"records:\n  - {type: A, name: www, data: 10.0.0.1, droplet: web}\n", "record 1 in %s can't use a droplet, only manifests can"},
+	}
+
+	for _, c := range cases {
+		withTempFile(t, c.file, func(path string) {
+			_, err := loadRecordsFile(path)
+			assert.EqualError(t, err, fmt.Sprintf(c.err, path))
+		})
+	}
+
+	_, err := diffRecords("example.com", []manifestRecord{
+		{Type: "CNAME", Name: "www", Data: "@"},
+		{Type: "CNAME", Name: "www", Data: "example.com."},
+	}, nil, false)
+	assert.EqualError(t, err, "record CNAME www example.com. is listed twice")
+}
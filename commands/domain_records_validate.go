@@ -0,0 +1,127 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/digitalocean/godo"
+)
+
+var (
+	// hostLabelRE matches a label in a host name. Underscores are allowed
+	// since they are common in names like _dmarc.
+	hostLabelRE = regexp.MustCompile(`^(?i)[a-z0-9_]([a-z0-9_-]{0,61}[a-z0-9_])?$`)
+
+	// srvNameRE matches the _service._proto prefix of an SRV record name.
+	srvNameRE = regexp.MustCompile(`^(?i)_[a-z0-9-]+\._[a-z0-9-]+(\.|$)`)
+)
+
+// maxPort is the largest port, priority or weight a record can have.
+const maxPort = 65535
+
+// validateRecord checks a record before it is sent to the API so mistakes
+// are caught with a clear error rather than failing or being accepted
+// malformed. The type is upper cased, and TXT data too long for one string
+// is quoted and split. Updates are checked once merged with the record they
+// change.
+func validateRecord(r *godo.DomainRecordEditRequest) error {
+	if r.Type == "" {
+		return fmt.Errorf("records need a type, use --%s", doit.ArgRecordType)
+	}
+	r.Type = strings.ToUpper(r.Type)
+
+	if r.Data == "" {
+		return fmt.Errorf("%s records need data", r.Type)
+	}
+
+	if r.Priority != 0 && r.Type != "MX" && r.Type != "SRV" {
+		return fmt.Errorf("--%s only applies to MX and SRV records", doit.ArgRecordPriority)
+	}
+	if (r.Port != 0 || r.Weight != 0) && r.Type != "SRV" {
+		return fmt.Errorf("--%s and --%s only apply to SRV records", doit.ArgRecordPort, doit.ArgRecordWeight)
+	}
+
+	switch r.Type {
+	case "A":
+		if ip := net.ParseIP(r.Data); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("A record data must be an IPv4 address, not %q", r.Data)
+		}
+	case "AAAA":
+		if ip := net.ParseIP(r.Data); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("AAAA record data must be an IPv6 address, not %q", r.Data)
+		}
+	case "CNAME", "NS":
+		return validateTarget(r)
+	case "MX":
+		if r.Priority < 1 || r.Priority > maxPort {
+			return fmt.Errorf("MX records need a priority between 1 and %d, use --%s", maxPort, doit.ArgRecordPriority)
+		}
+		return validateTarget(r)
+	case "SRV":
+		if r.Name != "" && r.Name != "@" && !srvNameRE.MatchString(r.Name) {
+			return fmt.Errorf("SRV record names start with _service._protocol, like _sip._tcp, not %q", r.Name)
+		}
+		if r.Port < 1 || r.Port > maxPort {
+			return fmt.Errorf("SRV records need a port between 1 and %d, use --%s", maxPort, doit.ArgRecordPort)
+		}
+		if r.Priority < 0 || r.Priority > maxPort {
+			return fmt.Errorf("SRV record priority must be between 0 and %d", maxPort)
+		}
+		if r.Weight < 0 || r.Weight > maxPort {
+			return fmt.Errorf("SRV record weight must be between 0 and %d", maxPort)
+		}
+		return validateTarget(r)
+	case "TXT":
+		if len(unquoteTXT(r.Data)) > maxTXTChunk {
+			r.Data = quoteTXT(r.Data)
+		}
+	case "CAA":
+	default:
+		return fmt.Errorf("unknown record type %q, expected A, AAAA, CAA, CNAME, MX, NS, SRV or TXT", r.Type)
+	}
+
+	return nil
+}
+
+// validateTarget checks the host name in CNAME, MX, NS and SRV data. It can
+// be "@" for the domain, a single label relative to the domain, or a fully
+// qualified name ending in a dot.
+func validateTarget(r *godo.DomainRecordEditRequest) error {
+	host := r.Data
+	if host == "@" {
+		return nil
+	}
+
+	if len(strings.TrimSuffix(host, ".")) > 253 {
+		return fmt.Errorf("%s record data %q is too long for a host name", r.Type, host)
+	}
+
+	labels := strings.Split(strings.TrimSuffix(host, "."), ".")
+	for _, l := range labels {
+		if !hostLabelRE.MatchString(l) {
+			return fmt.Errorf("%s record data %q isn't a valid host name", r.Type, host)
+		}
+	}
+
+	if len(labels) > 1 && !strings.HasSuffix(host, ".") {
+		return fmt.Errorf("%s record data %q needs a trailing dot to be fully qualified, like %q", r.Type, host, host+".")
+	}
+
+	return nil
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateRecord(t *testing.T) {
+	cases := []struct {
+		r   godo.DomainRecordEditRequest
+		err string
+	}{
+		{r: godo.DomainRecordEditRequest{Type: "a", Name: "www", Data: "192.168.1.1"}},
+		{r: godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "::1"},
+			err: `A record data must be an IPv4 address, not "::1"`},
+		{r: godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1"},
+			err: `A record data must be an IPv4 address, not "192.168.1"`},
+		{r: godo.DomainRecordEditRequest{Type: "AAAA", Name: "www", Data: "2001:db8::1"}},
+		{r: godo.DomainRecordEditRequest{Type: "AAAA", Name: "www", Data: "192.168.1.1"},
+			err: `AAAA record data must be an IPv6 address, not "192.168.1.1"`},
+		{r: godo.DomainRecordEditRequest{Type: "A", Name: "www"},
+			err: "A records need data"},
+		{r: godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1.1", Priority: 10},
+			err: "--record-priority only applies to MX and SRV records"},
+		{r: godo.DomainRecordEditRequest{Type: "MX", Name: "@", Data: "mx.example.com.", Priority: 10, Port: 25},
+			err: "--record-port and --record-weight only apply to SRV records"},
+		{r: godo.DomainRecordEditRequest{Type: "CNAME", Name: "docs", Data: "www"}},
+		{r: godo.DomainRecordEditRequest{Type: "CNAME", Name: "docs", Data: "@"}},
+		{r: godo.DomainRecordEditRequest{Type: "CNAME", Name: "docs", Data: "example.github.io."}},
+		{r: godo.DomainRecordEditRequest{Type: "CNAME", Name: "docs", Data: "example.github.io"},
+			err: `CNAME record data "example.github.io" needs a trailing dot to be fully qualified, like "example.github.io."`},
+		{r: godo.DomainRecordEditRequest{Type: "NS", Name: "sub", Data: "ns1..example.com."},
+			err: `NS record data "ns1..example.com." isn't a valid host name`},
+		{r: godo.DomainRecordEditRequest{Type: "CNAME", Name: "docs", Data: "-bad.example.com."},
+			err: `CNAME record data "-bad.example.com." isn't a valid host name`},
+		{r: godo.DomainRecordEditRequest{Type: "MX", Name: "@", Data: "mx.example.com.", Priority: 10}},
+		{r: godo.DomainRecordEditRequest{Type: "MX", Name: "@", Data: "mx.example.com."},
+			err: "MX records need a priority between 1 and 65535, use --record-priority"},
+		{r: godo.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com.", Port: 5060, Weight: 5}},
+		{r: godo.DomainRecordEditRequest{Type: "SRV", Name: "sip", Data: "sip.example.com.", Port: 5060},
+			err: `SRV record names start with _service._protocol, like _sip._tcp, not "sip"`},
+		{r: godo.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com."},
+			err: "SRV records need a port between 1 and 65535, use --record-port"},
+		{r: godo.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com.", Port: 5060, Weight: 70000},
+			err: "SRV record weight must be between 0 and 65535"},
+		{r: godo.DomainRecordEditRequest{Type: "SPF", Name: "@", Data: "v=spf1 -all"},
+			err: `unknown record type "SPF", expected A, AAAA, CAA, CNAME, MX, NS, SRV or TXT`},
+		{r: godo.DomainRecordEditRequest{Name: "www", Data: "anything"},
+			err: "records need a type, use --record-type"},
+	}
+
+	for _, c := range cases {
+		r := c.r
+		err := validateRecord(&r)
+		if c.err == "" {
+			assert.NoError(t, err, "%+v", c.r)
+		} else {
+			assert.EqualError(t, err, c.err, "%+v", c.r)
+		}
+	}
+}
+
+func TestValidateRecord_TXT(t *testing.T) {
+	short := &godo.DomainRecordEditRequest{Type: "TXT", Name: "@", Data: "v=spf1 -all"}
+	assert.NoError(t, validateRecord(short))
+	assert.Equal(t, "v=spf1 -all", short.Data)
+
+	key := strings.Repeat("a", 300)
+	long := &godo.DomainRecordEditRequest{Type: "TXT", Name: "dkim._domainkey", Data: key}
+	assert.NoError(t, validateRecord(long))
+	assert.Equal(t, `"`+key[:255]+`" "`+key[255:]+`"`, long.Data)
+
+	quoted := &godo.DomainRecordEditRequest{Type: "TXT", Name: "dkim._domainkey", Data: long.Data}
+	assert.NoError(t, validateRecord(quoted))
+	assert.Equal(t, long.Data, quoted.Data)
+}
+
+func TestRecordsCreate_Invalid(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doit.ArgRecordType, "MX")
+		config.Doit.Set(config.NS, doit.ArgRecordName, "@")
+		config.Doit.Set(config.NS, doit.ArgRecordData, "mx.example.com.")
+
+		config.Args = append(config.Args, "example.com")
+
+		err := RunRecordCreate(config)
+		assert.EqualError(t, err, "MX records need a priority between 1 and 65535, use --record-priority")
+	})
+}
+
+func TestRecordsUpdate_Invalid(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.On("Record", "example.com", 1).Return(&testRecord, nil)
+
+		config.Doit.Set(config.NS, doit.ArgRecordID, 1)
+		config.Doit.Set(config.NS, doit.ArgRecordType, "AAAA")
+		config.Doit.Set(config.NS, doit.ArgRecordName, "www")
+		config.Doit.Set(config.NS, doit.ArgRecordData, "192.168.1.1")
+
+		config.Args = append(config.Args, "example.com")
+
+		err := RunRecordUpdate(config)
+		assert.EqualError(t, err, `AAAA record data must be an IPv6 address, not "192.168.1.1"`)
+	})
+}
+
+var testCNAMERecord = do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 13, Name: "www", Type: "CNAME", Data: "example.com"}}
+
+func TestRecordsUpdate_PartialInvalid(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.On("Records", "example.com").Return(do.DomainRecords{testCNAMERecord}, nil)
+
+		config.Doit.Set(config.NS, doit.ArgRecordData, "foo.example.com")
+		config.Args = append(config.Args, "example.com", "www:CNAME")
+
+		err := RunRecordUpdate(config)
+		assert.EqualError(t, err, `CNAME record data "foo.example.com" needs a trailing dot to be fully qualified, like "foo.example.com."`)
+	})
+}
+
+func TestRecordsUpdate_Partial(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		mx := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 11, Name: "@", Type: "MX", Data: "mx1.example.com", Priority: 10}}
+		tm.domains.On("Record", "example.com", 11).Return(&mx, nil)
+		tm.domains.On("EditRecord", "example.com", 11, &godo.DomainRecordEditRequest{
+			Type: "MX", Name: "@", Data: "mx1.example.com.", Priority: 5,
+		}).Return(&mx, nil)
+
+		config.Doit.Set(config.NS, doit.ArgRecordPriority, 5)
+		config.Args = append(config.Args, "example.com", "11")
+
+		err := RunRecordUpdate(config)
+		assert.NoError(t, err)
+	})
+}
@@ -0,0 +1,602 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+)
+
+// maxTXTChunk is the longest string a TXT record can hold. Longer values
+// are split into several strings.
+const maxTXTChunk = 255
+
+// RunDomainExport writes a domain's records as an RFC 1035 zone file. SOA
+// records are managed by DigitalOcean and are left out.
+func RunDomainExport(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+	name := c.Args[0]
+
+	ds := c.Domains()
+
+	d, err := ds.Get(name)
+	if err != nil {
+		return err
+	}
+
+	records, err := ds.Records(name)
+	if err != nil {
+		return err
+	}
+
+	return writeZone(c.Out, d, records)
+}
+
+func writeZone(out io.Writer, d *do.Domain, records do.DomainRecords) error {
+	w := tabwriter.NewWriter(out, 0, 8, 1, '\t', 0)
+
+	fmt.Fprintf(w, "$ORIGIN %s.\n", d.Name)
+	if d.TTL > 0 {
+		fmt.Fprintf(w, "$TTL %d\n", d.TTL)
+	}
+
+	for _, r := range records {
+		var data string
+		switch strings.ToUpper(r.Type) {
+		case "SOA":
+			continue
+		case "CNAME", "NS":
+			data = zoneTarget(r.Data)
+		case "MX":
+			data = fmt.Sprintf("%d %s", r.Priority, zoneTarget(r.Data))
+		case "SRV":
+			data = fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, zoneTarget(r.Data))
+		case "TXT":
+			data = quoteTXT(r.Data)
+		default:
+			data = r.Data
+		}
+
+		fmt.Fprintf(w, "%s\tIN\t%s\t%s\n", r.Name, strings.ToUpper(r.Type), data)
+	}
+
+	return w.Flush()
+}
+
+// zoneTarget writes a host name from a record's data. DigitalOcean keeps
+// them fully qualified, which a zone file marks with a trailing dot.
+func zoneTarget(host string) string {
+	if host == "@" || strings.HasSuffix(host, ".") {
+		return host
+	}
+
+	return host + "."
+}
+
+// quoteTXT quotes TXT data, splitting it into strings short enough for a
+// TXT record. Data which is already quoted is split again rather than
+// quoted twice.
+func quoteTXT(data string) string {
+	data = unquoteTXT(data)
+
+	var chunks []string
+	for len(data) > maxTXTChunk {
+		chunks = append(chunks, data[:maxTXTChunk])
+		data = data[maxTXTChunk:]
+	}
+	chunks = append(chunks, data)
+
+	for i, chunk := range chunks {
+		chunk = strings.Replace(chunk, `\`, `\\`, -1)
+		chunks[i] = `"` + strings.Replace(chunk, `"`, `\"`, -1) + `"`
+	}
+
+	return strings.Join(chunks, " ")
+}
+
+// unquoteTXT joins the strings in quoted TXT data, the form long TXT values
+// are stored in. Data which isn't quoted is returned as it is.
+func unquoteTXT(data string) string {
+	if !strings.HasPrefix(data, `"`) {
+		return data
+	}
+
+	depth := 0
+	fields, err := zoneFields(data, &depth)
+	if err != nil || depth != 0 {
+		return data
+	}
+
+	var text []string
+	for _, f := range fields {
+		if !f.quoted {
+			return data
+		}
+		text = append(text, f.text)
+	}
+
+	return strings.Join(text, "")
+}
+
+// RunDomainImport creates the records in a zone file. The records to be
+// created are shown first. Records which already exist are an error
+// unless --skip-existing is given.
+func RunDomainImport(c *CmdConfig) error {
+	if len(c.Args) != 2 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+	name, path := c.Args[0], c.Args[1]
+
+	skipExisting, err := c.Doit.GetBool(c.NS, doit.ArgSkipExisting)
+	if err != nil {
+		return err
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	zone, err := parseZone(bytes.NewReader(b), name)
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	live, err := c.Domains().Records(name)
+	if err != nil {
+		return err
+	}
+
+	exists := map[string]bool{}
+	for _, r := range live {
+		exists[zoneRecordKey(name, r.Type, r.Name, r.Data)] = true
+	}
+
+	p := &infraPlan{}
+	for _, n := range zone.skipped {
+		p.add(opNote, n.what, n.why, nil)
+	}
+
+	for _, r := range zone.records {
+		what := fmt.Sprintf("record %s %s %s", name, r.Type, r.Name)
+		if err := validateRecord(r); err != nil {
+			return fmt.Errorf("%s: %v", what, err)
+		}
+
+		if exists[zoneRecordKey(name, r.Type, r.Name, r.Data)] {
+			if !skipExisting {
+				return fmt.Errorf("%s %s already exists, use --%s to leave it", what, r.Data, doit.ArgSkipExisting)
+			}
+
+			p.add(opNote, what, r.Data+" already exists", nil)
+			continue
+		}
+
+		r := r
+		p.add(opCreate, what, recordSummary(r), func(s *applyState) error {
+			_, err := s.c.Domains().CreateRecord(name, r)
+			return err
+		})
+	}
+
+	return runPlan(c, p, &applyState{c: c}, "import", "records into "+name, "no changes, "+name+" has every record in "+path)
+}
+
+// recordSummary describes a record's data and its type's other fields.
+func recordSummary(r *godo.DomainRecordEditRequest) string {
+	switch r.Type {
+	case "MX":
+		return fmt.Sprintf("%d %s", r.Priority, r.Data)
+	case "SRV":
+		return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Data)
+	}
+
+	return r.Data
+}
+
+// zoneRecordKey identifies a record by type, name and data. Host names in
+// data are compared without their trailing dot, and a host name which is
+// the domain itself is the same as "@". TXT data is compared unquoted.
+func zoneRecordKey(domain, recordType, name, data string) string {
+	recordType = strings.ToUpper(recordType)
+
+	switch recordType {
+	case "TXT":
+		data = unquoteTXT(data)
+	case "CNAME", "MX", "NS", "SRV":
+		data = strings.ToLower(strings.TrimSuffix(data, "."))
+		if data == strings.ToLower(domain) {
+			data = "@"
+		}
+	}
+
+	return recordType + " " + strings.ToLower(name) + " " + data
+}
+
+// zoneNote is something in a zone file which isn't imported.
+type zoneNote struct {
+	what string
+	why  string
+}
+
+type parsedZone struct {
+	records []*godo.DomainRecordEditRequest
+	skipped []zoneNote
+}
+
+// zoneParser reads records for a domain from a zone file. Names are made
+// relative to the domain the way DigitalOcean names records, and host
+// names in data fully qualified.
+type zoneParser struct {
+	domain string
+	origin string
+	owner  string
+	zone   parsedZone
+}
+
+// parseZone reads the A, AAAA, CNAME, MX, TXT, SRV and NS records in an
+// RFC 1035 zone file for domain. SOA records, NS records for the domain
+// itself, which DigitalOcean manages, and other types are skipped.
+func parseZone(r io.Reader, domain string) (*parsedZone, error) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	p := &zoneParser{domain: domain, origin: domain + "."}
+
+	lines, err := zoneLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, l := range lines {
+		if err := p.entry(l); err != nil {
+			return nil, fmt.Errorf("line %d: %v", l.number, err)
+		}
+	}
+
+	return &p.zone, nil
+}
+
+func (p *zoneParser) entry(l zoneLine) error {
+	fields := l.fields
+
+	switch strings.ToUpper(fields[0].text) {
+	case "$ORIGIN":
+		if len(fields) != 2 {
+			return fmt.Errorf("$ORIGIN needs a name")
+		}
+		p.origin = p.fqdn(fields[1].text)
+		return nil
+	case "$TTL":
+		return nil
+	case "$INCLUDE", "$GENERATE":
+		return fmt.Errorf("%s isn't supported", fields[0].text)
+	}
+
+	if !l.continued {
+		p.owner, fields = p.fqdn(fields[0].text), fields[1:]
+	}
+
+	if p.owner == "" {
+		return fmt.Errorf("record has no name")
+	}
+
+	// the TTL and class can come in either order before the type.
+	for len(fields) > 0 && !fields[0].quoted {
+		f := strings.ToUpper(fields[0].text)
+		if f != "IN" && f != "CH" && f != "HS" && !isZoneTTL(f) {
+			break
+		}
+		fields = fields[1:]
+	}
+
+	if len(fields) == 0 {
+		return fmt.Errorf("record has no type")
+	}
+
+	recordType, rdata := strings.ToUpper(fields[0].text), fields[1:]
+
+	name, err := p.relative(p.owner)
+	if err != nil {
+		return err
+	}
+
+	what := fmt.Sprintf("record %s %s %s", p.domain, recordType, name)
+	switch recordType {
+	case "SOA":
+		p.skip(what, "SOA records are managed by DigitalOcean")
+		return nil
+	case "NS":
+		if name == "@" {
+			p.skip(what, "the domain's own NS records are managed by DigitalOcean")
+			return nil
+		}
+	case "A", "AAAA", "CNAME", "MX", "TXT", "SRV":
+	default:
+		p.skip(what, recordType+" records aren't supported")
+		return nil
+	}
+
+	r, err := p.record(recordType, rdata)
+	if err != nil {
+		return fmt.Errorf("%s %s: %v", recordType, name, err)
+	}
+
+	r.Type, r.Name = recordType, name
+	p.zone.records = append(p.zone.records, r)
+	return nil
+}
+
+func (p *zoneParser) skip(what, why string) {
+	p.zone.skipped = append(p.zone.skipped, zoneNote{what: what, why: why})
+}
+
+// record parses the data of a record.
+func (p *zoneParser) record(recordType string, rdata []zoneField) (*godo.DomainRecordEditRequest, error) {
+	r := &godo.DomainRecordEditRequest{}
+
+	if recordType == "TXT" {
+		if len(rdata) == 0 {
+			return nil, fmt.Errorf("no data")
+		}
+
+		var data []string
+		for _, f := range rdata {
+			data = append(data, f.text)
+		}
+		r.Data = strings.Join(data, "")
+		return r, nil
+	}
+
+	want := map[string]int{"A": 1, "AAAA": 1, "CNAME": 1, "NS": 1, "MX": 2, "SRV": 4}[recordType]
+	if len(rdata) != want {
+		return nil, fmt.Errorf("expected %d fields of data, found %d", want, len(rdata))
+	}
+
+	var nums []int
+	for _, f := range rdata[:want-1] {
+		n, err := strconv.Atoi(f.text)
+		if err != nil || n < 0 || n > 65535 {
+			return nil, fmt.Errorf("invalid number %q", f.text)
+		}
+		nums = append(nums, n)
+	}
+
+	data := rdata[want-1].text
+	switch recordType {
+	case "A", "AAAA":
+		ip := net.ParseIP(data)
+		if ip == nil || (ip.To4() != nil) != (recordType == "A") {
+			return nil, fmt.Errorf("invalid address %q", data)
+		}
+		r.Data = data
+	case "MX":
+		r.Priority, r.Data = nums[0], p.target(data)
+	case "SRV":
+		r.Priority, r.Weight, r.Port, r.Data = nums[0], nums[1], nums[2], p.target(data)
+	default:
+		r.Data = p.target(data)
+	}
+
+	return r, nil
+}
+
+// fqdn qualifies a name with the origin.
+func (p *zoneParser) fqdn(name string) string {
+	switch {
+	case name == "@":
+		return p.origin
+	case strings.HasSuffix(name, "."):
+		return strings.ToLower(name)
+	}
+
+	return strings.ToLower(name) + "." + p.origin
+}
+
+// relative names a record the way DigitalOcean does: "@" for the domain
+// itself and otherwise without the domain.
+func (p *zoneParser) relative(fqdn string) (string, error) {
+	name := strings.TrimSuffix(fqdn, ".")
+	switch {
+	case name == p.domain:
+		return "@", nil
+	case strings.HasSuffix(name, "."+p.domain):
+		return strings.TrimSuffix(name, "."+p.domain), nil
+	}
+
+	return "", fmt.Errorf("%s is outside %s", fqdn, p.domain)
+}
+
+// target is a host name in a record's data, which DigitalOcean wants fully
+// qualified or "@" for the domain itself.
+func (p *zoneParser) target(name string) string {
+	fqdn := p.fqdn(name)
+	if fqdn == p.domain+"." {
+		return "@"
+	}
+
+	return fqdn
+}
+
+// isZoneTTL reports whether s is a TTL, in seconds or with BIND's units
+// like 1h30m.
+func isZoneTTL(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	digits := false
+	for _, r := range strings.ToLower(s) {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case strings.ContainsRune("smhdw", r) && digits:
+			digits = false
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// zoneField is a word or quoted string in a zone file.
+type zoneField struct {
+	text   string
+	quoted bool
+}
+
+// zoneLine is an entry in a zone file. continued is true when the entry
+// starts with white space, so it has the previous entry's name.
+type zoneLine struct {
+	number    int
+	continued bool
+	fields    []zoneField
+}
+
+// zoneLines splits a zone file into entries, removing comments and joining
+// lines inside parentheses.
+func zoneLines(r io.Reader) ([]zoneLine, error) {
+	var (
+		lines   []zoneLine
+		current *zoneLine
+		depth   int
+	)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := scanner.Text()
+
+		if depth == 0 {
+			current = &zoneLine{
+				number:    n,
+				continued: len(text) > 0 && (text[0] == ' ' || text[0] == '\t'),
+			}
+		}
+
+		fields, err := zoneFields(text, &depth)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", n, err)
+		}
+		current.fields = append(current.fields, fields...)
+
+		if depth == 0 && len(current.fields) > 0 {
+			lines = append(lines, *current)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if depth > 0 {
+		return nil, fmt.Errorf("line %d: unclosed parenthesis", current.number)
+	}
+
+	return lines, nil
+}
+
+// zoneFields splits a line into fields, tracking how deep in parentheses
+// it is.
+func zoneFields(line string, depth *int) ([]zoneField, error) {
+	var (
+		fields []zoneField
+		word   bytes.Buffer
+		inWord bool
+	)
+
+	flush := func() {
+		if inWord {
+			fields = append(fields, zoneField{text: word.String()})
+			word.Reset()
+			inWord = false
+		}
+	}
+
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case ch == ';':
+			flush()
+			return fields, nil
+		case ch == ' ' || ch == '\t':
+			flush()
+		case ch == '(':
+			flush()
+			*depth++
+		case ch == ')':
+			flush()
+			if *depth == 0 {
+				return nil, fmt.Errorf("unexpected )")
+			}
+			*depth--
+		case ch == '"':
+			flush()
+
+			var s bytes.Buffer
+			closed := false
+			for i++; i < len(line); i++ {
+				if line[i] == '"' {
+					closed = true
+					break
+				}
+
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+					if b, ok := zoneEscape(line[i:]); ok {
+						s.WriteByte(b)
+						i += 2
+						continue
+					}
+				}
+				s.WriteByte(line[i])
+			}
+
+			if !closed {
+				return nil, fmt.Errorf("unterminated string")
+			}
+			fields = append(fields, zoneField{text: s.String(), quoted: true})
+		default:
+			word.WriteByte(ch)
+			inWord = true
+		}
+	}
+
+	flush()
+	return fields, nil
+}
+
+// zoneEscape decodes a \DDD escape.
+func zoneEscape(s string) (byte, bool) {
+	if len(s) < 3 {
+		return 0, false
+	}
+
+	n, err := strconv.Atoi(s[:3])
+	if err != nil || n > 255 {
+		return 0, false
+	}
+
+	return byte(n), true
+}
@@ -0,0 +1,268 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func zoneRecord(id int, recordType, name, data string, priority, port, weight int) do.DomainRecord {
+	return do.DomainRecord{DomainRecord: &godo.DomainRecord{
+		ID: id, Type: recordType, Name: name, Data: data, Priority: priority, Port: port, Weight: weight,
+	}}
+}
+
+var testZoneRecords = do.DomainRecords{
+	zoneRecord(1, "SOA", "@", "1800", 0, 0, 0),
+	zoneRecord(2, "NS", "@", "ns1.digitalocean.com", 0, 0, 0),
+	zoneRecord(3, "A", "@", "10.0.0.1", 0, 0, 0),
+	zoneRecord(4, "AAAA", "www", "2001:db8::1", 0, 0, 0),
+	zoneRecord(5, "CNAME", "blog", "@", 0, 0, 0),
+	zoneRecord(6, "MX", "@", "mail.example.com", 10, 0, 0),
+	zoneRecord(7, "SRV", "_sip._tcp", "sip.example.com", 10, 5060, 5),
+	zoneRecord(8, "TXT", "@", `v=spf1 include:"_spf.example.com" ~all`, 0, 0, 0),
+	zoneRecord(9, "NS", "dev", "ns1.other.net", 0, 0, 0),
+}
+
+func TestDomainExport(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		d := do.Domain{Domain: &godo.Domain{Name: "example.com", TTL: 1800}}
+		tm.domains.On("Get", "example.com").Return(&d, nil)
+		tm.domains.On("Records", "example.com").Return(testZoneRecords, nil)
+
+		var out bytes.Buffer
+		config.Out = &out
+		config.Args = append(config.Args, "example.com")
+
+		err := RunDomainExport(config)
+		assert.NoError(t, err)
+
+		expected := `$ORIGIN example.com.
+$TTL 1800
+@		IN	NS	ns1.digitalocean.com.
+@		IN	A	10.0.0.1
+www		IN	AAAA	2001:db8::1
+blog		IN	CNAME	@
+@		IN	MX	10 mail.example.com.
+_sip._tcp	IN	SRV	10 5 5060 sip.example.com.
+@		IN	TXT	"v=spf1 include:\"_spf.example.com\" ~all"
+dev		IN	NS	ns1.other.net.
+`
+		assert.Equal(t, expected, out.String())
+	})
+}
+
+func TestQuoteTXT_Long(t *testing.T) {
+	data := strings.Repeat("a", 300)
+	assert.Equal(t, `"`+strings.Repeat("a", 255)+`" "`+strings.Repeat("a", 45)+`"`, quoteTXT(data))
+}
+
+func TestLongTXT_RoundTrip(t *testing.T) {
+	data := strings.Repeat("a", 150) + strings.Repeat("b", 150)
+	stored := quoteTXT(data)
+	live := do.DomainRecords{zoneRecord(1, "TXT", "@", stored, 0, 0, 0)}
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.On("CreateRecord", "example.com", &godo.DomainRecordEditRequest{
+			Type: "TXT", Name: "@", Data: stored,
+		}).Return(&live[0], nil).Once()
+
+		config.Args = append(config.Args, "example.com")
+		config.Doit.Set(config.NS, doit.ArgRecordType, "TXT")
+		config.Doit.Set(config.NS, doit.ArgRecordName, "@")
+		config.Doit.Set(config.NS, doit.ArgRecordData, data)
+
+		assert.NoError(t, RunRecordCreate(config))
+	})
+
+	var zone bytes.Buffer
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		d := do.Domain{Domain: &godo.Domain{Name: "example.com"}}
+		tm.domains.On("Get", "example.com").Return(&d, nil)
+		tm.domains.On("Records", "example.com").Return(live, nil)
+
+		config.Out = &zone
+		config.Args = append(config.Args, "example.com")
+
+		assert.NoError(t, RunDomainExport(config))
+		assert.Equal(t, "$ORIGIN example.com.\n@\tIN\tTXT\t"+stored+"\n", zone.String())
+	})
+
+	withTempFile(t, zone.String(), func(path string) {
+		parsed, err := parseZone(strings.NewReader(zone.String()), "example.com")
+		assert.NoError(t, err)
+		assert.Equal(t, data, parsed.records[0].Data)
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(live, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com", path)
+			config.Doit.Set(config.NS, doit.ArgSkipExisting, true)
+
+			assert.NoError(t, RunDomainImport(config))
+			assert.Equal(t, "no changes, example.com has every record in "+path+"\n", out.String())
+		})
+	})
+
+	withTempFile(t, "records:\n  - {type: TXT, name: \"@\", data: "+data+"}\n", func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(live, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com")
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+
+			assert.NoError(t, RunRecordSync(config))
+			assert.Equal(t, "no changes, example.com matches "+path+"\n", out.String())
+		})
+	})
+}
+
+func TestParseZone(t *testing.T) {
+	zone := `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2016101901 ; serial
+		7200 3600 1209600 3600 )
+	IN	NS	ns1.example.com.
+	3600 IN A 10.0.0.1
+www.example.com.	IN	AAAA	2001:db8::1
+blog	CNAME	www
+mail	IN 300 MX	10 mx
+_sip._tcp	SRV	10 5 5060 sip.other.net.
+@	TXT	"v=spf1 \"quoted\"" " ~all" ; two strings
+dev	NS	ns1.other.net.
+old	HINFO	"PC" "Linux"
+$ORIGIN sub.example.com.
+api	A	10.0.0.2
+`
+
+	z, err := parseZone(strings.NewReader(zone), "example.com")
+	assert.NoError(t, err)
+
+	expected := []*godo.DomainRecordEditRequest{
+		{Type: "A", Name: "@", Data: "10.0.0.1"},
+		{Type: "AAAA", Name: "www", Data: "2001:db8::1"},
+		{Type: "CNAME", Name: "blog", Data: "www.example.com."},
+		{Type: "MX", Name: "mail", Data: "mx.example.com.", Priority: 10},
+		{Type: "SRV", Name: "_sip._tcp", Data: "sip.other.net.", Priority: 10, Weight: 5, Port: 5060},
+		{Type: "TXT", Name: "@", Data: `v=spf1 "quoted" ~all`},
+		{Type: "NS", Name: "dev", Data: "ns1.other.net."},
+		{Type: "A", Name: "api.sub", Data: "10.0.0.2"},
+	}
+	assert.Equal(t, expected, z.records)
+
+	assert.Equal(t, []zoneNote{
+		{"record example.com SOA @", "SOA records are managed by DigitalOcean"},
+		{"record example.com NS @", "the domain's own NS records are managed by DigitalOcean"},
+		{"record example.com HINFO old", "HINFO records aren't supported"},
+	}, z.skipped)
+}
+
+func TestParseZone_RoundTrip(t *testing.T) {
+	var out bytes.Buffer
+	d := &do.Domain{Domain: &godo.Domain{Name: "example.com", TTL: 1800}}
+	long := zoneRecord(10, "TXT", "long", strings.Repeat("x", 400), 0, 0, 0)
+	assert.NoError(t, writeZone(&out, d, append(testZoneRecords, long)))
+
+	z, err := parseZone(&out, "example.com")
+	assert.NoError(t, err)
+
+	var got []string
+	for _, r := range z.records {
+		got = append(got, zoneRecordKey("example.com", r.Type, r.Name, r.Data))
+	}
+
+	var want []string
+	for _, r := range append(testZoneRecords, long) {
+		if r.Type != "SOA" && !(r.Type == "NS" && r.Name == "@") {
+			want = append(want, zoneRecordKey("example.com", r.Type, r.Name, r.Data))
+		}
+	}
+
+	assert.Equal(t, want, got)
+}
+
+func TestParseZone_Invalid(t *testing.T) {
+	cases := []struct {
+		zone string
+		err  string
+	}{
+		{"www A 10.0.0.300\n", `line 1: A www: invalid address "10.0.0.300"`},
+		{"www AAAA 10.0.0.1\n", `line 1: AAAA www: invalid address "10.0.0.1"`},
+		{"@ MX mail.example.com.\n", "line 1: MX @: expected 2 fields of data, found 1"},
+		{"www.other.com. A 10.0.0.1\n", "line 1: www.other.com. is outside example.com"},
+		{"@ TXT \"unterminated\n", "line 1: unterminated string"},
+		{"@ SOA ns1 host (\n1 2 3\n", "line 1: unclosed parenthesis"},
+		{"$INCLUDE other.db\n", "line 1: $INCLUDE isn't supported"},
+		{"  A 10.0.0.1\n", "line 1: record has no name"},
+	}
+
+	for _, c := range cases {
+		_, err := parseZone(strings.NewReader(c.zone), "example.com")
+		assert.EqualError(t, err, c.err, c.zone)
+	}
+}
+
+func TestDomainImport(t *testing.T) {
+	zone := "@ IN A 10.0.0.1\nwww IN CNAME @\n@ IN MX 10 mail.example.com.\n"
+
+	withTempFile(t, zone, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(testZoneRecords, nil)
+			tm.domains.On("CreateRecord", "example.com", &godo.DomainRecordEditRequest{
+				Type: "CNAME", Name: "www", Data: "@",
+			}).Return(&testRecord, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "example.com", path)
+			config.Doit.Set(config.NS, doit.ArgSkipExisting, true)
+			config.Doit.Set(config.NS, doit.ArgForce, true)
+
+			err := RunDomainImport(config)
+			assert.NoError(t, err)
+
+			expected := "! record example.com A @: 10.0.0.1 already exists\n" +
+				"+ record example.com CNAME www: @\n" +
+				"! record example.com MX @: mail.example.com. already exists\n" +
+				"\n1 to create, 0 to update, 0 to delete\n" +
+				"applied 1 changes\n"
+			assert.Equal(t, expected, out.String())
+		})
+	})
+}
+
+func TestDomainImport_Existing(t *testing.T) {
+	withTempFile(t, "@ IN A 10.0.0.1\n", func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(testZoneRecords, nil)
+
+			config.Args = append(config.Args, "example.com", path)
+
+			err := RunDomainImport(config)
+			assert.EqualError(t, err, "record example.com A @ 10.0.0.1 already exists, use --skip-existing to leave it")
+		})
+	})
+}
@@ -16,7 +16,7 @@ package commands
 import (
 	"errors"
 	"fmt"
-	"strconv"
+	"strings"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
@@ -46,7 +46,15 @@ func Domain() *Command {
 	CmdBuilder(cmd, RunDomainGet, "get <domain>", "get domain", Writer,
 		aliasOpt("g"), displayerType(&domain{}), docCategories("domain"))
 
-	CmdBuilder(cmd, RunDomainDelete, "delete <domain>", "delete droplet", Writer, aliasOpt("g"))
+	CmdBuilder(cmd, RunDomainDelete, "delete <domain>", "delete droplet", Writer, aliasOpt("g"),
+		destructiveOpt())
+
+	CmdBuilder(cmd, RunDomainExport, "export <domain>", "write a domain's records as a zone file", Writer,
+		docCategories("domain"))
+
+	cmdDomainImport := CmdBuilder(cmd, RunDomainImport, "import <domain> <zone file>", "create records from a zone file", Writer,
+		docCategories("domain"), destructiveOpt())
+	AddBoolFlag(cmdDomainImport, doit.ArgSkipExisting, false, "Leave records which already exist instead of failing")
 
 	cmdRecord := &Command{
 		Command: &cobra.Command{
@@ -70,10 +78,13 @@ func Domain() *Command {
 	AddIntFlag(cmdRecordCreate, doit.ArgRecordPort, 0, "Record port")
 	AddIntFlag(cmdRecordCreate, doit.ArgRecordWeight, 0, "Record weight")
 
-	CmdBuilder(cmdRecord, RunRecordDelete, "delete <domain> <record id...>", "delete record", Writer,
-		aliasOpt("d"), docCategories("domain"))
+	CmdBuilder(cmdRecord, RunRecordGet, "get <domain> <record id|name|data|name:type>", "get record", Writer,
+		aliasOpt("g"), displayerType(&domainRecord{}), docCategories("domain"))
 
-	cmdRecordUpdate := CmdBuilder(cmdRecord, RunRecordUpdate, "update <domain>", "update record", Writer,
+	CmdBuilder(cmdRecord, RunRecordDelete, "delete <domain> <record id|name|data|name:type...>", "delete record", Writer,
+		aliasOpt("d"), destructiveOpt(), docCategories("domain"))
+
+	cmdRecordUpdate := CmdBuilder(cmdRecord, RunRecordUpdate, "update <domain> [<record id|name|data|name:type>]", "update record", Writer,
 		aliasOpt("u"), displayerType(&domainRecord{}), docCategories("domain"))
 	AddIntFlag(cmdRecordUpdate, doit.ArgRecordID, 0, "Record ID")
 	AddStringFlag(cmdRecordUpdate, doit.ArgRecordType, "", "Record type")
@@ -83,6 +94,17 @@ func Domain() *Command {
 	AddIntFlag(cmdRecordUpdate, doit.ArgRecordPort, 0, "Record port")
 	AddIntFlag(cmdRecordUpdate, doit.ArgRecordWeight, 0, "Record weight")
 
+	cmdRecordSet := CmdBuilder(cmdRecord, RunRecordSet, "set <domain> <name> <type> <data>", "create or update the record with a name and type", Writer,
+		displayerType(&domainRecord{}), docCategories("domain"))
+	AddIntFlag(cmdRecordSet, doit.ArgRecordPriority, 0, "Record priority")
+	AddIntFlag(cmdRecordSet, doit.ArgRecordPort, 0, "Record port")
+	AddIntFlag(cmdRecordSet, doit.ArgRecordWeight, 0, "Record weight")
+
+	cmdRecordSync := CmdBuilder(cmdRecord, RunRecordSync, "sync <domain>", "make a domain's records match a records file", Writer,
+		docCategories("domain"), destructiveOpt())
+	AddStringFlagP(cmdRecordSync, doit.ArgManifestFile, "f", "", "YAML file listing the domain's records")
+	AddBoolFlag(cmdRecordSync, doit.ArgPrune, false, "Delete records which aren't in the file")
+
 	return cmd
 }
 
@@ -162,7 +184,12 @@ func RunDomainDelete(c *CmdConfig) error {
 		return errors.New("invalid domain name")
 	}
 
-	err := ds.Delete(name)
+	ok, err := confirmDestructive(c, "delete", "domain", staticDescription(name))
+	if err != nil || !ok {
+		return err
+	}
+
+	err = ds.Delete(name)
 	return err
 }
 
@@ -241,6 +268,10 @@ func RunRecordCreate(c *CmdConfig) error {
 		return errors.New("record request is missing type")
 	}
 
+	if err := validateRecord(drcr); err != nil {
+		return err
+	}
+
 	r, err := ds.CreateRecord(name, drcr)
 	if err != nil {
 		return err
@@ -251,26 +282,61 @@ func RunRecordCreate(c *CmdConfig) error {
 
 }
 
+// RunRecordGet retrieves a domain record.
+func RunRecordGet(c *CmdConfig) error {
+	if len(c.Args) != 2 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	r, err := c.Resolver().Record(c.Args[0], c.Args[1])
+	if err != nil {
+		return err
+	}
+
+	item := &domainRecord{domainRecords: do.DomainRecords{*r}}
+	return c.Display(item)
+}
+
 // RunRecordDelete deletes a domain record.
 func RunRecordDelete(c *CmdConfig) error {
 	if len(c.Args) < 2 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	domainName, ids := c.Args[0], c.Args[1:]
-	if len(ids) < 1 {
+	domainName, refs := c.Args[0], c.Args[1:]
+	if len(refs) < 1 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
 	ds := c.Domains()
 
-	for _, i := range ids {
-		id, err := strconv.Atoi(i)
+	res := c.Resolver()
+	records, err := res.Records(domainName, refs...)
+	if err != nil {
+		return err
+	}
+
+	describe := func() ([]string, error) {
+		loaded, err := res.LoadRecords(domainName, records)
 		if err != nil {
-			return fmt.Errorf("invalid record id %q", i)
+			return nil, err
 		}
 
-		err = ds.DeleteRecord(domainName, id)
+		var out []string
+		for _, r := range loaded {
+			out = append(out, fmt.Sprintf("%s %s %s (%d)", r.Name, r.Type, r.Data, r.ID))
+		}
+
+		return out, nil
+	}
+
+	ok, err := confirmDestructive(c, "delete", "records from "+domainName, describe)
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, r := range records {
+		err := ds.DeleteRecord(domainName, r.ID)
 		if err != nil {
 			return err
 		}
@@ -279,9 +345,10 @@ func RunRecordDelete(c *CmdConfig) error {
 	return nil
 }
 
-// RunRecordUpdate updates a domain record.
+// RunRecordUpdate updates a domain record. The record is given as an
+// argument or with --record-id.
 func RunRecordUpdate(c *CmdConfig) error {
-	if len(c.Args) != 1 {
+	if len(c.Args) < 1 || len(c.Args) > 2 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 	domainName := c.Args[0]
@@ -293,6 +360,19 @@ func RunRecordUpdate(c *CmdConfig) error {
 		return err
 	}
 
+	var existing *do.DomainRecord
+	if len(c.Args) == 2 {
+		existing, err = c.Resolver().Record(domainName, c.Args[1])
+		if err != nil {
+			return err
+		}
+		recordID = existing.ID
+	}
+
+	if recordID == 0 {
+		return fmt.Errorf("a record is required, give one or use --%s", doit.ArgRecordID)
+	}
+
 	rType, err := c.Doit.GetString(c.NS, doit.ArgRecordType)
 	if err != nil {
 		return err
@@ -323,13 +403,23 @@ func RunRecordUpdate(c *CmdConfig) error {
 		return err
 	}
 
-	drcr := &godo.DomainRecordEditRequest{
+	if existing == nil {
+		if existing, err = ds.Record(domainName, recordID); err != nil {
+			return err
+		}
+	}
+
+	drcr := mergeRecord(existing, &godo.DomainRecordEditRequest{
 		Type:     rType,
 		Name:     rName,
 		Data:     rData,
 		Priority: rPriority,
 		Port:     rPort,
 		Weight:   rWeight,
+	})
+
+	if err := validateRecord(drcr); err != nil {
+		return err
 	}
 
 	r, err := ds.EditRecord(domainName, recordID, drcr)
@@ -340,3 +430,149 @@ func RunRecordUpdate(c *CmdConfig) error {
 	item := &domainRecord{domainRecords: do.DomainRecords{*r}}
 	return c.Display(item)
 }
+
+// mergeRecord returns a record with the fields given in an update in place
+// of the existing record's, so the whole record can be checked. Existing
+// host names are fully qualified, the way they are given to the API.
+func mergeRecord(existing *do.DomainRecord, update *godo.DomainRecordEditRequest) *godo.DomainRecordEditRequest {
+	r := &godo.DomainRecordEditRequest{
+		Type:     existing.Type,
+		Name:     existing.Name,
+		Data:     existing.Data,
+		Priority: existing.Priority,
+		Port:     existing.Port,
+		Weight:   existing.Weight,
+	}
+
+	if update.Type != "" {
+		r.Type = update.Type
+	}
+	if update.Name != "" {
+		r.Name = update.Name
+	}
+	if update.Priority != 0 {
+		r.Priority = update.Priority
+	}
+	if update.Port != 0 {
+		r.Port = update.Port
+	}
+	if update.Weight != 0 {
+		r.Weight = update.Weight
+	}
+
+	if update.Data != "" {
+		r.Data = update.Data
+	} else {
+		switch strings.ToUpper(r.Type) {
+		case "CNAME", "MX", "NS", "SRV":
+			r.Data = zoneTarget(r.Data)
+		}
+	}
+
+	return r
+}
+
+// RunRecordSet makes sure a domain has a record with a name, type and
+// data. A record with the same name and type is updated if there is one,
+// otherwise the record is created. Nothing changes if the record is
+// already set.
+func RunRecordSet(c *CmdConfig) error {
+	if len(c.Args) != 4 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+	domainName := c.Args[0]
+
+	rPriority, err := c.Doit.GetInt(c.NS, doit.ArgRecordPriority)
+	if err != nil {
+		return err
+	}
+
+	rPort, err := c.Doit.GetInt(c.NS, doit.ArgRecordPort)
+	if err != nil {
+		return err
+	}
+
+	rWeight, err := c.Doit.GetInt(c.NS, doit.ArgRecordWeight)
+	if err != nil {
+		return err
+	}
+
+	drcr := &godo.DomainRecordEditRequest{
+		Type:     strings.ToUpper(c.Args[2]),
+		Name:     c.Args[1],
+		Data:     c.Args[3],
+		Priority: rPriority,
+		Port:     rPort,
+		Weight:   rWeight,
+	}
+
+	if err := validateRecord(drcr); err != nil {
+		return err
+	}
+
+	ds := c.Domains()
+
+	list, err := ds.Records(domainName)
+	if err != nil {
+		return err
+	}
+
+	existing, err := recordToSet(domainName, drcr, list)
+	if err != nil {
+		return err
+	}
+
+	var r *do.DomainRecord
+	switch {
+	case existing == nil:
+		r, err = ds.CreateRecord(domainName, drcr)
+	case zoneRecordKey(domainName, existing.Type, existing.Name, existing.Data) ==
+		zoneRecordKey(domainName, drcr.Type, drcr.Name, drcr.Data) &&
+		existing.Priority == drcr.Priority && existing.Port == drcr.Port && existing.Weight == drcr.Weight:
+		r = existing
+	default:
+		r, err = ds.EditRecord(domainName, existing.ID, drcr)
+	}
+	if err != nil {
+		return err
+	}
+
+	item := &domainRecord{domainRecords: do.DomainRecords{*r}}
+	return c.Display(item)
+}
+
+// recordToSet finds the record set should update: the record with the same
+// name, type and data, or else the only record with the same name and type.
+// It returns nil if the record should be created.
+func recordToSet(domain string, drcr *godo.DomainRecordEditRequest, list do.DomainRecords) (*do.DomainRecord, error) {
+	var matches do.DomainRecords
+	for _, r := range list {
+		if strings.ToUpper(r.Type) == drcr.Type && strings.EqualFold(r.Name, drcr.Name) {
+			matches = append(matches, r)
+		}
+	}
+
+	key := zoneRecordKey(domain, drcr.Type, drcr.Name, drcr.Data)
+	for _, r := range matches {
+		if zoneRecordKey(domain, r.Type, r.Name, r.Data) == key {
+			return &r, nil
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, nil
+	case 1:
+		return &matches[0], nil
+	}
+
+	var labels []string
+	for _, r := range matches {
+		labels = append(labels, fmt.Sprintf("%s (%d)", recordSummary(&godo.DomainRecordEditRequest{
+			Type: r.Type, Data: r.Data, Priority: r.Priority, Port: r.Port, Weight: r.Weight,
+		}), r.ID))
+	}
+
+	return nil, fmt.Errorf("%s has %d %s records, use update to choose one of %s",
+		drcr.Name, len(matches), drcr.Type, strings.Join(labels, ", "))
+}
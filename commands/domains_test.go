@@ -34,7 +34,7 @@ var (
 func TestDomainsCommand(t *testing.T) {
 	cmd := Domain()
 	assert.NotNil(t, cmd)
-	assertCommandNames(t, cmd, "create", "list", "get", "delete", "records")
+	assertCommandNames(t, cmd, "create", "list", "get", "delete", "export", "import", "records")
 }
 
 func TestDomainsCreate(t *testing.T) {
@@ -80,6 +80,7 @@ func TestDomainsDelete(t *testing.T) {
 		tm.domains.On("Delete", "example.com").Return(nil)
 
 		config.Args = append(config.Args, testDomain.Name)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDomainDelete(config)
 		assert.NoError(t, err)
@@ -139,6 +140,7 @@ func TestRecordsDelete(t *testing.T) {
 		tm.domains.On("DeleteRecord", "example.com", 1).Return(nil)
 
 		config.Args = append(config.Args, "example.com", "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunRecordDelete(config)
 		assert.NoError(t, err)
@@ -148,6 +150,7 @@ func TestRecordsDelete(t *testing.T) {
 func TestRecordsUpdate(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		dcer := &godo.DomainRecordEditRequest{Type: "A", Name: "foo.example.com.", Data: "192.168.1.1", Priority: 0, Port: 0, Weight: 0}
+		tm.domains.On("Record", "example.com", 1).Return(&testRecord, nil)
 		tm.domains.On("EditRecord", "example.com", 1, dcer).Return(&testRecord, nil)
 
 		config.Doit.Set(config.NS, doit.ArgRecordID, 1)
@@ -161,3 +164,115 @@ func TestRecordsUpdate(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+var testSelectorRecords = do.DomainRecords{
+	{DomainRecord: &godo.DomainRecord{ID: 10, Name: "www", Type: "A", Data: "192.168.1.1"}},
+	{DomainRecord: &godo.DomainRecord{ID: 11, Name: "@", Type: "MX", Data: "mx1.example.com", Priority: 10}},
+	{DomainRecord: &godo.DomainRecord{ID: 12, Name: "@", Type: "MX", Data: "mx2.example.com", Priority: 20}},
+}
+
+func TestRecordsGet(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.On("Records", "example.com").Return(testSelectorRecords, nil)
+
+		config.Args = append(config.Args, "example.com", "@:MX:20")
+
+		err := RunRecordGet(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRecordsUpdate_Selector(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		dcer := &godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1.2"}
+		tm.domains.On("Records", "example.com").Return(testSelectorRecords, nil)
+		tm.domains.On("EditRecord", "example.com", 10, dcer).Return(&testRecord, nil)
+
+		config.Doit.Set(config.NS, doit.ArgRecordType, "A")
+		config.Doit.Set(config.NS, doit.ArgRecordName, "www")
+		config.Doit.Set(config.NS, doit.ArgRecordData, "192.168.1.2")
+
+		config.Args = append(config.Args, "example.com", "www:a")
+
+		err := RunRecordUpdate(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestRecordsUpdate_RecordRequired(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "example.com")
+
+		err := RunRecordUpdate(config)
+		assert.EqualError(t, err, "a record is required, give one or use --record-id")
+	})
+}
+
+func TestRecordsSet(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		priority int
+		create   *godo.DomainRecordEditRequest
+		editID   int
+		edit     *godo.DomainRecordEditRequest
+	}{
+		{
+			name:   "create",
+			args:   []string{"example.com", "api", "a", "192.168.1.5"},
+			create: &godo.DomainRecordEditRequest{Type: "A", Name: "api", Data: "192.168.1.5"},
+		},
+		{
+			name:   "change data",
+			args:   []string{"example.com", "www", "A", "192.168.1.2"},
+			editID: 10,
+			edit:   &godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1.2"},
+		},
+		{
+			name: "unchanged",
+			args: []string{"example.com", "www", "A", "192.168.1.1"},
+		},
+		{
+			name:     "change priority",
+			args:     []string{"example.com", "@", "MX", "mx2.example.com."},
+			priority: 30,
+			editID:   12,
+			edit:     &godo.DomainRecordEditRequest{Type: "MX", Name: "@", Data: "mx2.example.com.", Priority: 30},
+		},
+		{
+			name:     "unchanged with trailing dot",
+			args:     []string{"example.com", "@", "MX", "mx1.example.com."},
+			priority: 10,
+		},
+	}
+
+	for _, tc := range cases {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.domains.On("Records", "example.com").Return(testSelectorRecords, nil)
+			if tc.create != nil {
+				tm.domains.On("CreateRecord", "example.com", tc.create).Return(&testRecord, nil)
+			}
+			if tc.edit != nil {
+				tm.domains.On("EditRecord", "example.com", tc.editID, tc.edit).Return(&testRecord, nil)
+			}
+
+			config.Doit.Set(config.NS, doit.ArgRecordPriority, tc.priority)
+			config.Args = append(config.Args, tc.args...)
+
+			err := RunRecordSet(config)
+			assert.NoError(t, err, tc.name)
+		})
+	}
+}
+
+func TestRecordsSet_Ambiguous(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.domains.On("Records", "example.com").Return(testSelectorRecords, nil)
+
+		config.Doit.Set(config.NS, doit.ArgRecordPriority, 30)
+		config.Args = append(config.Args, "example.com", "@", "MX", "mx3.example.com.")
+
+		err := RunRecordSet(config)
+		assert.EqualError(t, err, "@ has 2 MX records, use update to choose one of 10 mx1.example.com (11), 20 mx2.example.com (12)")
+	})
+}
@@ -18,36 +18,70 @@ import (
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
 	"github.com/spf13/cobra"
 )
 
-type actionFn func(das do.DropletActionsService) (*do.Action, error)
+type actionFn func(das do.DropletActionsService, id int) (*do.Action, error)
 
+// performAction performs an action on every droplet selected by the
+// command's arguments.
 func performAction(c *CmdConfig, fn actionFn) error {
-	das := c.DropletActions()
-
-	a, err := fn(das)
-	if err != nil {
-		return err
+	if len(c.Args) < 1 {
+		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	wait, err := c.Doit.GetBool(c.NS, doit.ArgCommandWait)
+	droplets, err := c.Resolver().Droplets(c.Args...)
 	if err != nil {
 		return err
 	}
 
-	if wait {
-		a, err = actionWait(c, a.ID, 5)
+	return performActionOn(c, droplets, fn)
+}
+
+func performActionOn(c *CmdConfig, droplets do.Droplets, fn actionFn) error {
+	das := c.DropletActions()
+
+	var actions do.Actions
+	for _, d := range droplets {
+		a, err := fn(das, d.ID)
 		if err != nil {
 			return err
 		}
 
+		actions = append(actions, *a)
 	}
 
-	item := &action{actions: do.Actions{*a}}
+	actions, err := waitIfRequested(c, actions)
+	if err != nil {
+		return err
+	}
+
+	item := &action{actions: actions}
 	return c.Display(item)
 }
 
+// performDestructiveAction confirms with the user before performing an
+// action which interrupts or replaces droplets.
+func performDestructiveAction(c *CmdConfig, operation string, fn actionFn) error {
+	if len(c.Args) < 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	r := c.Resolver()
+	droplets, err := r.Droplets(c.Args...)
+	if err != nil {
+		return err
+	}
+
+	ok, err := confirmDestructive(c, operation, "droplets", describeDroplets(r, droplets))
+	if err != nil || !ok {
+		return err
+	}
+
+	return performActionOn(c, droplets, fn)
+}
+
 // DropletAction creates the droplet-action command.
 func DropletAction() *Command {
 	cmd := &Command{
@@ -59,226 +93,154 @@ func DropletAction() *Command {
 		},
 	}
 
-	cmdDropletActionGet := CmdBuilder(cmd, RunDropletActionGet, "get", "get droplet action", Writer,
+	cmdDropletActionGet := CmdBuilder(cmd, RunDropletActionGet, "get <droplet>", "get droplet action", Writer,
 		aliasOpt("g"), displayerType(&action{}), docCategories("droplet"))
 	AddIntFlag(cmdDropletActionGet, doit.ArgActionID, 0, "Action ID", requiredOpt())
 
-	cmdDropletActionDisableBackups := CmdBuilder(cmd, RunDropletActionDisableBackups,
-		"disable-backups <droplet-id>", "disable backups", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionDisableBackups, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionReboot := CmdBuilder(cmd, RunDropletActionReboot,
-		"reboot <droplet-id>", "reboot droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionReboot, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionPowerCycle := CmdBuilder(cmd, RunDropletActionPowerCycle,
-		"power-cycle <droplet-id>", "power cycle droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionPowerCycle, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionShutdown := CmdBuilder(cmd, RunDropletActionShutdown,
-		"shutdown <droplet-id>", "shutdown droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionShutdown, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionPowerOff := CmdBuilder(cmd, RunDropletActionPowerOff,
-		"power-off <droplet-id>", "power off droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionPowerOff, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionPowerOn := CmdBuilder(cmd, RunDropletActionPowerOn,
-		"power-on <droplet-id>", "power on droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionPowerOn, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionPasswordReset := CmdBuilder(cmd, RunDropletActionPasswordReset,
-		"power-reset <droplet-id>", "power reset droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionPasswordReset, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionEnableIPv6 := CmdBuilder(cmd, RunDropletActionEnableIPv6,
-		"enable-ipv6 <droplet-id>", "enable ipv6", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionEnableIPv6, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionEnablePrivateNetworking := CmdBuilder(cmd, RunDropletActionEnablePrivateNetworking,
-		"enable-private-networking <droplet-id>", "enable private networking", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionEnablePrivateNetworking, doit.ArgCommandWait, false, "Wait for action to complete")
-
-	cmdDropletActionUpgrade := CmdBuilder(cmd, RunDropletActionUpgrade,
-		"upgrade <droplet-id>", "upgrade droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddBoolFlag(cmdDropletActionUpgrade, doit.ArgCommandWait, false, "Wait for action to complete")
+	CmdBuilder(cmd, RunDropletActionDisableBackups,
+		"disable-backups <droplet> [droplet ...]", "disable backups", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionReboot,
+		"reboot <droplet> [droplet ...]", "reboot droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionPowerCycle,
+		"power-cycle <droplet> [droplet ...]", "power cycle droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionShutdown,
+		"shutdown <droplet> [droplet ...]", "shutdown droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionPowerOff,
+		"power-off <droplet> [droplet ...]", "power off droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionPowerOn,
+		"power-on <droplet> [droplet ...]", "power on droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionPasswordReset,
+		"power-reset <droplet> [droplet ...]", "power reset droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionEnableIPv6,
+		"enable-ipv6 <droplet> [droplet ...]", "enable ipv6", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionEnablePrivateNetworking,
+		"enable-private-networking <droplet> [droplet ...]", "enable private networking", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
+
+	CmdBuilder(cmd, RunDropletActionUpgrade,
+		"upgrade <droplet> [droplet ...]", "upgrade droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
 
 	cmdDropletActionRestore := CmdBuilder(cmd, RunDropletActionRestore,
-		"restore <droplet-id>", "restore backup", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddIntFlag(cmdDropletActionRestore, doit.ArgImageID, 0, "Image ID", requiredOpt())
-	AddBoolFlag(cmdDropletActionRestore, doit.ArgCommandWait, false, "Wait for action to complete")
+		"restore <droplet> [droplet ...]", "restore backup", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+	AddStringFlag(cmdDropletActionRestore, doit.ArgImageID, "", "ID or name of the snapshot or backup", requiredOpt())
 
 	cmdDropletActionResize := CmdBuilder(cmd, RunDropletActionResize,
-		"resize <droplet-id>", "resize droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
+		"resize <droplet> [droplet ...]", "resize droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
 	AddBoolFlag(cmdDropletActionResize, doit.ArgResizeDisk, false, "Resize disk")
 	AddStringFlag(cmdDropletActionResize, doit.ArgSizeSlug, "", "New size")
-	AddBoolFlag(cmdDropletActionResize, doit.ArgCommandWait, false, "Wait for action to complete")
 
 	cmdDropletActionRebuild := CmdBuilder(cmd, RunDropletActionRebuild,
-		"rebuild <droplet-id>", "rebuild droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
-	AddIntFlag(cmdDropletActionRebuild, doit.ArgImageID, 0, "Image ID", requiredOpt())
-	AddBoolFlag(cmdDropletActionRebuild, doit.ArgCommandWait, false, "Wait for action to complete")
+		"rebuild <droplet> [droplet ...]", "rebuild droplet", Writer,
+		displayerType(&action{}), destructiveOpt(), actionWaitOpt(), docCategories("droplet"))
+	AddStringFlag(cmdDropletActionRebuild, doit.ArgImage, "", "Image ID, slug or name", requiredOpt())
 
 	cmdDropletActionRename := CmdBuilder(cmd, RunDropletActionRename,
-		"rename <droplet-id>", "rename droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
+		"rename <droplet>", "rename droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
 	AddStringFlag(cmdDropletActionRename, doit.ArgDropletName, "", "Droplet name", requiredOpt())
-	AddBoolFlag(cmdDropletActionRename, doit.ArgCommandWait, false, "Wait for action to complete")
 
 	cmdDropletActionChangeKernel := CmdBuilder(cmd, RunDropletActionChangeKernel,
-		"change-kernel <droplet-id>", "change kernel", Writer,
-		docCategories("droplet"))
+		"change-kernel <droplet> [droplet ...]", "change kernel", Writer,
+		actionWaitOpt(), docCategories("droplet"))
 	AddIntFlag(cmdDropletActionChangeKernel, doit.ArgKernelID, 0, "Kernel ID", requiredOpt())
-	AddBoolFlag(cmdDropletActionChangeKernel, doit.ArgCommandWait, false, "Wait for action to complete")
 
 	cmdDropletActionSnapshot := CmdBuilder(cmd, RunDropletActionSnapshot,
-		"snapshot <droplet-id>", "snapshot droplet", Writer,
-		displayerType(&action{}), docCategories("droplet"))
+		"snapshot <droplet>", "snapshot droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("droplet"))
 	AddStringFlag(cmdDropletActionSnapshot, doit.ArgSnapshotName, "", "Snapshot name", requiredOpt())
-	AddBoolFlag(cmdDropletActionSnapshot, doit.ArgCommandWait, false, "Wait for action to complete")
 
 	return cmd
 }
 
 // RunDropletActionGet returns a droplet action by id.
 func RunDropletActionGet(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		dropletID, err := strconv.Atoi(c.Args[0])
-		if err != nil {
-			return nil, err
-		}
+	dropletID, err := getDropletIDArg(c)
+	if err != nil {
+		return err
+	}
 
-		actionID, err := c.Doit.GetInt(c.NS, doit.ArgActionID)
-		if err != nil {
-			return nil, err
-		}
+	actionID, err := c.Doit.GetInt(c.NS, doit.ArgActionID)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.Get(dropletID, actionID)
-		return a, err
+	a, err := c.DropletActions().Get(dropletID, actionID)
+	if err != nil {
+		return err
 	}
 
-	return performAction(c, fn)
+	item := &action{actions: do.Actions{*a}}
+	return c.Display(item)
 }
 
-// RunDropletActionDisableBackups disables backups for a droplet.
+// RunDropletActionDisableBackups disables backups for droplets.
 func RunDropletActionDisableBackups(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.DisableBackups(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.DisableBackups(id)
 	}
 
 	return performAction(c, fn)
 }
 
-// RunDropletActionReboot reboots a droplet.
+// RunDropletActionReboot reboots droplets.
 func RunDropletActionReboot(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.Reboot(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Reboot(id)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "reboot", fn)
 }
 
-// RunDropletActionPowerCycle power cycles a droplet.
+// RunDropletActionPowerCycle power cycles droplets.
 func RunDropletActionPowerCycle(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.PowerCycle(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.PowerCycle(id)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "power cycle", fn)
 }
 
-// RunDropletActionShutdown shuts a droplet down.
+// RunDropletActionShutdown shuts droplets down.
 func RunDropletActionShutdown(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		a, err := das.Shutdown(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Shutdown(id)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "shut down", fn)
 }
 
 // RunDropletActionPowerOff turns droplet power off.
 func RunDropletActionPowerOff(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.PowerOff(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.PowerOff(id)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "power off", fn)
 }
 
 // RunDropletActionPowerOn turns droplet power on.
 func RunDropletActionPowerOn(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.PowerOn(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.PowerOn(id)
 	}
 
 	return performAction(c, fn)
@@ -286,206 +248,137 @@ func RunDropletActionPowerOn(c *CmdConfig) error {
 
 // RunDropletActionPasswordReset resets the droplet root password.
 func RunDropletActionPasswordReset(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.PasswordReset(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.PasswordReset(id)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "reset the password of", fn)
 }
 
-// RunDropletActionEnableIPv6 enables IPv6 for a droplet.
+// RunDropletActionEnableIPv6 enables IPv6 for droplets.
 func RunDropletActionEnableIPv6(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.EnableIPv6(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.EnableIPv6(id)
 	}
 
 	return performAction(c, fn)
 }
 
-// RunDropletActionEnablePrivateNetworking enables private networking for a droplet.
+// RunDropletActionEnablePrivateNetworking enables private networking for droplets.
 func RunDropletActionEnablePrivateNetworking(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.EnablePrivateNetworking(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.EnablePrivateNetworking(id)
 	}
 
 	return performAction(c, fn)
 }
 
-// RunDropletActionUpgrade upgrades a droplet.
+// RunDropletActionUpgrade upgrades droplets.
 func RunDropletActionUpgrade(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		a, err := das.Upgrade(id)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Upgrade(id)
 	}
 
 	return performAction(c, fn)
 }
 
-// RunDropletActionRestore restores a droplet using an image id.
+// RunDropletActionRestore restores droplets from a snapshot or backup.
 func RunDropletActionRestore(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
+	ref, err := c.Doit.GetString(c.NS, doit.ArgImageID)
+	if err != nil {
+		return err
+	}
 
-		image, err := c.Doit.GetInt(c.NS, doit.ArgImageID)
-		if err != nil {
-			return nil, err
-		}
+	image, err := c.Resolver().SnapshotID(ref)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.Restore(id, image)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Restore(id, image)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "restore", fn)
 }
 
-// RunDropletActionResize resizesx a droplet giving a size slug and
+// RunDropletActionResize resizes droplets giving a size slug and
 // optionally expands the disk.
 func RunDropletActionResize(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		size, err := c.Doit.GetString(c.NS, doit.ArgSizeSlug)
-		if err != nil {
-			return nil, err
-		}
+	size, err := c.Doit.GetString(c.NS, doit.ArgSizeSlug)
+	if err != nil {
+		return err
+	}
 
-		disk, err := c.Doit.GetBool(c.NS, doit.ArgResizeDisk)
-		if err != nil {
-			return nil, err
-		}
+	disk, err := c.Doit.GetBool(c.NS, doit.ArgResizeDisk)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.Resize(id, size, disk)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Resize(id, size, disk)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "resize", fn)
 }
 
-// RunDropletActionRebuild rebuilds a droplet using an image id or slug.
+// RunDropletActionRebuild rebuilds droplets using an image id, slug or
+// name.
 func RunDropletActionRebuild(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
+	ref, err := c.Doit.GetString(c.NS, doit.ArgImage)
+	if err != nil {
+		return err
+	}
 
+	imageID, slug := 0, ""
+	if i, aerr := strconv.Atoi(ref); aerr == nil {
+		imageID = i
+	} else {
+		image, err := c.Resolver().Image(ref)
 		if err != nil {
-			return nil, err
+			return err
 		}
+		imageID, slug = image.ID, image.Slug
+	}
 
-		image, err := c.Doit.GetString(c.NS, doit.ArgImage)
-		if err != nil {
-			return nil, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		if slug != "" {
+			return das.RebuildByImageSlug(id, slug)
 		}
 
-		var a *do.Action
-		if i, aerr := strconv.Atoi(image); aerr == nil {
-			a, err = das.RebuildByImageID(id, i)
-		} else {
-			a, err = das.RebuildByImageSlug(id, image)
-		}
-		return a, err
+		return das.RebuildByImageID(id, imageID)
 	}
 
-	return performAction(c, fn)
+	return performDestructiveAction(c, "rebuild", fn)
 }
 
 // RunDropletActionRename renames a droplet.
 func RunDropletActionRename(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
+	id, err := getDropletIDArg(c)
+	if err != nil {
+		return err
+	}
 
-		name, err := c.Doit.GetString(c.NS, doit.ArgDropletName)
-		if err != nil {
-			return nil, err
-		}
+	name, err := c.Doit.GetString(c.NS, doit.ArgDropletName)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.Rename(id, name)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Rename(id, name)
 	}
 
-	return performAction(c, fn)
+	return performActionOn(c, do.Droplets{{Droplet: &godo.Droplet{ID: id}}}, fn)
 }
 
-// RunDropletActionChangeKernel changes the kernel for a droplet.
+// RunDropletActionChangeKernel changes the kernel for droplets.
 func RunDropletActionChangeKernel(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
-
-		kernel, err := c.Doit.GetInt(c.NS, doit.ArgKernelID)
-		if err != nil {
-			return nil, err
-		}
+	kernel, err := c.Doit.GetInt(c.NS, doit.ArgKernelID)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.ChangeKernel(id, kernel)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.ChangeKernel(id, kernel)
 	}
 
 	return performAction(c, fn)
@@ -493,24 +386,19 @@ func RunDropletActionChangeKernel(c *CmdConfig) error {
 
 // RunDropletActionSnapshot creates a snapshot for a droplet.
 func RunDropletActionSnapshot(c *CmdConfig) error {
-	fn := func(das do.DropletActionsService) (*do.Action, error) {
-		if len(c.Args) != 1 {
-			return nil, doit.NewMissingArgsErr(c.NS)
-		}
-		id, err := strconv.Atoi(c.Args[0])
-
-		if err != nil {
-			return nil, err
-		}
+	id, err := getDropletIDArg(c)
+	if err != nil {
+		return err
+	}
 
-		name, err := c.Doit.GetString(c.NS, doit.ArgSnapshotName)
-		if err != nil {
-			return nil, err
-		}
+	name, err := c.Doit.GetString(c.NS, doit.ArgSnapshotName)
+	if err != nil {
+		return err
+	}
 
-		a, err := das.Snapshot(id, name)
-		return a, err
+	fn := func(das do.DropletActionsService, id int) (*do.Action, error) {
+		return das.Snapshot(id, name)
 	}
 
-	return performAction(c, fn)
+	return performActionOn(c, do.Droplets{{Droplet: &godo.Droplet{ID: id}}}, fn)
 }
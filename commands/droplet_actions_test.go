@@ -17,6 +17,8 @@ import (
 	"testing"
 
 	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -87,6 +89,7 @@ func TestDropletActionsPasswordReset(t *testing.T) {
 		tm.dropletActions.On("PasswordReset", 1).Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionPasswordReset(config)
 		assert.NoError(t, err)
@@ -98,6 +101,7 @@ func TestDropletActionsPowerCycle(t *testing.T) {
 		tm.dropletActions.On("PowerCycle", 1).Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionPowerCycle(config)
 		assert.NoError(t, err)
@@ -109,6 +113,7 @@ func TestDropletActionsPowerOff(t *testing.T) {
 		tm.dropletActions.On("PowerOff", 1).Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionPowerOff(config)
 		assert.NoError(t, err)
@@ -130,6 +135,7 @@ func TestDropletActionsReboot(t *testing.T) {
 		tm.dropletActions.On("Reboot", 1).Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionReboot(config)
 		assert.NoError(t, err)
@@ -143,6 +149,7 @@ func TestDropletActionsRebuildByImageID(t *testing.T) {
 		config.Args = append(config.Args, "1")
 
 		config.Doit.Set(config.NS, doit.ArgImage, "2")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionRebuild(config)
 		assert.NoError(t, err)
@@ -151,11 +158,14 @@ func TestDropletActionsRebuildByImageID(t *testing.T) {
 
 func TestDropletActionsRebuildByImageSlug(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		image := &do.Image{Image: &godo.Image{ID: 2, Slug: "slug"}}
+		tm.images.On("GetBySlug", "slug").Return(image, nil)
 		tm.dropletActions.On("RebuildByImageSlug", 1, "slug").Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
 
 		config.Doit.Set(config.NS, doit.ArgImage, "slug")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionRebuild(config)
 		assert.NoError(t, err)
@@ -183,12 +193,45 @@ func TestDropletActionsResize(t *testing.T) {
 
 		config.Doit.Set(config.NS, doit.ArgSizeSlug, "1gb")
 		config.Doit.Set(config.NS, doit.ArgResizeDisk, true)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionResize(config)
 		assert.NoError(t, err)
 	})
 }
 
+func TestDropletActionsRebuildByImageName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		snapshot := do.Image{Image: &godo.Image{ID: 7, Name: "web snapshot"}}
+		tm.images.On("List", false).Return(do.Images{snapshot}, nil)
+		tm.dropletActions.On("RebuildByImageID", 1, 7).Return(&testAction, nil)
+
+		config.Args = append(config.Args, "1")
+
+		config.Doit.Set(config.NS, doit.ArgImage, "web snapshot")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
+
+		err := RunDropletActionRebuild(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDropletActionsRestoreBySnapshotName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		snapshot := do.Image{Image: &godo.Image{ID: 7, Name: "nightly-1", Type: "snapshot"}}
+		tm.images.On("ListUser", false).Return(do.Images{snapshot}, nil)
+		tm.dropletActions.On("Restore", 1, 7).Return(&testAction, nil)
+
+		config.Args = append(config.Args, "1")
+
+		config.Doit.Set(config.NS, doit.ArgImageID, "nightly-1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
+
+		err := RunDropletActionRestore(config)
+		assert.NoError(t, err)
+	})
+}
+
 func TestDropletActionsRestore(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		tm.dropletActions.On("Restore", 1, 2).Return(&testAction, nil)
@@ -196,6 +239,7 @@ func TestDropletActionsRestore(t *testing.T) {
 		config.Args = append(config.Args, "1")
 
 		config.Doit.Set(config.NS, doit.ArgImageID, 2)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionRestore(config)
 		assert.NoError(t, err)
@@ -207,6 +251,7 @@ func TestDropletActionsShutdown(t *testing.T) {
 		tm.dropletActions.On("Shutdown", 1).Return(&testAction, nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletActionShutdown(config)
 		assert.NoError(t, err)
@@ -236,3 +281,17 @@ func TestDropletActionsUpgrade(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestDropletActionRebootRegex(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+		tm.dropletActions.On("Reboot", 1).Return(&testAction, nil)
+		tm.dropletActions.On("Reboot", 3).Return(&testAction, nil)
+
+		config.Args = append(config.Args, "re:droplet$")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
+
+		err := RunDropletActionReboot(config)
+		assert.NoError(t, err)
+	})
+}
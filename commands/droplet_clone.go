@@ -0,0 +1,375 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+)
+
+// cloneStateDir returns the directory clone progress is saved in.
+var cloneStateDir = func() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(usr.HomeDir, ".doctl", "clones"), nil
+}
+
+// cloneState records how far a clone has got so an interrupted clone can
+// be resumed. It is removed once the clone has finished.
+type cloneState struct {
+	Source         int    `json:"source"`
+	Region         string `json:"region"`
+	Name           string `json:"name"`
+	Size           string `json:"size"`
+	IPv6           bool   `json:"ipv6"`
+	Private        bool   `json:"private_networking"`
+	SnapshotName   string `json:"snapshot_name"`
+	SnapshotAction int    `json:"snapshot_action,omitempty"`
+	ImageID        int    `json:"image_id,omitempty"`
+	TransferAction int    `json:"transfer_action,omitempty"`
+	DropletID      int    `json:"droplet_id,omitempty"`
+
+	path string
+}
+
+func (s *cloneState) save() error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, b, 0600)
+}
+
+// actionFailed forgets a saved action if it errored, so the step is started
+// again when the clone is resumed instead of waiting on the failed action.
+func (s *cloneState) actionFailed(id *int, err error) error {
+	if _, ok := err.(*actionErroredErr); ok {
+		*id = 0
+		if serr := s.save(); serr != nil {
+			return serr
+		}
+	}
+
+	return err
+}
+
+// check returns an error if the options given to resume a clone differ from
+// the ones it was started with. Empty options take the saved values.
+func (s *cloneState) check(region, name, size string) error {
+	opts := []struct{ flag, given, saved string }{
+		{doit.ArgRegionSlug, region, s.Region},
+		{doit.ArgDropletName, name, s.Name},
+		{doit.ArgSizeSlug, size, s.Size},
+	}
+	for _, o := range opts {
+		if o.given != "" && o.given != o.saved {
+			return fmt.Errorf("clone in %s was started with --%s %s, not %s; run it again with the same options or remove it to start over",
+				s.path, o.flag, o.saved, o.given)
+		}
+	}
+
+	return nil
+}
+
+// loadCloneState reads the saved state of a clone of a droplet to a region.
+// A nil state is returned if there is no clone to resume.
+func loadCloneState(source int, region string) (*cloneState, string, error) {
+	dir, err := cloneStateDir()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, "", err
+	}
+
+	path := filepath.Join(dir, fmt.Sprintf("%d-%s.json", source, region))
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, path, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	var s cloneState
+	if err := json.Unmarshal(b, &s); err != nil {
+		return nil, "", fmt.Errorf("unable to read clone state %s: %v", path, err)
+	}
+	s.path = path
+
+	return &s, path, nil
+}
+
+// RunDropletClone copies a droplet to a region by snapshotting it,
+// transferring the snapshot and creating a droplet from it. Progress is
+// saved after each step, so running the same clone again after an
+// interruption carries on where it stopped.
+func RunDropletClone(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	region, err := c.Doit.GetString(c.NS, doit.ArgRegionSlug)
+	if err != nil {
+		return err
+	}
+
+	if region == "" {
+		return fmt.Errorf("--%s is needed", doit.ArgRegionSlug)
+	}
+
+	name, err := c.Doit.GetString(c.NS, doit.ArgDropletName)
+	if err != nil {
+		return err
+	}
+
+	size, err := c.Doit.GetString(c.NS, doit.ArgSizeSlug)
+	if err != nil {
+		return err
+	}
+
+	cleanup, err := c.Doit.GetBool(c.NS, doit.ArgCleanup)
+	if err != nil {
+		return err
+	}
+
+	src, err := c.Resolver().Droplet(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	state, path, err := loadCloneState(src.ID, region)
+	if err != nil {
+		return err
+	}
+
+	if state != nil {
+		if err := state.check(region, name, size); err != nil {
+			return err
+		}
+		fmt.Fprintf(progressOut, "resuming clone of %s to %s from %s\n", src.Name, region, path)
+	} else {
+		state = newCloneState(src, region, name, size)
+		state.path = path
+	}
+
+	w, err := newActionWaiter(c, 5)
+	if err != nil {
+		return err
+	}
+
+	steps := []func(*CmdConfig, *cloneState, *actionWaiter) error{
+		cloneSnapshot, cloneTransfer, cloneCreate,
+	}
+	for _, step := range steps {
+		if err := step(c, state, w); err != nil {
+			return fmt.Errorf("%v (run the command again to resume)", err)
+		}
+	}
+
+	if cleanup {
+		fmt.Fprintf(progressOut, "deleting snapshot %s\n", state.SnapshotName)
+		if err := c.Images().Delete(state.ImageID); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	d, err := c.Droplets().Get(state.DropletID)
+	if err != nil {
+		return err
+	}
+
+	item := &droplet{droplets: do.Droplets{*d}}
+	return c.Display(item)
+}
+
+func newCloneState(src *do.Droplet, region, name, size string) *cloneState {
+	if name == "" {
+		name = fmt.Sprintf("%s-%s", src.Name, region)
+	}
+
+	if size == "" {
+		size = src.SizeSlug
+		if size == "" && src.Size != nil {
+			size = src.Size.Slug
+		}
+	}
+
+	ipv6, _ := src.PublicIPv6()
+	private, _ := src.PrivateIPv4()
+
+	return &cloneState{
+		Source:       src.ID,
+		Region:       region,
+		Name:         name,
+		Size:         size,
+		IPv6:         ipv6 != "",
+		Private:      private != "",
+		SnapshotName: fmt.Sprintf("%s-clone-%s", src.Name, timeNow().Format("20060102-150405")),
+	}
+}
+
+func cloneSnapshot(c *CmdConfig, s *cloneState, w *actionWaiter) error {
+	if s.ImageID != 0 {
+		return nil
+	}
+
+	if s.SnapshotAction == 0 {
+		fmt.Fprintf(progressOut, "snapshotting droplet %d as %s\n", s.Source, s.SnapshotName)
+		a, err := c.DropletActions().Snapshot(s.Source, s.SnapshotName)
+		if err != nil {
+			return err
+		}
+
+		s.SnapshotAction = a.ID
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.wait(s.SnapshotAction); err != nil {
+		return s.actionFailed(&s.SnapshotAction, err)
+	}
+
+	snapshots, err := c.Droplets().Snapshots(s.Source)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range snapshots {
+		if i.Name == s.SnapshotName {
+			s.ImageID = i.ID
+			return s.save()
+		}
+	}
+
+	return fmt.Errorf("unable to find snapshot %s of droplet %d", s.SnapshotName, s.Source)
+}
+
+func cloneTransfer(c *CmdConfig, s *cloneState, w *actionWaiter) error {
+	if s.DropletID != 0 {
+		return nil
+	}
+
+	if s.TransferAction == 0 {
+		i, err := c.Images().GetByID(s.ImageID)
+		if err != nil {
+			return err
+		}
+
+		for _, r := range i.Regions {
+			if r == s.Region {
+				return nil
+			}
+		}
+
+		fmt.Fprintf(progressOut, "transferring snapshot %s to %s\n", s.SnapshotName, s.Region)
+		a, err := c.ImageActions().Transfer(s.ImageID, &godo.ActionRequest{"region": s.Region})
+		if err != nil {
+			return err
+		}
+
+		s.TransferAction = a.ID
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+
+	if _, err := w.wait(s.TransferAction); err != nil {
+		return s.actionFailed(&s.TransferAction, err)
+	}
+
+	return nil
+}
+
+func cloneCreate(c *CmdConfig, s *cloneState, w *actionWaiter) error {
+	ds := c.Droplets()
+
+	if s.DropletID == 0 {
+		fmt.Fprintf(progressOut, "creating droplet %s in %s\n", s.Name, s.Region)
+		d, err := ds.Create(&godo.DropletCreateRequest{
+			Name:              s.Name,
+			Region:            s.Region,
+			Size:              s.Size,
+			Image:             godo.DropletCreateImage{ID: s.ImageID},
+			IPv6:              s.IPv6,
+			PrivateNetworking: s.Private,
+		}, false)
+		if err != nil {
+			return err
+		}
+
+		s.DropletID = d.ID
+		if err := s.save(); err != nil {
+			return err
+		}
+	}
+
+	actions, err := ds.Actions(s.DropletID)
+	if err != nil {
+		return err
+	}
+
+	var ids []int
+	for _, a := range actions {
+		switch {
+		case a.Status == actionInProgress:
+			ids = append(ids, a.ID)
+		case a.Status == actionErrored && a.Type == "create":
+			return s.createFailed()
+		}
+	}
+
+	if len(ids) == 0 {
+		return nil
+	}
+
+	if _, err := w.wait(ids...); err != nil {
+		if _, ok := err.(*actionErroredErr); ok {
+			return s.createFailed()
+		}
+		return err
+	}
+
+	return nil
+}
+
+// createFailed forgets a droplet which errored while it was created, so the
+// next run creates another rather than finishing with a broken droplet.
+func (s *cloneState) createFailed() error {
+	id := s.DropletID
+	s.DropletID = 0
+	if err := s.save(); err != nil {
+		return err
+	}
+
+	return fmt.Errorf("droplet %d errored while it was created, delete it", id)
+}
@@ -0,0 +1,257 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+var testClone = do.Droplet{
+	Droplet: &godo.Droplet{
+		ID:     5,
+		Name:   "a-droplet-nyc3",
+		Image:  &godo.Image{ID: 20, Name: "a-droplet-clone-20160630-020000"},
+		Region: &godo.Region{Slug: "nyc3", Name: "nyc 3"},
+	},
+}
+
+func withCloneStateDir(t *testing.T, fn func(dir string)) {
+	withTempDir(t, func(dir string) {
+		og := cloneStateDir
+		defer func() { cloneStateDir = og }()
+		cloneStateDir = func() (string, error) { return dir, nil }
+
+		withRotateClock(func() {
+			withActionWaiterStubs(false, func(_ *bytes.Buffer, _ *[]time.Duration) {
+				fn(dir)
+			})
+		})
+	})
+}
+
+func TestDropletClone(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			snapshot := testSnapshot(20, "a-droplet-clone-20160630-020000", "2016-06-30T02:05:00Z")
+			snapshot.Regions = []string{"test0"}
+			dcr := &godo.DropletCreateRequest{
+				Name:              "a-droplet-nyc3",
+				Region:            "nyc3",
+				Image:             godo.DropletCreateImage{ID: 20},
+				PrivateNetworking: true,
+			}
+
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.dropletActions.On("Snapshot", 1, "a-droplet-clone-20160630-020000").Return(testActionWithStatus(2, "in-progress"), nil)
+			tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+			tm.droplets.On("Snapshots", 1).Return(do.Images{snapshot}, nil)
+			tm.images.On("GetByID", 20).Return(&snapshot, nil)
+			tm.imageActions.On("Transfer", 20, &godo.ActionRequest{"region": "nyc3"}).Return(testActionWithStatus(3, "in-progress"), nil)
+			tm.actions.On("Get", 3).Return(testActionWithStatus(3, "completed"), nil)
+			tm.droplets.On("Create", dcr, false).Return(&testClone, nil)
+			tm.droplets.On("Actions", 5).Return(do.Actions{*testActionWithStatus(4, "in-progress")}, nil)
+			tm.actions.On("Get", 4).Return(testActionWithStatus(4, "completed"), nil)
+			tm.images.On("Delete", 20).Return(nil)
+			tm.droplets.On("Get", 5).Return(&testClone, nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+			config.Doit.Set(config.NS, doit.ArgCleanup, true)
+
+			err := RunDropletClone(config)
+			assert.NoError(t, err)
+
+			_, err = os.Stat(filepath.Join(dir, "1-nyc3.json"))
+			assert.True(t, os.IsNotExist(err))
+		})
+	})
+}
+
+func TestDropletClone_Resume(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			state := cloneState{
+				Source:         1,
+				Region:         "nyc3",
+				Name:           "copy",
+				Size:           "1gb",
+				SnapshotName:   "a-droplet-clone-20160629-020000",
+				SnapshotAction: 2,
+				ImageID:        20,
+				TransferAction: 3,
+			}
+			b, err := json.Marshal(state)
+			assert.NoError(t, err)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1-nyc3.json"), b, 0600))
+
+			dcr := &godo.DropletCreateRequest{
+				Name:   "copy",
+				Region: "nyc3",
+				Size:   "1gb",
+				Image:  godo.DropletCreateImage{ID: 20},
+			}
+
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.actions.On("Get", 3).Return(testActionWithStatus(3, "completed"), nil)
+			tm.droplets.On("Create", dcr, false).Return(&testClone, nil)
+			tm.droplets.On("Actions", 5).Return(do.Actions{*testActionWithStatus(4, "completed")}, nil)
+			tm.droplets.On("Get", 5).Return(&testClone, nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+
+			err = RunDropletClone(config)
+			assert.NoError(t, err)
+		})
+	})
+}
+
+func TestDropletClone_SaveOnFailure(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.dropletActions.On("Snapshot", 1, "a-droplet-clone-20160630-020000").Return(testActionWithStatus(2, "in-progress"), nil)
+			tm.actions.On("Get", 2).Return(testActionWithStatus(2, "errored"), nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+
+			err := RunDropletClone(config)
+			assert.Error(t, err)
+
+			b, err := ioutil.ReadFile(filepath.Join(dir, "1-nyc3.json"))
+			assert.NoError(t, err)
+
+			var state cloneState
+			assert.NoError(t, json.Unmarshal(b, &state))
+			assert.Equal(t, 0, state.SnapshotAction)
+			assert.Equal(t, "a-droplet-nyc3", state.Name)
+		})
+	})
+}
+
+func TestDropletClone_ResumeErroredTransfer(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			state := cloneState{
+				Source:         1,
+				Region:         "nyc3",
+				Name:           "copy",
+				Size:           "1gb",
+				SnapshotName:   "a-droplet-clone-20160629-020000",
+				ImageID:        20,
+				TransferAction: 3,
+			}
+			b, err := json.Marshal(state)
+			assert.NoError(t, err)
+			path := filepath.Join(dir, "1-nyc3.json")
+			assert.NoError(t, ioutil.WriteFile(path, b, 0600))
+
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.actions.On("Get", 3).Return(testActionWithStatus(3, "errored"), nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+
+			err = RunDropletClone(config)
+			assert.Error(t, err)
+
+			b, err = ioutil.ReadFile(path)
+			assert.NoError(t, err)
+
+			var saved cloneState
+			assert.NoError(t, json.Unmarshal(b, &saved))
+			assert.Equal(t, 0, saved.TransferAction)
+			assert.Equal(t, 20, saved.ImageID)
+		})
+	})
+}
+
+func TestDropletClone_ResumeDifferentOptions(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			state := cloneState{
+				Source:       1,
+				Region:       "nyc3",
+				Name:         "copy",
+				Size:         "1gb",
+				SnapshotName: "a-droplet-clone-20160629-020000",
+			}
+			b, err := json.Marshal(state)
+			assert.NoError(t, err)
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "1-nyc3.json"), b, 0600))
+
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+			config.Doit.Set(config.NS, doit.ArgSizeSlug, "2gb")
+
+			err = RunDropletClone(config)
+			assert.Error(t, err)
+			assert.Contains(t, err.Error(), "--size 1gb, not 2gb")
+		})
+	})
+}
+
+func TestDropletClone_ResumeErroredCreate(t *testing.T) {
+	withCloneStateDir(t, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			state := cloneState{
+				Source:       1,
+				Region:       "nyc3",
+				Name:         "copy",
+				Size:         "1gb",
+				SnapshotName: "a-droplet-clone-20160629-020000",
+				ImageID:      20,
+				DropletID:    5,
+			}
+			b, err := json.Marshal(state)
+			assert.NoError(t, err)
+			path := filepath.Join(dir, "1-nyc3.json")
+			assert.NoError(t, ioutil.WriteFile(path, b, 0600))
+
+			create := testActionWithStatus(4, "errored")
+			create.Type = "create"
+
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+			tm.droplets.On("Actions", 5).Return(do.Actions{*create}, nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "nyc3")
+
+			err = RunDropletClone(config)
+			assert.EqualError(t, err, "droplet 5 errored while it was created, delete it (run the command again to resume)")
+
+			b, err = ioutil.ReadFile(path)
+			assert.NoError(t, err)
+
+			var saved cloneState
+			assert.NoError(t, json.Unmarshal(b, &saved))
+			assert.Equal(t, 0, saved.DropletID)
+			assert.Equal(t, 20, saved.ImageID)
+		})
+	})
+}
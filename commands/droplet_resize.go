@@ -0,0 +1,166 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+)
+
+const dropletStatusOff = "off"
+
+// RunDropletResize resizes a droplet, powering it off first and back on
+// afterwards if it was running. The droplet is powered back on even if the
+// resize fails.
+func RunDropletResize(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	slug, err := c.Doit.GetString(c.NS, doit.ArgSizeSlug)
+	if err != nil {
+		return err
+	}
+
+	if slug == "" {
+		return fmt.Errorf("--%s is needed", doit.ArgSizeSlug)
+	}
+
+	disk, err := c.Doit.GetBool(c.NS, doit.ArgResizeDisk)
+	if err != nil {
+		return err
+	}
+
+	d, err := c.Resolver().Droplet(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	if err := checkResize(c, d, slug, disk); err != nil {
+		return err
+	}
+
+	ok, err := confirmDestructive(c, "resize to "+slug, "droplet", describeDroplets(c.Resolver(), do.Droplets{*d}))
+	if err != nil || !ok {
+		return err
+	}
+
+	w, err := newActionWaiter(c, 5)
+	if err != nil {
+		return err
+	}
+
+	das := c.DropletActions()
+	wasOn := d.Status != dropletStatusOff
+
+	if wasOn {
+		fmt.Fprintf(progressOut, "%s: powering off\n", d.Name)
+		if err := runDropletAction(w, func() (*do.Action, error) { return das.PowerOff(d.ID) }); err != nil {
+			return fmt.Errorf("unable to power off %s: %v", d.Name, err)
+		}
+	}
+
+	fmt.Fprintf(progressOut, "%s: resizing to %s\n", d.Name, slug)
+	resizeErr := runDropletAction(w, func() (*do.Action, error) { return das.Resize(d.ID, slug, disk) })
+
+	if wasOn {
+		fmt.Fprintf(progressOut, "%s: powering on\n", d.Name)
+		if err := runDropletAction(w, func() (*do.Action, error) { return das.PowerOn(d.ID) }); err != nil {
+			if resizeErr != nil {
+				return fmt.Errorf("unable to resize %s: %v, and unable to power it back on: %v", d.Name, resizeErr, err)
+			}
+			return fmt.Errorf("resized %s but unable to power it back on: %v", d.Name, err)
+		}
+	}
+
+	if resizeErr != nil {
+		return fmt.Errorf("unable to resize %s: %v", d.Name, resizeErr)
+	}
+
+	resized, err := c.Droplets().Get(d.ID)
+	if err != nil {
+		return err
+	}
+
+	item := &droplet{droplets: do.Droplets{*resized}}
+	return c.Display(item)
+}
+
+// checkResize makes sure a droplet can be resized to a size. The size has
+// to be offered in the droplet's region, and it can't have a smaller disk
+// than the droplet has now, as disks can't shrink.
+func checkResize(c *CmdConfig, d *do.Droplet, slug string, disk bool) error {
+	current := d.SizeSlug
+	if current == "" && d.Size != nil {
+		current = d.Size.Slug
+	}
+
+	if current == slug {
+		return fmt.Errorf("%s is already size %s", d.Name, slug)
+	}
+
+	if d.Region != nil {
+		available := false
+		for _, s := range d.Region.Sizes {
+			if s == slug {
+				available = true
+				break
+			}
+		}
+
+		if !available {
+			return fmt.Errorf("size %s isn't available in %s, choose one of: %s",
+				slug, d.Region.Slug, strings.Join(d.Region.Sizes, ", "))
+		}
+	}
+
+	sizes, err := c.Sizes().List()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range sizes {
+		if s.Slug != slug {
+			continue
+		}
+
+		if s.Disk < d.Disk {
+			return fmt.Errorf("size %s has a %dGB disk, which is smaller than the %dGB disk of %s",
+				slug, s.Disk, d.Disk, d.Name)
+		}
+
+		if !disk && s.Disk > d.Disk {
+			fmt.Fprintf(progressOut, "%s: keeping the %dGB disk, use --%s to grow it to %dGB\n",
+				d.Name, d.Disk, doit.ArgResizeDisk, s.Disk)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown size %s", slug)
+}
+
+// runDropletAction starts a droplet action and waits for it to finish.
+func runDropletAction(w *actionWaiter, fn func() (*do.Action, error)) error {
+	a, err := fn()
+	if err != nil {
+		return err
+	}
+
+	_, err = w.wait(a.ID)
+	return err
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+var testResizeSizes = do.Sizes{
+	{Size: &godo.Size{Slug: "512mb", Disk: 20}},
+	{Size: &godo.Size{Slug: "1gb", Disk: 30}},
+	{Size: &godo.Size{Slug: "2gb", Disk: 40}},
+}
+
+func testResizeDroplet(status string, disk int) *do.Droplet {
+	return &do.Droplet{Droplet: &godo.Droplet{
+		ID:       1,
+		Name:     "a-droplet",
+		Status:   status,
+		SizeSlug: "1gb",
+		Disk:     disk,
+		Region:   &godo.Region{Slug: "test0", Sizes: []string{"512mb", "1gb", "2gb"}},
+	}}
+}
+
+func withResize(t *testing.T, d *do.Droplet, size string, fn func(config *CmdConfig, tm *tcMocks)) {
+	withActionWaiterStubs(false, func(_ *bytes.Buffer, _ *[]time.Duration) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(d, nil)
+
+			config.Args = append(config.Args, "1")
+			config.Doit.Set(config.NS, doit.ArgSizeSlug, size)
+			config.Doit.Set(config.NS, doit.ArgForce, true)
+
+			fn(config, tm)
+		})
+	})
+}
+
+func TestDropletResize_PowersOffAndOn(t *testing.T) {
+	d := testResizeDroplet("active", 30)
+	d.Image = testDroplet.Image
+
+	withResize(t, d, "2gb", func(config *CmdConfig, tm *tcMocks) {
+		tm.sizes.On("List").Return(testResizeSizes, nil)
+		tm.dropletActions.On("PowerOff", 1).Return(testActionWithStatus(2, "in-progress"), nil)
+		tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+		tm.dropletActions.On("Resize", 1, "2gb", true).Return(testActionWithStatus(3, "in-progress"), nil)
+		tm.actions.On("Get", 3).Return(testActionWithStatus(3, "completed"), nil)
+		tm.dropletActions.On("PowerOn", 1).Return(testActionWithStatus(4, "in-progress"), nil)
+		tm.actions.On("Get", 4).Return(testActionWithStatus(4, "completed"), nil)
+
+		config.Doit.Set(config.NS, doit.ArgResizeDisk, true)
+
+		err := RunDropletResize(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDropletResize_PowersOnAfterError(t *testing.T) {
+	withResize(t, testResizeDroplet("active", 30), "2gb", func(config *CmdConfig, tm *tcMocks) {
+		tm.sizes.On("List").Return(testResizeSizes, nil)
+		tm.dropletActions.On("PowerOff", 1).Return(testActionWithStatus(2, "in-progress"), nil)
+		tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+		tm.dropletActions.On("Resize", 1, "2gb", false).Return(testActionWithStatus(3, "in-progress"), nil)
+		tm.actions.On("Get", 3).Return(testActionWithStatus(3, "errored"), nil)
+		tm.dropletActions.On("PowerOn", 1).Return(testActionWithStatus(4, "in-progress"), nil)
+		tm.actions.On("Get", 4).Return(testActionWithStatus(4, "completed"), nil)
+
+		err := RunDropletResize(config)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "unable to resize a-droplet")
+	})
+}
+
+func TestDropletResize_DiskCantShrink(t *testing.T) {
+	withResize(t, testResizeDroplet("off", 30), "512mb", func(config *CmdConfig, tm *tcMocks) {
+		tm.sizes.On("List").Return(testResizeSizes, nil)
+
+		err := RunDropletResize(config)
+		assert.EqualError(t, err, "size 512mb has a 20GB disk, which is smaller than the 30GB disk of a-droplet")
+	})
+}
+
+func TestDropletResize_AlreadyOff(t *testing.T) {
+	d := testResizeDroplet("off", 20)
+	d.Image = testDroplet.Image
+
+	withResize(t, d, "512mb", func(config *CmdConfig, tm *tcMocks) {
+		tm.sizes.On("List").Return(testResizeSizes, nil)
+		tm.dropletActions.On("Resize", 1, "512mb", false).Return(testActionWithStatus(3, "in-progress"), nil)
+		tm.actions.On("Get", 3).Return(testActionWithStatus(3, "completed"), nil)
+
+		err := RunDropletResize(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestDropletResize_SizeNotInRegion(t *testing.T) {
+	d := testResizeDroplet("active", 30)
+	d.Region.Sizes = []string{"512mb", "1gb"}
+
+	withResize(t, d, "2gb", func(config *CmdConfig, tm *tcMocks) {
+		err := RunDropletResize(config)
+		assert.EqualError(t, err, "size 2gb isn't available in test0, choose one of: 512mb, 1gb")
+	})
+}
@@ -0,0 +1,246 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/pkg/ssh"
+)
+
+const (
+	waitForSSH       = "ssh"
+	waitForCloudInit = "cloud-init"
+
+	// cloudInitBootFinished is created by cloud-init once it has finished running.
+	cloudInitBootFinished = "/var/lib/cloud/instance/boot-finished"
+)
+
+var (
+	// readinessPollInterval is how long to wait between readiness checks.
+	readinessPollInterval = 5 * time.Second
+
+	// readinessReportInterval is how often a still waiting message is written.
+	readinessReportInterval = 30 * time.Second
+
+	// progressOut is where progress messages are written.
+	progressOut io.Writer = os.Stderr
+
+	// dialSSH checks if something is accepting connections at addr.
+	dialSSH = func(addr string) error {
+		conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
+		if err != nil {
+			return err
+		}
+
+		return conn.Close()
+	}
+
+	// remoteFileExists checks if a file exists on a remote host.
+	remoteFileExists = func(user, host, keyPath string, port int, acceptNew bool, path string) (bool, error) {
+		r := &ssh.Runner{
+			User:    user,
+			Host:    host,
+			KeyPath: keyPath,
+			Port:    port,
+			Options: ssh.Options{AcceptNewHostKeys: acceptNew},
+		}
+
+		return r.FileExists(path)
+	}
+)
+
+// readiness describes what to wait for after a droplet has been created.
+type readiness struct {
+	waitFor   string
+	private   bool
+	timeout   time.Duration
+	user      string
+	keyPath   string
+	port      int
+	acceptNew bool
+}
+
+func newReadiness(c *CmdConfig) (*readiness, error) {
+	waitFor, err := c.Doit.GetString(c.NS, doit.ArgWaitFor)
+	if err != nil {
+		return nil, err
+	}
+
+	switch waitFor {
+	case "", waitForSSH, waitForCloudInit:
+	default:
+		return nil, fmt.Errorf("unknown %s value %q, expected %q or %q",
+			doit.ArgWaitFor, waitFor, waitForSSH, waitForCloudInit)
+	}
+
+	private, err := c.Doit.GetBool(c.NS, doit.ArgPrivate)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := c.Doit.GetInt(c.NS, doit.ArgWaitTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := c.Doit.GetString(c.NS, doit.ArgSSHUser)
+	if err != nil {
+		return nil, err
+	}
+
+	keyPath, err := c.Doit.GetString(c.NS, doit.ArgsSSHKeyPath)
+	if err != nil {
+		return nil, err
+	}
+
+	port, err := c.Doit.GetInt(c.NS, doit.ArgsSSHPort)
+	if err != nil {
+		return nil, err
+	}
+
+	if port == 0 {
+		port = 22
+	}
+
+	acceptNew, err := c.Doit.GetBool(c.NS, doit.ArgAcceptNewHostKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &readiness{
+		waitFor:   waitFor,
+		private:   private,
+		timeout:   time.Duration(timeout) * time.Second,
+		user:      user,
+		keyPath:   keyPath,
+		port:      port,
+		acceptNew: acceptNew,
+	}, nil
+}
+
+// enabled returns true if the droplet should be waited on.
+func (r *readiness) enabled() bool {
+	return r.waitFor != ""
+}
+
+// wait blocks until the droplet is ready or the timeout expires.
+func (r *readiness) wait(d *do.Droplet) error {
+	ip, err := r.address(d)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	deadline := start.Add(r.timeout)
+	addr := net.JoinHostPort(ip, strconv.Itoa(r.port))
+
+	fmt.Fprintf(progressOut, "%s: waiting for ssh on %s\n", d.Name, addr)
+	err = pollUntil(deadline, d.Name, "ssh", func() (bool, error) {
+		return dialSSH(addr) == nil, nil
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(progressOut, "%s: ssh is ready (%s)\n", d.Name, since(start))
+
+	if r.waitFor != waitForCloudInit {
+		return nil
+	}
+
+	user := r.user
+	if user == "" {
+		user = defaultSSHUser(d)
+	}
+
+	fmt.Fprintf(progressOut, "%s: waiting for cloud-init to finish\n", d.Name)
+	err = pollUntil(deadline, d.Name, "cloud-init", func() (bool, error) {
+		// sshd can restart while cloud-init runs, so dropped connections
+		// are treated as not ready yet. Problems with keys won't go away
+		// by waiting and are returned.
+		ok, err := remoteFileExists(user, ip, r.keyPath, r.port, r.acceptNew, cloudInitBootFinished)
+		if err != nil && ssh.IsConnectionError(err) {
+			return false, nil
+		}
+
+		return ok, err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(progressOut, "%s: cloud-init has finished (%s)\n", d.Name, since(start))
+
+	return nil
+}
+
+func (r *readiness) address(d *do.Droplet) (string, error) {
+	var ip string
+	var err error
+	if r.private {
+		ip, err = d.PrivateIPv4()
+	} else {
+		ip, err = d.PublicIPv4()
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	if ip == "" {
+		return "", fmt.Errorf("could not find address for droplet %q", d.Name)
+	}
+
+	return ip, nil
+}
+
+// pollUntil calls fn until it returns true, it returns an error, or the
+// deadline passes.
+func pollUntil(deadline time.Time, name, what string, fn func() (bool, error)) error {
+	start := time.Now()
+	lastReport := start
+
+	for {
+		ok, err := fn()
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			return nil
+		}
+
+		now := time.Now()
+		if now.After(deadline) {
+			return fmt.Errorf("%s: timed out waiting for %s after %s", name, what, since(start))
+		}
+
+		if now.Sub(lastReport) >= readinessReportInterval {
+			fmt.Fprintf(progressOut, "%s: still waiting for %s (%s)\n", name, what, since(start))
+			lastReport = now
+		}
+
+		time.Sleep(readinessPollInterval)
+	}
+}
+
+func since(t time.Time) time.Duration {
+	return time.Since(t) / time.Second * time.Second
+}
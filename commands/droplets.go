@@ -14,7 +14,6 @@ limitations under the License.
 package commands
 
 import (
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"strconv"
@@ -23,8 +22,8 @@ import (
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/resolver"
 	"github.com/digitalocean/godo"
-	"github.com/gobwas/glob"
 	"github.com/spf13/cobra"
 )
 
@@ -47,12 +46,28 @@ func Droplet() *Command {
 	CmdBuilder(cmd, RunDropletBackups, "backups <droplet id>", "droplet backups", Writer,
 		aliasOpt("b"), displayerType(&image{}), docCategories("droplet"))
 
+	cmdDropletClone := CmdBuilder(cmd, RunDropletClone, "clone <droplet>",
+		"copy a droplet to a region through a snapshot", Writer,
+		displayerType(&droplet{}), docCategories("droplet"))
+	AddStringFlag(cmdDropletClone, doit.ArgRegionSlug, "", "Region to clone the droplet to", requiredOpt())
+	AddStringFlag(cmdDropletClone, doit.ArgDropletName, "", "Name of the clone, defaults to <droplet>-<region>")
+	AddStringFlag(cmdDropletClone, doit.ArgSizeSlug, "", "Size of the clone, defaults to the droplet's size")
+	AddBoolFlag(cmdDropletClone, doit.ArgCleanup, false, "Delete the intermediate snapshot once the clone exists")
+	AddIntFlag(cmdDropletClone, doit.ArgWaitTimeout, 0, "Seconds to wait for each step to complete, 0 waits forever")
+
 	cmdDropletCreate := CmdBuilder(cmd, RunDropletCreate, "create NAME [NAME ...]", "create droplet", Writer,
-		aliasOpt("c"), displayerType(&droplet{}), docCategories("droplet"))
-	AddStringSliceFlag(cmdDropletCreate, doit.ArgSSHKeys, []string{}, "SSH Keys or fingerprints")
-	AddStringFlag(cmdDropletCreate, doit.ArgUserData, "", "User data")
-	AddStringFlag(cmdDropletCreate, doit.ArgUserDataFile, "", "User data file")
+		aliasOpt("c"), displayerType(&droplet{}), userDataOpt(), docCategories("droplet"))
+	AddStringSliceFlag(cmdDropletCreate, doit.ArgSSHKeys, []string{}, "SSH key IDs, fingerprints or names")
 	AddBoolFlag(cmdDropletCreate, doit.ArgCommandWait, false, "Wait for droplet to be created")
+	AddStringFlag(cmdDropletCreate, doit.ArgWaitFor, "",
+		"Wait for droplet to be ready after it is created [ssh|cloud-init]")
+	AddIntFlag(cmdDropletCreate, doit.ArgWaitTimeout, 600, "How long to wait for the droplet to be ready in seconds")
+	AddBoolFlag(cmdDropletCreate, doit.ArgPrivate, false, "Wait for the droplet's private address")
+	AddStringFlag(cmdDropletCreate, doit.ArgSSHUser, "", "ssh user used to check cloud-init (default is based on image)")
+	AddStringFlag(cmdDropletCreate, doit.ArgsSSHKeyPath, defaultSSHKeyPath(), "path to private ssh key used to check cloud-init")
+	AddIntFlag(cmdDropletCreate, doit.ArgsSSHPort, 22, "port sshd is running on")
+	AddBoolFlag(cmdDropletCreate, doit.ArgAcceptNewHostKeys, false,
+		"add the droplet's host key to known_hosts when checking cloud-init, which is refused otherwise")
 	AddStringFlag(cmdDropletCreate, doit.ArgRegionSlug, "", "Droplet region",
 		requiredOpt())
 	AddStringFlag(cmdDropletCreate, doit.ArgSizeSlug, "", "Droplet size",
@@ -63,16 +78,25 @@ func Droplet() *Command {
 	AddStringFlag(cmdDropletCreate, doit.ArgImage, "", "Droplet image",
 		requiredOpt())
 
-	CmdBuilder(cmd, RunDropletDelete, "delete ID [ID|Name ...]", "Delete droplet by id or name", Writer,
-		aliasOpt("d", "del", "rm"), docCategories("droplet"))
+	CmdBuilder(cmd, RunDropletDelete, "delete <ID|Name|GLOB|re:REGEX> [...]", "Delete droplets by id, name, glob or regular expression", Writer,
+		aliasOpt("d", "del", "rm"), destructiveOpt(), docCategories("droplet"))
 
-	CmdBuilder(cmd, RunDropletGet, "get", "get droplet", Writer,
+	CmdBuilder(cmd, RunDropletGet, "get <droplet id|name>", "get droplet", Writer,
 		aliasOpt("g"), displayerType(&droplet{}), docCategories("droplet"))
 
+	cmdDropletInventory := CmdBuilder(cmd, RunDropletInventory, "inventory [droplet ...]",
+		"generate an inventory of droplets", Writer, docCategories("droplet"))
+	AddStringFlag(cmdDropletInventory, doit.ArgFormat, inventoryAnsibleINI,
+		"Inventory format: ansible-ini, ansible-yaml, ssh-config or hosts")
+	AddStringFlag(cmdDropletInventory, doit.ArgAddress, string(do.InterfacePublic),
+		"Address to use: public, private or public_ipv6")
+	AddStringFlag(cmdDropletInventory, doit.ArgSSHUser, "", "ssh user, defaults to the image's user")
+	AddStringFlag(cmdDropletInventory, doit.ArgOutFile, "", "File to write the inventory to")
+
 	CmdBuilder(cmd, RunDropletKernels, "kernels <droplet id>", "droplet kernels", Writer,
 		aliasOpt("k"), displayerType(&kernel{}), docCategories("droplet"))
 
-	cmdRunDropletList := CmdBuilder(cmd, RunDropletList, "list [GLOB]", "list droplets", Writer,
+	cmdRunDropletList := CmdBuilder(cmd, RunDropletList, "list [GLOB|re:REGEX ...]", "list droplets", Writer,
 		aliasOpt("ls"), displayerType(&droplet{}), docCategories("droplet"))
 	AddStringFlag(cmdRunDropletList, doit.ArgRegionSlug, "", "Droplet region")
 
@@ -82,6 +106,30 @@ func Droplet() *Command {
 	CmdBuilder(cmd, RunDropletSnapshots, "snapshots <droplet id>", "snapshots", Writer,
 		aliasOpt("s"), displayerType(&image{}), docCategories("droplet"))
 
+	cmdDropletResize := CmdBuilder(cmd, RunDropletResize, "resize <droplet>",
+		"power off, resize and power on a droplet", Writer,
+		displayerType(&droplet{}), destructiveOpt(), docCategories("droplet"))
+	AddStringFlag(cmdDropletResize, doit.ArgSizeSlug, "", "New size", requiredOpt())
+	AddBoolFlag(cmdDropletResize, doit.ArgResizeDisk, false, "Grow the disk too, which can't be undone")
+	AddIntFlag(cmdDropletResize, doit.ArgWaitTimeout, 0, "Seconds to wait for each step to complete, 0 waits forever")
+
+	CmdBuilder(cmd, RunDropletValidateUserData, "validate-user-data [file ...]",
+		"check cloud-config syntax and the size of user data", Writer,
+		userDataOpt(), docCategories("droplet"))
+
+	cmdDropletSnapshotRotate := CmdBuilder(cmd, RunDropletSnapshotRotate, "snapshot-rotate [droplet ...]",
+		"snapshot droplets and delete old snapshots", Writer, destructiveOpt(), docCategories("droplet"))
+	AddStringFlag(cmdDropletSnapshotRotate, doit.ArgTagName, "", "Rotate snapshots of droplets with this tag")
+	AddStringFlag(cmdDropletSnapshotRotate, doit.ArgNameTemplate, defaultSnapshotNameTemplate,
+		"Template for the snapshot name, with .Droplet, .ID and .Now")
+	AddStringFlag(cmdDropletSnapshotRotate, doit.ArgMatch, "",
+		"Glob or re:REGEX matching the names of snapshots to rotate", requiredOpt())
+	AddIntFlag(cmdDropletSnapshotRotate, doit.ArgKeep, 0, "Number of matching snapshots to keep")
+	AddStringFlag(cmdDropletSnapshotRotate, doit.ArgOlderThan, "",
+		"Delete matching snapshots older than this, eg. 30d, 2w or 12h")
+	AddIntFlag(cmdDropletSnapshotRotate, doit.ArgWaitTimeout, 0,
+		"Seconds to wait for each snapshot to complete, 0 waits forever")
+
 	return cmd
 }
 
@@ -90,7 +138,7 @@ func RunDropletActions(c *CmdConfig) error {
 
 	ds := c.Droplets()
 
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -105,7 +153,7 @@ func RunDropletBackups(c *CmdConfig) error {
 
 	ds := c.Droplets()
 
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -156,19 +204,17 @@ func RunDropletCreate(c *CmdConfig) error {
 		return err
 	}
 
-	sshKeys := extractSSHKeys(keys)
-
-	userData, err := c.Doit.GetString(c.NS, doit.ArgUserData)
+	sshKeys, err := resolveSSHKeys(c.Resolver(), extractSSHKeys(keys))
 	if err != nil {
 		return err
 	}
 
-	filename, err := c.Doit.GetString(c.NS, doit.ArgUserDataFile)
+	parts, err := userDataFromFlags(c)
 	if err != nil {
 		return err
 	}
 
-	userData, err = extractUserData(userData, filename)
+	userData, err := assembleUserData(parts)
 	if err != nil {
 		return err
 	}
@@ -187,6 +233,16 @@ func RunDropletCreate(c *CmdConfig) error {
 		return err
 	}
 
+	ready, err := newReadiness(c)
+	if err != nil {
+		return err
+	}
+
+	// the droplet needs to be active before it has an address to wait on.
+	if ready.enabled() {
+		wait = true
+	}
+
 	ds := c.Droplets()
 
 	var wg sync.WaitGroup
@@ -213,6 +269,13 @@ func RunDropletCreate(c *CmdConfig) error {
 				return
 			}
 
+			if ready.enabled() {
+				if err := ready.wait(d); err != nil {
+					errs <- err
+					return
+				}
+			}
+
 			item := &droplet{droplets: do.Droplets{*d}}
 			c.Display(item)
 		}()
@@ -256,6 +319,26 @@ func extractSSHKeys(keys []string) []godo.DropletCreateSSHKey {
 	return sshKeys
 }
 
+// resolveSSHKeys replaces keys given by name with their ids.
+func resolveSSHKeys(r *resolver.Resolver, keys []godo.DropletCreateSSHKey) ([]godo.DropletCreateSSHKey, error) {
+	for i, k := range keys {
+		if k.Fingerprint == "" {
+			continue
+		}
+
+		id, err := r.KeyID(k.Fingerprint)
+		if err != nil {
+			return nil, err
+		}
+
+		if n, err := strconv.Atoi(id); err == nil {
+			keys[i] = godo.DropletCreateSSHKey{ID: n}
+		}
+	}
+
+	return keys, nil
+}
+
 func extractUserData(userData, filename string) (string, error) {
 	if userData == "" && filename != "" {
 		data, err := ioutil.ReadFile(filename)
@@ -268,7 +351,7 @@ func extractUserData(userData, filename string) (string, error) {
 	return userData, nil
 }
 
-// RunDropletDelete destroy a droplet by id.
+// RunDropletDelete destroy droplets by id, name, glob or regular expression.
 func RunDropletDelete(c *CmdConfig) error {
 
 	ds := c.Droplets()
@@ -277,41 +360,24 @@ func RunDropletDelete(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	listedDroplets := false
-	list := do.Droplets{}
-
-	for _, idStr := range c.Args {
-		id, err := strconv.Atoi(idStr)
-		if err != nil {
-			if !listedDroplets {
-				list, err = ds.List()
-				if err != nil {
-					return errors.New("unable to build list of droplets")
-				}
-				listedDroplets = true
-			}
-
-			var matchedDroplet *do.Droplet
-			for _, d := range list {
-				if d.Name == idStr {
-					matchedDroplet = &d
-					break
-				}
-			}
-
-			if matchedDroplet == nil {
-				return fmt.Errorf("unable to find droplet with name %q", idStr)
-			}
+	r := c.Resolver()
+	matched, err := r.Droplets(c.Args...)
+	if err != nil {
+		return err
+	}
 
-			id = matchedDroplet.ID
-		}
+	ok, err := confirmDestructive(c, "delete", "droplets", describeDroplets(r, matched))
+	if err != nil || !ok {
+		return err
+	}
 
-		err = ds.Delete(id)
+	for _, d := range matched {
+		err := ds.Delete(d.ID)
 		if err != nil {
-			return fmt.Errorf("unable to delete droplet %d: %v", id, err)
+			return fmt.Errorf("unable to delete droplet %d: %v", d.ID, err)
 		}
 
-		fmt.Printf("deleted droplet %d\n", id)
+		fmt.Printf("deleted droplet %d\n", d.ID)
 	}
 
 	return nil
@@ -319,7 +385,7 @@ func RunDropletDelete(c *CmdConfig) error {
 
 // RunDropletGet returns a droplet.
 func RunDropletGet(c *CmdConfig) error {
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -339,7 +405,7 @@ func RunDropletGet(c *CmdConfig) error {
 func RunDropletKernels(c *CmdConfig) error {
 
 	ds := c.Droplets()
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -363,14 +429,14 @@ func RunDropletList(c *CmdConfig) error {
 		return err
 	}
 
-	matches := []glob.Glob{}
-	for _, globStr := range c.Args {
-		g, err := glob.Compile(globStr)
+	matches := []*resolver.Pattern{}
+	for _, ref := range c.Args {
+		m, err := resolver.Parse(ref)
 		if err != nil {
-			return fmt.Errorf("unknown glob %q", globStr)
+			return err
 		}
 
-		matches = append(matches, g)
+		matches = append(matches, m)
 	}
 
 	var matchedList do.Droplets
@@ -386,7 +452,7 @@ func RunDropletList(c *CmdConfig) error {
 			skip = false
 		} else {
 			for _, m := range matches {
-				if m.Match(droplet.Name) {
+				if resolver.MatchDroplet(m, droplet) {
 					skip = false
 				}
 			}
@@ -412,7 +478,7 @@ func RunDropletNeighbors(c *CmdConfig) error {
 
 	ds := c.Droplets()
 
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -430,7 +496,7 @@ func RunDropletNeighbors(c *CmdConfig) error {
 func RunDropletSnapshots(c *CmdConfig) error {
 
 	ds := c.Droplets()
-	id, err := getDropletIDArg(c.NS, c.Args)
+	id, err := getDropletIDArg(c)
 	if err != nil {
 		return err
 	}
@@ -444,10 +510,12 @@ func RunDropletSnapshots(c *CmdConfig) error {
 	return c.Display(item)
 }
 
-func getDropletIDArg(ns string, args []string) (int, error) {
-	if len(args) != 1 {
-		return 0, doit.NewMissingArgsErr(ns)
+// getDropletIDArg returns the id of the single droplet selected by the
+// command's argument.
+func getDropletIDArg(c *CmdConfig) (int, error) {
+	if len(c.Args) != 1 {
+		return 0, doit.NewMissingArgsErr(c.NS)
 	}
 
-	return strconv.Atoi(args[0])
+	return c.Resolver().DropletID(c.Args[0])
 }
@@ -14,14 +14,19 @@ limitations under the License.
 package commands
 
 import (
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"net"
 	"strconv"
 	"testing"
+	"time"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
 	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 )
 
 var (
@@ -36,7 +41,7 @@ var (
 func TestDropletCommand(t *testing.T) {
 	cmd := Droplet()
 	assert.NotNil(t, cmd)
-	assertCommandNames(t, cmd, "actions", "backups", "create", "delete", "get", "kernels", "list", "neighbors", "snapshots")
+	assertCommandNames(t, cmd, "actions", "backups", "clone", "create", "delete", "get", "inventory", "kernels", "list", "neighbors", "resize", "snapshot-rotate", "snapshots", "validate-user-data")
 }
 
 func TestDropletActionList(t *testing.T) {
@@ -95,11 +100,137 @@ func TestDropletCreateUserDataFile(t *testing.T) {
 	})
 }
 
+func TestDropletCreateWaitForSSH(t *testing.T) {
+	withReadinessStubs(t, func(dials *[]string, checks *[]string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			dcr := &godo.DropletCreateRequest{Name: "droplet", Region: "dev0", Size: "1gb", Image: godo.DropletCreateImage{ID: 0, Slug: "image"}, SSHKeys: []godo.DropletCreateSSHKey{}}
+			tm.droplets.On("Create", dcr, true).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, "droplet")
+
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "dev0")
+			config.Doit.Set(config.NS, doit.ArgSizeSlug, "1gb")
+			config.Doit.Set(config.NS, doit.ArgImage, "image")
+			config.Doit.Set(config.NS, doit.ArgWaitFor, "ssh")
+			config.Doit.Set(config.NS, doit.ArgWaitTimeout, 10)
+
+			err := RunDropletCreate(config)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"8.8.8.8:22"}, *dials)
+			assert.Empty(t, *checks)
+		})
+	})
+}
+
+func TestDropletCreateWaitForCloudInit(t *testing.T) {
+	withReadinessStubs(t, func(dials *[]string, checks *[]string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			dcr := &godo.DropletCreateRequest{Name: "droplet", Region: "dev0", Size: "1gb", Image: godo.DropletCreateImage{ID: 0, Slug: "image"}, SSHKeys: []godo.DropletCreateSSHKey{}}
+			tm.droplets.On("Create", dcr, true).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, "droplet")
+
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "dev0")
+			config.Doit.Set(config.NS, doit.ArgSizeSlug, "1gb")
+			config.Doit.Set(config.NS, doit.ArgImage, "image")
+			config.Doit.Set(config.NS, doit.ArgWaitFor, "cloud-init")
+			config.Doit.Set(config.NS, doit.ArgPrivate, true)
+			config.Doit.Set(config.NS, doit.ArgWaitTimeout, 10)
+			config.Doit.Set(config.NS, doit.ArgAcceptNewHostKeys, true)
+
+			err := RunDropletCreate(config)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"172.16.1.2:22"}, *dials)
+			assert.Equal(t, []string{"root@172.16.1.2:" + cloudInitBootFinished + " accepting new host keys"}, *checks)
+		})
+	})
+}
+
+func TestDropletCreateWaitForCloudInit_Errors(t *testing.T) {
+	cases := []struct {
+		err    error
+		checks int
+		ok     bool
+	}{
+		{err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}, checks: 2, ok: true},
+		{err: errors.New("ssh: handshake failed: EOF"), checks: 2, ok: true},
+		{err: errors.New("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey]"), checks: 1},
+	}
+
+	for _, c := range cases {
+		withReadinessStubs(t, func(dials *[]string, checks *[]string) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				tm.droplets.On("Create", mock.Anything, true).Return(&testDroplet, nil)
+
+				n := 0
+				remoteFileExists = func(user, host, keyPath string, port int, acceptNew bool, path string) (bool, error) {
+					n++
+					if n == 1 {
+						return false, c.err
+					}
+					return true, nil
+				}
+
+				config.Args = append(config.Args, "droplet")
+				config.Doit.Set(config.NS, doit.ArgRegionSlug, "dev0")
+				config.Doit.Set(config.NS, doit.ArgSizeSlug, "1gb")
+				config.Doit.Set(config.NS, doit.ArgImage, "image")
+				config.Doit.Set(config.NS, doit.ArgWaitFor, "cloud-init")
+				config.Doit.Set(config.NS, doit.ArgWaitTimeout, 10)
+
+				err := RunDropletCreate(config)
+				assert.Equal(t, c.checks, n)
+				if c.ok {
+					assert.NoError(t, err)
+				} else {
+					assert.EqualError(t, err, c.err.Error())
+				}
+			})
+		})
+	}
+}
+
+func TestDropletCreateWaitForInvalid(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "droplet")
+		config.Doit.Set(config.NS, doit.ArgWaitFor, "http")
+
+		err := RunDropletCreate(config)
+		assert.Error(t, err)
+	})
+}
+
+func withReadinessStubs(t *testing.T, fn func(dials *[]string, checks *[]string)) {
+	ogDial, ogExists, ogInterval, ogOut := dialSSH, remoteFileExists, readinessPollInterval, progressOut
+	defer func() {
+		dialSSH, remoteFileExists, readinessPollInterval, progressOut = ogDial, ogExists, ogInterval, ogOut
+	}()
+
+	var dials, checks []string
+	dialSSH = func(addr string) error {
+		dials = append(dials, addr)
+		return nil
+	}
+	remoteFileExists = func(user, host, keyPath string, port int, acceptNew bool, path string) (bool, error) {
+		check := fmt.Sprintf("%s@%s:%s", user, host, path)
+		if acceptNew {
+			check += " accepting new host keys"
+		}
+		checks = append(checks, check)
+		return true, nil
+	}
+	readinessPollInterval = time.Millisecond
+	progressOut = ioutil.Discard
+
+	fn(&dials, &checks)
+}
+
 func TestDropletDelete(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		tm.droplets.On("Delete", 1).Return(nil)
 
 		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletDelete(config)
 		assert.NoError(t, err)
@@ -112,6 +243,7 @@ func TestDropletDeleteByName(t *testing.T) {
 		tm.droplets.On("Delete", 1).Return(nil)
 
 		config.Args = append(config.Args, testDroplet.Name)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunDropletDelete(config)
 		assert.NoError(t, err)
@@ -199,3 +331,17 @@ func Test_extractSSHKey(t *testing.T) {
 		assert.Equal(t, c.expected, got)
 	}
 }
+
+func TestDropletDeleteGlob(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+		tm.droplets.On("Delete", 1).Return(nil)
+		tm.droplets.On("Delete", 3).Return(nil)
+
+		config.Args = append(config.Args, "*droplet")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
+
+		err := RunDropletDelete(config)
+		assert.NoError(t, err)
+	})
+}
@@ -21,6 +21,7 @@ import (
 	"regexp"
 	"testing"
 
+	"github.com/bryanl/doit/pkg/runner"
 	"github.com/fatih/color"
 	"github.com/stretchr/testify/assert"
 )
@@ -44,3 +45,20 @@ func Test_checkErr(t *testing.T) {
 	re := regexp.MustCompile(`an error`)
 	assert.True(t, re.Match(b.Bytes()))
 }
+
+func Test_checkErr_ExitStatus(t *testing.T) {
+	defer func(a func()) { errAction = a }(errAction)
+	defer func(a func(int)) { exitWithStatus = a }(exitWithStatus)
+
+	errAction = func() {
+		t.Fatal("a remote exit status shouldn't be reported as an error")
+	}
+
+	var status int
+	exitWithStatus = func(code int) {
+		status = code
+	}
+
+	checkErr(&runner.ExitError{Status: 3})
+	assert.Equal(t, 3, status)
+}
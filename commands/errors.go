@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"os"
 
+	"github.com/bryanl/doit/pkg/runner"
 	"github.com/fatih/color"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -30,6 +31,9 @@ var (
 	errAction = func() {
 		os.Exit(1)
 	}
+
+	// exitWithStatus exits with the status of a failed remote command.
+	exitWithStatus = os.Exit
 )
 
 type outputErrors struct {
@@ -45,6 +49,13 @@ func checkErr(err error, cmd ...*cobra.Command) {
 		return
 	}
 
+	// a remote command has already reported its own failure, so only its
+	// exit status is passed on.
+	if ee, ok := err.(*runner.ExitError); ok {
+		exitWithStatus(ee.Status)
+		return
+	}
+
 	output := viper.GetString("output")
 
 	switch output {
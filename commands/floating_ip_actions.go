@@ -34,16 +34,16 @@ func FloatingIPAction() *Command {
 	}
 
 	CmdBuilder(cmd, RunFloatingIPActionsGet,
-		"get <floating-ip> <action-id>", "get floating-ip action", Writer,
+		"get <floating-ip|droplet> <action-id>", "get floating-ip action", Writer,
 		displayerType(&action{}), docCategories("floatingip"))
 
 	CmdBuilder(cmd, RunFloatingIPActionsAssign,
-		"assign <floating-ip> <droplet-id>", "assign a floating IP to a droplet", Writer,
-		displayerType(&action{}), docCategories("floatingip"))
+		"assign <floating-ip> <droplet>", "assign a floating IP to a droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("floatingip"))
 
 	CmdBuilder(cmd, RunFloatingIPActionsUnassign,
-		"unassign <floating-ip>", "unassign a floating IP to a droplet", Writer,
-		displayerType(&action{}), docCategories("floatingip"))
+		"unassign <floating-ip|droplet>", "unassign a floating IP to a droplet", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("floatingip"))
 
 	return cmd
 }
@@ -54,7 +54,10 @@ func RunFloatingIPActionsGet(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	ip := c.Args[0]
+	ip, err := c.Resolver().FloatingIPAddress(c.Args[0])
+	if err != nil {
+		return err
+	}
 
 	fia := c.FloatingIPActions()
 
@@ -78,11 +81,15 @@ func RunFloatingIPActionsAssign(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	ip := c.Args[0]
+	r := c.Resolver()
+	ip, err := r.FloatingIPAddress(c.Args[0])
+	if err != nil {
+		return err
+	}
 
 	fia := c.FloatingIPActions()
 
-	dropletID, err := strconv.Atoi(c.Args[1])
+	dropletID, err := r.DropletID(c.Args[1])
 	if err != nil {
 		return err
 	}
@@ -92,7 +99,12 @@ func RunFloatingIPActionsAssign(c *CmdConfig) error {
 		checkErr(fmt.Errorf("could not assign IP to droplet: %v", err))
 	}
 
-	item := &action{actions: do.Actions{*a}}
+	actions, err := waitIfRequested(c, do.Actions{*a})
+	if err != nil {
+		return err
+	}
+
+	item := &action{actions: actions}
 	return c.Display(item)
 }
 
@@ -102,7 +114,10 @@ func RunFloatingIPActionsUnassign(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	ip := c.Args[0]
+	ip, err := c.Resolver().FloatingIPAddress(c.Args[0])
+	if err != nil {
+		return err
+	}
 
 	fia := c.FloatingIPActions()
 
@@ -111,6 +126,11 @@ func RunFloatingIPActionsUnassign(c *CmdConfig) error {
 		checkErr(fmt.Errorf("could not unassign IP to droplet: %v", err))
 	}
 
-	item := &action{actions: do.Actions{*a}}
+	actions, err := waitIfRequested(c, do.Actions{*a})
+	if err != nil {
+		return err
+	}
+
+	item := &action{actions: actions}
 	return c.Display(item)
 }
@@ -48,6 +48,18 @@ func TestFloatingIPActionsAssign(t *testing.T) {
 	})
 }
 
+func TestFloatingIPActionsAssignByName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+		tm.floatingIPActions.On("Assign", "127.0.0.1", testDroplet.ID).Return(&testAction, nil)
+
+		config.Args = append(config.Args, "127.0.0.1", testDroplet.Name)
+
+		err := RunFloatingIPActionsAssign(config)
+		assert.NoError(t, err)
+	})
+}
+
 func TestFloatingIPActionsUnassign(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		tm.floatingIPActions.On("Unassign", "127.0.0.1").Return(&testAction, nil)
@@ -45,10 +45,11 @@ func FloatingIP() *Command {
 		fmt.Sprintf("ID of the droplet to assign the IP to. (mutually exclusive with %s)",
 			doit.ArgRegionSlug))
 
-	CmdBuilder(cmd, RunFloatingIPGet, "get <floating-ip>", "get the details of a floating IP", Writer,
+	CmdBuilder(cmd, RunFloatingIPGet, "get <floating-ip|droplet>", "get the details of a floating IP", Writer,
 		aliasOpt("g"), displayerType(&floatingIP{}), docCategories("floatingip"))
 
-	CmdBuilder(cmd, RunFloatingIPDelete, "delete <floating-ip>", "delete a floating IP address", Writer, aliasOpt("d"))
+	CmdBuilder(cmd, RunFloatingIPDelete, "delete <floating-ip|droplet>", "delete a floating IP address", Writer, aliasOpt("d"),
+		destructiveOpt())
 
 	cmdFloatingIPList := CmdBuilder(cmd, RunFloatingIPList, "list", "list all floating IP addresses", Writer,
 		aliasOpt("ls"), displayerType(&floatingIP{}), docCategories("floatingip"))
@@ -90,8 +91,6 @@ func RunFloatingIPCreate(c *CmdConfig) error {
 
 // RunFloatingIPGet retrieves a floating IP's details.
 func RunFloatingIPGet(c *CmdConfig) error {
-	fis := c.FloatingIPs()
-
 	if len(c.Args) != 1 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
@@ -102,7 +101,7 @@ func RunFloatingIPGet(c *CmdConfig) error {
 		return errors.New("invalid ip address")
 	}
 
-	fip, err := fis.Get(ip)
+	fip, err := c.Resolver().FloatingIP(ip)
 	if err != nil {
 		return err
 	}
@@ -119,7 +118,29 @@ func RunFloatingIPDelete(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	ip := c.Args[0]
+	r := c.Resolver()
+	ip, err := r.FloatingIPAddress(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	describe := func() ([]string, error) {
+		fip, err := r.FloatingIP(ip)
+		if err != nil {
+			return nil, err
+		}
+
+		if fip.Droplet == nil {
+			return []string{fip.IP}, nil
+		}
+
+		return []string{fmt.Sprintf("%s (assigned to %s)", fip.IP, fip.Droplet.Name)}, nil
+	}
+
+	ok, err := confirmDestructive(c, "delete", "floating IP", describe)
+	if err != nil || !ok {
+		return err
+	}
 
 	return fis.Delete(ip)
 }
@@ -91,6 +91,7 @@ func TestFloatingIPsDelete(t *testing.T) {
 		tm.floatingIPs.On("Delete", "127.0.0.1").Return(nil)
 
 		config.Args = append(config.Args, "127.0.0.1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		RunFloatingIPDelete(config)
 	})
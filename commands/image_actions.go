@@ -15,7 +15,6 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
@@ -34,15 +33,14 @@ func ImageAction() *Command {
 	}
 
 	cmdImageActionsGet := CmdBuilder(cmd, RunImageActionsGet,
-		"get <image-id>", "get image action", Writer,
+		"get <image-id|slug|name>", "get image action", Writer,
 		displayerType(&action{}), docCategories("image"))
 	AddIntFlag(cmdImageActionsGet, doit.ArgActionID, 0, "action id", requiredOpt())
 
 	cmdImageActionsTransfer := CmdBuilder(cmd, RunImageActionsTransfer,
-		"transfer <image-id>", "transfer image", Writer,
-		displayerType(&action{}), docCategories("image"))
+		"transfer <image-id|slug|name>", "transfer image", Writer,
+		displayerType(&action{}), actionWaitOpt(), docCategories("image"))
 	AddStringFlag(cmdImageActionsTransfer, doit.ArgRegionSlug, "", "region", requiredOpt())
-	AddBoolFlag(cmdImageActionsTransfer, doit.ArgCommandWait, false, "Wait for action to complete")
 
 	return cmd
 }
@@ -55,7 +53,7 @@ func RunImageActionsGet(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	imageID, err := strconv.Atoi(c.Args[0])
+	imageID, err := c.Resolver().ImageID(c.Args[0])
 	if err != nil {
 		return err
 	}
@@ -82,7 +80,7 @@ func RunImageActionsTransfer(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	id, err := strconv.Atoi(c.Args[0])
+	id, err := c.Resolver().ImageID(c.Args[0])
 	if err != nil {
 		return err
 	}
@@ -101,19 +99,11 @@ func RunImageActionsTransfer(c *CmdConfig) error {
 		checkErr(fmt.Errorf("could not transfer image: %v", err))
 	}
 
-	wait, err := c.Doit.GetBool(c.NS, doit.ArgCommandWait)
+	actions, err := waitIfRequested(c, do.Actions{*a})
 	if err != nil {
 		return err
 	}
 
-	if wait {
-		a, err = actionWait(c, a.ID, 5)
-		if err != nil {
-			return err
-		}
-
-	}
-
-	item := &action{actions: do.Actions{*a}}
+	item := &action{actions: actions}
 	return c.Display(item)
 }
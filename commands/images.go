@@ -15,7 +15,6 @@ package commands
 
 import (
 	"fmt"
-	"strconv"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
@@ -54,15 +53,15 @@ func Images() *Command {
 		displayerType(&image{}), docCategories("image"))
 	AddBoolFlag(cmdImagesListUser, doit.ArgImagePublic, false, "List public images")
 
-	CmdBuilder(cmd, RunImagesGet, "get <image-id|image-slug>", "Get image", Writer,
+	CmdBuilder(cmd, RunImagesGet, "get <image-id|slug|name>", "Get image", Writer,
 		displayerType(&image{}), docCategories("image"))
 
-	cmdImagesUpdate := CmdBuilder(cmd, RunImagesUpdate, "update <image-id>", "Update image", Writer,
+	cmdImagesUpdate := CmdBuilder(cmd, RunImagesUpdate, "update <image-id|slug|name>", "Update image", Writer,
 		displayerType(&image{}), docCategories("image"))
 	AddStringFlag(cmdImagesUpdate, doit.ArgImageName, "", "Image name", requiredOpt())
 
-	CmdBuilder(cmd, RunImagesDelete, "delete <image-id>", "Delete image", Writer,
-		docCategories("image"))
+	CmdBuilder(cmd, RunImagesDelete, "delete <image-id|slug|name>", "Delete image", Writer,
+		destructiveOpt(), docCategories("image"))
 
 	return cmd
 }
@@ -142,27 +141,11 @@ func RunImagesListUser(c *CmdConfig) error {
 
 // RunImagesGet retrieves an image by id or slug.
 func RunImagesGet(c *CmdConfig) error {
-	is := c.Images()
-
 	if len(c.Args) != 1 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	rawID := c.Args[0]
-
-	var i *do.Image
-	var err error
-
-	if id, cerr := strconv.Atoi(rawID); cerr == nil {
-		i, err = is.GetByID(id)
-	} else {
-		if len(rawID) > 0 {
-			i, err = is.GetBySlug(rawID)
-		} else {
-			err = fmt.Errorf("image identifier is required")
-		}
-	}
-
+	i, err := c.Resolver().Image(c.Args[0])
 	if err != nil {
 		return err
 	}
@@ -179,7 +162,7 @@ func RunImagesUpdate(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	id, err := strconv.Atoi(c.Args[0])
+	id, err := c.Resolver().ImageID(c.Args[0])
 	if err != nil {
 		return err
 	}
@@ -207,10 +190,24 @@ func RunImagesDelete(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	id, err := strconv.Atoi(c.Args[0])
+	id, err := c.Resolver().ImageID(c.Args[0])
 	if err != nil {
 		return err
 	}
 
+	describe := func() ([]string, error) {
+		i, err := is.GetByID(id)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{fmt.Sprintf("%s (%d)", i.Name, i.ID)}, nil
+	}
+
+	ok, err := confirmDestructive(c, "delete", "image", describe)
+	if err != nil || !ok {
+		return err
+	}
+
 	return is.Delete(id)
 }
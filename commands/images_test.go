@@ -108,6 +108,7 @@ func TestImagesDelete(t *testing.T) {
 		tm.images.On("Delete", testImage.ID).Return(nil)
 
 		config.Args = append(config.Args, strconv.Itoa(testImage.ID))
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunImagesDelete(config)
 		assert.NoError(t, err)
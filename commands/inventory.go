@@ -0,0 +1,297 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	inventoryAnsibleINI  = "ansible-ini"
+	inventoryAnsibleYAML = "ansible-yaml"
+	inventorySSHConfig   = "ssh-config"
+	inventoryHosts       = "hosts"
+)
+
+// groupNameRE matches characters which can't be used in ansible group names.
+var groupNameRE = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// inventoryHost is a droplet which will be written to an inventory.
+type inventoryHost struct {
+	name    string
+	address string
+	user    string
+}
+
+// inventory is a set of hosts and the groups they belong to.
+type inventory struct {
+	hosts  []inventoryHost
+	groups map[string][]string
+}
+
+// RunDropletInventory writes an inventory of droplets for use by other
+// tools.
+func RunDropletInventory(c *CmdConfig) error {
+	format, err := c.Doit.GetString(c.NS, doit.ArgFormat)
+	if err != nil {
+		return err
+	}
+
+	var write func(io.Writer, *inventory) error
+	switch format {
+	case inventoryAnsibleINI:
+		write = writeAnsibleINI
+	case inventoryAnsibleYAML:
+		write = writeAnsibleYAML
+	case inventorySSHConfig:
+		write = writeSSHConfig
+	case inventoryHosts:
+		write = writeHosts
+	default:
+		return fmt.Errorf("unknown inventory format %q", format)
+	}
+
+	address, err := c.Doit.GetString(c.NS, doit.ArgAddress)
+	if err != nil {
+		return err
+	}
+
+	switch iface := do.InterfaceType(address); iface {
+	case do.InterfacePublic, do.InterfacePrivate, do.InterfacePublicIPv6:
+	default:
+		return fmt.Errorf("unknown address type %q", address)
+	}
+
+	user, err := c.Doit.GetString(c.NS, doit.ArgSSHUser)
+	if err != nil {
+		return err
+	}
+
+	outFile, err := c.Doit.GetString(c.NS, doit.ArgOutFile)
+	if err != nil {
+		return err
+	}
+
+	var droplets do.Droplets
+	if len(c.Args) > 0 {
+		r := c.Resolver()
+		droplets, err = r.Droplets(c.Args...)
+		if err == nil {
+			droplets, err = r.LoadDroplets(droplets)
+		}
+	} else {
+		droplets, err = c.Droplets().List()
+	}
+	if err != nil {
+		return err
+	}
+
+	var tags map[int][]string
+	if format == inventoryAnsibleINI || format == inventoryAnsibleYAML {
+		tags, err = dropletTags(c.Tags(), c.Droplets())
+		if err != nil {
+			return err
+		}
+	}
+
+	inv := buildInventory(droplets, do.InterfaceType(address), user, tags)
+
+	var buf bytes.Buffer
+	if err := write(&buf, inv); err != nil {
+		return err
+	}
+
+	if outFile == "" {
+		_, err := buf.WriteTo(c.Out)
+		return err
+	}
+
+	return writeFileAtomic(outFile, buf.Bytes(), 0644)
+}
+
+// buildInventory builds an inventory from droplets, grouped by region, tag
+// and image. Droplets without an address of the requested type are left
+// out.
+func buildInventory(droplets do.Droplets, iface do.InterfaceType, user string, tags map[int][]string) *inventory {
+	inv := &inventory{groups: map[string][]string{}}
+
+	for i := range droplets {
+		d := &droplets[i]
+
+		address := d.IPs()[iface]
+		if address == "" {
+			fmt.Fprintf(progressOut, "skipping droplet %s: no %s address\n", d.Name, iface)
+			continue
+		}
+
+		hostUser := user
+		if hostUser == "" {
+			hostUser = defaultSSHUser(d)
+		}
+
+		inv.hosts = append(inv.hosts, inventoryHost{name: d.Name, address: address, user: hostUser})
+
+		if d.Region != nil {
+			inv.addToGroup("region_"+d.Region.Slug, d.Name)
+		}
+
+		for _, t := range tags[d.ID] {
+			inv.addToGroup("tag_"+t, d.Name)
+		}
+
+		if d.Image != nil {
+			image := d.Image.Slug
+			if image == "" {
+				image = d.Image.Name
+			}
+			inv.addToGroup("image_"+image, d.Name)
+		}
+	}
+
+	return inv
+}
+
+func (inv *inventory) addToGroup(group, host string) {
+	group = strings.ToLower(groupNameRE.ReplaceAllString(group, "_"))
+	inv.groups[group] = append(inv.groups[group], host)
+}
+
+func (inv *inventory) groupNames() []string {
+	var names []string
+	for name := range inv.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+func writeAnsibleINI(w io.Writer, inv *inventory) error {
+	fmt.Fprintln(w, "[all]")
+	for _, h := range inv.hosts {
+		fmt.Fprintf(w, "%s ansible_host=%s ansible_user=%s\n", h.name, h.address, h.user)
+	}
+
+	for _, g := range inv.groupNames() {
+		fmt.Fprintf(w, "\n[%s]\n", g)
+		for _, h := range inv.groups[g] {
+			fmt.Fprintln(w, h)
+		}
+	}
+
+	return nil
+}
+
+func writeAnsibleYAML(w io.Writer, inv *inventory) error {
+	hosts := map[string]interface{}{}
+	for _, h := range inv.hosts {
+		hosts[h.name] = map[string]string{
+			"ansible_host": h.address,
+			"ansible_user": h.user,
+		}
+	}
+
+	children := map[string]interface{}{}
+	for g, members := range inv.groups {
+		groupHosts := map[string]interface{}{}
+		for _, h := range members {
+			groupHosts[h] = map[string]string{}
+		}
+		children[g] = map[string]interface{}{"hosts": groupHosts}
+	}
+
+	all := map[string]interface{}{"hosts": hosts}
+	if len(children) > 0 {
+		all["children"] = children
+	}
+
+	b, err := yaml.Marshal(map[string]interface{}{"all": all})
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+	return err
+}
+
+func writeSSHConfig(w io.Writer, inv *inventory) error {
+	for i, h := range inv.hosts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "Host %s\n  HostName %s\n  User %s\n", h.name, h.address, h.user)
+	}
+
+	return nil
+}
+
+func writeHosts(w io.Writer, inv *inventory) error {
+	for _, h := range inv.hosts {
+		fmt.Fprintf(w, "%s\t%s\n", h.address, h.name)
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partially written file.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir, base := filepath.Split(path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f, err := ioutil.TempFile(dir, "."+base+".")
+	if err != nil {
+		return err
+	}
+
+	tmp := f.Name()
+	defer os.Remove(tmp)
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Chmod(perm); err != nil {
+		f.Close()
+		return err
+	}
+
+	// the data has to be on disk before the rename, or a crash can leave
+	// an empty file in place of the old one.
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,138 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func runInventory(t *testing.T, format string, setup func(config *CmdConfig)) string {
+	var out bytes.Buffer
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(do.Droplets{testDroplet, testPrivateDroplet}, nil)
+		if format == inventoryAnsibleINI || format == inventoryAnsibleYAML {
+			tm.tags.On("List").Return(do.Tags{{Tag: &godo.Tag{Name: "web"}}}, nil)
+			tm.droplets.On("ListByTag", "web").Return(do.Droplets{testDroplet}, nil)
+		}
+
+		config.Out = &out
+		config.Doit.Set(config.NS, doit.ArgFormat, format)
+		config.Doit.Set(config.NS, doit.ArgAddress, "public")
+		if setup != nil {
+			setup(config)
+		}
+
+		ogOut := progressOut
+		progressOut = ioutil.Discard
+		defer func() { progressOut = ogOut }()
+
+		err := RunDropletInventory(config)
+		assert.NoError(t, err)
+	})
+
+	return out.String()
+}
+
+func TestDropletInventory_AnsibleINI(t *testing.T) {
+	out := runInventory(t, inventoryAnsibleINI, nil)
+
+	expected := `[all]
+a-droplet ansible_host=8.8.8.8 ansible_user=root
+
+[image_an_image]
+a-droplet
+
+[region_test0]
+a-droplet
+
+[tag_web]
+a-droplet
+`
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventory_AnsibleYAML(t *testing.T) {
+	out := runInventory(t, inventoryAnsibleYAML, nil)
+
+	assert.Contains(t, out, "all:\n")
+	assert.Contains(t, out, "ansible_host: 8.8.8.8")
+	assert.Contains(t, out, "tag_web:\n      hosts:\n        a-droplet: {}\n")
+}
+
+func TestDropletInventory_SSHConfig(t *testing.T) {
+	out := runInventory(t, inventorySSHConfig, func(config *CmdConfig) {
+		config.Doit.Set(config.NS, doit.ArgAddress, "private")
+		config.Doit.Set(config.NS, doit.ArgSSHUser, "deploy")
+	})
+
+	expected := "Host a-droplet\n  HostName 172.16.1.2\n  User deploy\n\n" +
+		"Host a-droplet\n  HostName 172.16.1.2\n  User deploy\n"
+	assert.Equal(t, expected, out)
+}
+
+func TestDropletInventory_HostsFile(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "hosts")
+
+		out := runInventory(t, inventoryHosts, func(config *CmdConfig) {
+			config.Doit.Set(config.NS, doit.ArgOutFile, path)
+		})
+		assert.Empty(t, out)
+
+		b, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "8.8.8.8\ta-droplet\n", string(b))
+
+		files, err := ioutil.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, files, 1)
+	})
+}
+
+func TestDropletInventory_ByID(t *testing.T) {
+	var out bytes.Buffer
+
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		config.Out = &out
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgFormat, inventoryHosts)
+		config.Doit.Set(config.NS, doit.ArgAddress, "public")
+
+		err := RunDropletInventory(config)
+		assert.NoError(t, err)
+	})
+
+	assert.Equal(t, "8.8.8.8\ta-droplet\n", out.String())
+}
+
+func TestDropletInventory_UnknownFormat(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Doit.Set(config.NS, doit.ArgFormat, "chef")
+
+		err := RunDropletInventory(config)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,210 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// manifest describes infrastructure which plan and apply reconcile with
+// the live account.
+type manifest struct {
+	// Tag is added to every droplet in the manifest. Droplets with the tag
+	// which aren't in the manifest are removed by apply --prune.
+	Tag         string               `yaml:"tag"`
+	SSHKeys     []manifestKey        `yaml:"ssh_keys"`
+	Droplets    []manifestDroplet    `yaml:"droplets"`
+	Domains     []manifestDomain     `yaml:"domains"`
+	FloatingIPs []manifestFloatingIP `yaml:"floating_ips"`
+}
+
+type manifestKey struct {
+	Name          string `yaml:"name"`
+	PublicKey     string `yaml:"public_key"`
+	PublicKeyFile string `yaml:"public_key_file"`
+}
+
+type manifestDroplet struct {
+	Name              string   `yaml:"name"`
+	Region            string   `yaml:"region"`
+	Size              string   `yaml:"size"`
+	Image             string   `yaml:"image"`
+	SSHKeys           []string `yaml:"ssh_keys"`
+	Backups           bool     `yaml:"backups"`
+	IPv6              bool     `yaml:"ipv6"`
+	PrivateNetworking bool     `yaml:"private_networking"`
+	UserData          string   `yaml:"user_data"`
+	UserDataFile      string   `yaml:"user_data_file"`
+}
+
+type manifestDomain struct {
+	Name string `yaml:"name"`
+	// IPAddress or Droplet gives the address of the domain's apex record.
+	IPAddress string           `yaml:"ip_address"`
+	Droplet   string           `yaml:"droplet"`
+	Records   []manifestRecord `yaml:"records"`
+}
+
+type manifestRecord struct {
+	Type string `yaml:"type"`
+	Name string `yaml:"name"`
+	// Data or Droplet gives the record's data. A droplet's public address
+	// is used for A and AAAA records.
+	Data     string `yaml:"data"`
+	Droplet  string `yaml:"droplet"`
+	Priority int    `yaml:"priority"`
+	Port     int    `yaml:"port"`
+	Weight   int    `yaml:"weight"`
+}
+
+type manifestFloatingIP struct {
+	IP string `yaml:"ip"`
+	// Droplet is the droplet the floating IP is assigned to. The floating IP
+	// is unassigned if it is empty.
+	Droplet string `yaml:"droplet"`
+}
+
+// loadManifest reads and validates a manifest.
+func loadManifest(path string) (*manifest, error) {
+	if path == "" {
+		return nil, fmt.Errorf("a manifest is required, use --file")
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m manifest
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse manifest %s: %v", path, err)
+	}
+
+	if err := m.validate(); err != nil {
+		return nil, fmt.Errorf("invalid manifest %s: %v", path, err)
+	}
+
+	return &m, nil
+}
+
+func (m *manifest) validate() error {
+	keys := map[string]bool{}
+	for i, k := range m.SSHKeys {
+		if k.Name == "" {
+			return fmt.Errorf("ssh key %d has no name", i+1)
+		}
+		if keys[k.Name] {
+			return fmt.Errorf("ssh key %q is listed twice", k.Name)
+		}
+		if (k.PublicKey == "") == (k.PublicKeyFile == "") {
+			return fmt.Errorf("ssh key %q needs one of public_key or public_key_file", k.Name)
+		}
+		keys[k.Name] = true
+	}
+
+	droplets := map[string]bool{}
+	for i, d := range m.Droplets {
+		if d.Name == "" {
+			return fmt.Errorf("droplet %d has no name", i+1)
+		}
+		if droplets[d.Name] {
+			return fmt.Errorf("droplet %q is listed twice", d.Name)
+		}
+		if d.Region == "" || d.Size == "" || d.Image == "" {
+			return fmt.Errorf("droplet %q needs a region, size and image", d.Name)
+		}
+		if d.UserData != "" && d.UserDataFile != "" {
+			return fmt.Errorf("droplet %q has both user_data and user_data_file", d.Name)
+		}
+		droplets[d.Name] = true
+	}
+
+	checkDroplet := func(what, name string) error {
+		if name != "" && !droplets[name] {
+			return fmt.Errorf("%s refers to droplet %q which isn't in the manifest", what, name)
+		}
+		return nil
+	}
+
+	domains := map[string]bool{}
+	for i, d := range m.Domains {
+		if d.Name == "" {
+			return fmt.Errorf("domain %d has no name", i+1)
+		}
+		if domains[d.Name] {
+			return fmt.Errorf("domain %q is listed twice", d.Name)
+		}
+		if (d.IPAddress == "") == (d.Droplet == "") {
+			return fmt.Errorf("domain %q needs one of ip_address or droplet", d.Name)
+		}
+		if err := checkDroplet(fmt.Sprintf("domain %q", d.Name), d.Droplet); err != nil {
+			return err
+		}
+
+		for j, r := range d.Records {
+			what := fmt.Sprintf("record %d in domain %q", j+1, d.Name)
+			if r.Type == "" || r.Name == "" {
+				return fmt.Errorf("%s needs a type and name", what)
+			}
+			if (r.Data == "") == (r.Droplet == "") {
+				return fmt.Errorf("%s needs one of data or droplet", what)
+			}
+			if r.Droplet != "" {
+				switch strings.ToUpper(r.Type) {
+				case "A", "AAAA":
+				default:
+					return fmt.Errorf("%s can only use a droplet for A or AAAA records", what)
+				}
+			}
+			if err := checkDroplet(what, r.Droplet); err != nil {
+				return err
+			}
+		}
+		domains[d.Name] = true
+	}
+
+	ips := map[string]bool{}
+	for i, f := range m.FloatingIPs {
+		if f.IP == "" {
+			return fmt.Errorf("floating ip %d has no ip", i+1)
+		}
+		if ips[f.IP] {
+			return fmt.Errorf("floating ip %s is listed twice", f.IP)
+		}
+		if err := checkDroplet(fmt.Sprintf("floating ip %s", f.IP), f.Droplet); err != nil {
+			return err
+		}
+		ips[f.IP] = true
+	}
+
+	return nil
+}
+
+// publicKey returns the key's public key, reading it from a file if needed.
+func (k manifestKey) publicKey() (string, error) {
+	if k.PublicKey != "" {
+		return k.PublicKey, nil
+	}
+
+	b, err := ioutil.ReadFile(k.PublicKeyFile)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(b)), nil
+}
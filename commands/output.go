@@ -529,3 +529,90 @@ func (p *plugin) KV() []map[string]interface{} {
 
 	return out
 }
+
+type costReport struct {
+	lines []costLine
+	total costLine
+}
+
+var _ Displayable = &costReport{}
+
+func (cr *costReport) JSON(out io.Writer) error {
+	report := struct {
+		Groups []costLine `json:"groups"`
+		Total  costLine   `json:"total"`
+	}{
+		Groups: cr.lines,
+		Total:  cr.total,
+	}
+
+	return writeJSON(report, out)
+}
+
+func (cr *costReport) Cols() []string {
+	return []string{
+		"Group", "Droplets", "Monthly", "Backups", "Total",
+	}
+}
+
+func (cr *costReport) ColMap() map[string]string {
+	return map[string]string{
+		"Group": "Group", "Droplets": "Droplets", "Monthly": "Monthly",
+		"Backups": "Backups", "Total": "Total",
+	}
+}
+
+func (cr *costReport) KV() []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, l := range append(cr.lines, cr.total) {
+		o := map[string]interface{}{
+			"Group": l.Group, "Droplets": l.Droplets,
+			"Monthly": fmt.Sprintf("%0.2f", l.Monthly),
+			"Backups": fmt.Sprintf("%0.2f", l.Backups),
+			"Total":   fmt.Sprintf("%0.2f", l.Total),
+		}
+
+		out = append(out, o)
+	}
+
+	return out
+}
+
+type sshExecReport struct {
+	results []sshExecResult
+}
+
+var _ Displayable = &sshExecReport{}
+
+func (r *sshExecReport) JSON(out io.Writer) error {
+	return writeJSON(r.results, out)
+}
+
+func (r *sshExecReport) Cols() []string {
+	return []string{
+		"Droplet", "Status", "ExitStatus", "Duration", "Error",
+	}
+}
+
+func (r *sshExecReport) ColMap() map[string]string {
+	return map[string]string{
+		"Droplet": "Droplet", "Status": "Status", "ExitStatus": "Exit Status",
+		"Duration": "Duration", "Error": "Error",
+	}
+}
+
+func (r *sshExecReport) KV() []map[string]interface{} {
+	out := []map[string]interface{}{}
+
+	for _, res := range r.results {
+		o := map[string]interface{}{
+			"Droplet": res.Droplet, "Status": res.Status, "ExitStatus": res.ExitStatus,
+			"Duration": durationString(res.Duration), "Error": res.Error,
+		}
+
+		out = append(out, o)
+	}
+
+	return out
+}
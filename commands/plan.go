@@ -0,0 +1,762 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+)
+
+const (
+	opCreate = "+"
+	opUpdate = "~"
+	opDelete = "-"
+	opNote   = "!"
+)
+
+// Plan creates the plan command.
+func Plan() *Command {
+	cmd := CmdBuilder(nil, RunPlan, "plan", "show changes needed to make infrastructure match a manifest", Writer,
+		docCategories("compute"))
+	addManifestFlags(cmd)
+
+	return cmd
+}
+
+// Apply creates the apply command.
+func Apply() *Command {
+	cmd := CmdBuilder(nil, RunApply, "apply", "make infrastructure match a manifest", Writer,
+		docCategories("compute"), destructiveOpt())
+	addManifestFlags(cmd)
+	AddIntFlag(cmd, doit.ArgWaitTimeout, 0, "Seconds to wait for each action to complete, 0 waits forever")
+
+	return cmd
+}
+
+func addManifestFlags(cmd *Command) {
+	AddStringFlagP(cmd, doit.ArgManifestFile, "f", "", "Manifest describing droplets, domains, records, floating IPs and ssh keys")
+	AddBoolFlag(cmd, doit.ArgPrune, false, "Delete droplets with the manifest's tag which aren't in the manifest")
+}
+
+// RunPlan shows the changes apply would make.
+func RunPlan(c *CmdConfig) error {
+	p, _, err := planFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	if len(p.pending()) == 0 {
+		fmt.Fprintln(c.Out, "no changes, infrastructure matches the manifest")
+		return nil
+	}
+
+	for _, ch := range p.changes {
+		fmt.Fprintln(c.Out, ch)
+	}
+	fmt.Fprintf(c.Out, "\n%s\n", p.summary())
+
+	return nil
+}
+
+// RunApply makes the changes in the plan. Changes are made in dependency
+// order: tag, ssh keys, droplets, domains and records, floating IPs and
+// finally pruned droplets. Apply stops at the first change which fails.
+func RunApply(c *CmdConfig) error {
+	p, state, err := planFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	return runPlan(c, p, state, "apply", "changes", "no changes, infrastructure matches the manifest")
+}
+
+// runPlan shows a plan, asks whether to go ahead unless --force is given,
+// and applies it. With --dry-run only the plan is shown. Nothing is done
+// if the plan has no changes, and upToDate is shown instead.
+func runPlan(c *CmdConfig, p *infraPlan, state *applyState, operation, resourceType, upToDate string) error {
+	if len(p.pending()) == 0 {
+		fmt.Fprintln(c.Out, upToDate)
+		return nil
+	}
+
+	for _, ch := range p.changes {
+		fmt.Fprintln(c.Out, ch)
+	}
+	fmt.Fprintf(c.Out, "\n%s\n", p.summary())
+
+	return applyPlan(c, p, state, operation, resourceType, c.Out)
+}
+
+// applyPlan asks whether to go ahead with a plan which has been shown and
+// applies it, reporting to out when it is done.
+func applyPlan(c *CmdConfig, p *infraPlan, state *applyState, operation, resourceType string, out io.Writer) error {
+	ok, err := confirmDestructive(c, operation, resourceType, staticDescription(p.summary()))
+	if err != nil || !ok {
+		return err
+	}
+
+	pending := p.pending()
+	for i, ch := range pending {
+		if err := ch.apply(state); err != nil {
+			return fmt.Errorf("%s %s failed after %d of %d changes: %v", ch.op, ch.what, i, len(pending), err)
+		}
+	}
+
+	fmt.Fprintf(out, "applied %d changes\n", len(pending))
+	return nil
+}
+
+func planFromFlags(c *CmdConfig) (*infraPlan, *applyState, error) {
+	path, err := c.Doit.GetString(c.NS, doit.ArgManifestFile)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	prune, err := c.Doit.GetBool(c.NS, doit.ArgPrune)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	m, err := loadManifest(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return newPlan(c, m, prune)
+}
+
+// change is one step of a plan. Notes have no apply function.
+type change struct {
+	op     string
+	what   string
+	detail string
+	apply  func(*applyState) error
+}
+
+func (ch change) String() string {
+	if ch.detail == "" {
+		return fmt.Sprintf("%s %s", ch.op, ch.what)
+	}
+
+	return fmt.Sprintf("%s %s: %s", ch.op, ch.what, ch.detail)
+}
+
+// infraPlan is the ordered list of changes which make the live
+// infrastructure match a manifest.
+type infraPlan struct {
+	changes []change
+}
+
+func (p *infraPlan) add(op, what, detail string, fn func(*applyState) error) {
+	p.changes = append(p.changes, change{op: op, what: what, detail: detail, apply: fn})
+}
+
+func (p *infraPlan) pending() []change {
+	var out []change
+	for _, ch := range p.changes {
+		if ch.apply != nil {
+			out = append(out, ch)
+		}
+	}
+
+	return out
+}
+
+func (p *infraPlan) summary() string {
+	counts := map[string]int{}
+	for _, ch := range p.pending() {
+		counts[ch.op]++
+	}
+
+	return fmt.Sprintf("%d to create, %d to update, %d to delete",
+		counts[opCreate], counts[opUpdate], counts[opDelete])
+}
+
+// applyState holds what is known about resources while a plan is applied.
+// Changes which create resources add them so later changes can refer to
+// them.
+type applyState struct {
+	c        *CmdConfig
+	tag      string
+	keys     map[string]int
+	droplets map[string]*do.Droplet
+}
+
+// dropletAddress returns the address of a droplet for a record type.
+func (s *applyState) dropletAddress(name, recordType string) (string, error) {
+	d, ok := s.droplets[name]
+	if !ok {
+		return "", fmt.Errorf("droplet %q doesn't exist", name)
+	}
+
+	var ip string
+	var err error
+	if strings.ToUpper(recordType) == "AAAA" {
+		ip, err = d.PublicIPv6()
+	} else {
+		ip, err = d.PublicIPv4()
+	}
+	if err != nil {
+		return "", err
+	}
+
+	if ip == "" {
+		return "", fmt.Errorf("droplet %q has no %s address", name, recordType)
+	}
+
+	return ip, nil
+}
+
+func (s *applyState) tagDroplet(d *do.Droplet) error {
+	return s.c.Tags().TagResources(s.tag, &godo.TagResourcesRequest{
+		Resources: []godo.Resource{{ID: strconv.Itoa(d.ID), Type: godo.DropletResourceType}},
+	})
+}
+
+func (s *applyState) waitForAction(a *do.Action) error {
+	w, err := newActionWaiter(s.c, 5)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.wait(a.ID)
+	return err
+}
+
+// planner compares a manifest with the live infrastructure.
+type planner struct {
+	c     *CmdConfig
+	m     *manifest
+	prune bool
+	plan  *infraPlan
+	state *applyState
+
+	// tagged are the live droplets with the manifest's tag.
+	tagged map[int]do.Droplet
+}
+
+func newPlan(c *CmdConfig, m *manifest, prune bool) (*infraPlan, *applyState, error) {
+	if prune && m.Tag == "" {
+		return nil, nil, fmt.Errorf("--%s needs a tag in the manifest to find droplets to delete", doit.ArgPrune)
+	}
+
+	p := &planner{
+		c:     c,
+		m:     m,
+		prune: prune,
+		plan:  &infraPlan{},
+		state: &applyState{
+			c:        c,
+			tag:      m.Tag,
+			keys:     map[string]int{},
+			droplets: map[string]*do.Droplet{},
+		},
+		tagged: map[int]do.Droplet{},
+	}
+
+	steps := []func() error{p.planTag, p.planKeys, p.planDroplets, p.planDomains, p.planFloatingIPs, p.planPrune}
+	for _, step := range steps {
+		if err := step(); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return p.plan, p.state, nil
+}
+
+func (p *planner) planTag() error {
+	if p.m.Tag == "" {
+		return nil
+	}
+
+	tags, err := p.c.Tags().List()
+	if err != nil {
+		return err
+	}
+
+	for _, t := range tags {
+		if t.Name == p.m.Tag {
+			droplets, err := p.c.Droplets().ListByTag(p.m.Tag)
+			if err != nil {
+				return err
+			}
+
+			for _, d := range droplets {
+				p.tagged[d.ID] = d
+			}
+
+			return nil
+		}
+	}
+
+	p.plan.add(opCreate, "tag "+p.m.Tag, "", func(s *applyState) error {
+		_, err := s.c.Tags().Create(&godo.TagCreateRequest{Name: s.tag})
+		return err
+	})
+
+	return nil
+}
+
+func (p *planner) planKeys() error {
+	if len(p.m.SSHKeys) == 0 {
+		return nil
+	}
+
+	live, err := p.c.Keys().List()
+	if err != nil {
+		return err
+	}
+
+	for _, k := range live {
+		p.state.keys[k.Name] = k.ID
+	}
+
+	for _, k := range p.m.SSHKeys {
+		if _, ok := p.state.keys[k.Name]; ok {
+			continue
+		}
+
+		publicKey, err := k.publicKey()
+		if err != nil {
+			return fmt.Errorf("ssh key %q: %v", k.Name, err)
+		}
+
+		name := k.Name
+		p.plan.add(opCreate, "ssh key "+name, "", func(s *applyState) error {
+			created, err := s.c.Keys().Create(&godo.KeyCreateRequest{Name: name, PublicKey: publicKey})
+			if err != nil {
+				return err
+			}
+
+			s.keys[name] = created.ID
+			return nil
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) planDroplets() error {
+	if len(p.m.Droplets) == 0 {
+		return nil
+	}
+
+	live, err := p.c.Droplets().List()
+	if err != nil {
+		return err
+	}
+
+	byName := map[string][]do.Droplet{}
+	for _, d := range live {
+		byName[d.Name] = append(byName[d.Name], d)
+	}
+
+	for _, md := range p.m.Droplets {
+		what := "droplet " + md.Name
+
+		switch existing := byName[md.Name]; len(existing) {
+		case 0:
+			if err := p.planDropletCreate(md); err != nil {
+				return err
+			}
+		case 1:
+			d := existing[0]
+			p.state.droplets[md.Name] = &d
+			p.planDropletDrift(md, &d)
+		default:
+			return fmt.Errorf("%s: the name is used by %d droplets", what, len(existing))
+		}
+	}
+
+	return nil
+}
+
+func (p *planner) planDropletCreate(md manifestDroplet) error {
+	userData, err := extractUserData(md.UserData, md.UserDataFile)
+	if err != nil {
+		return fmt.Errorf("droplet %q: %v", md.Name, err)
+	}
+
+	detail := fmt.Sprintf("%s, %s, %s", md.Region, md.Size, md.Image)
+	p.plan.add(opCreate, "droplet "+md.Name, detail, func(s *applyState) error {
+		keys, err := s.sshKeys(md.SSHKeys)
+		if err != nil {
+			return err
+		}
+
+		image := godo.DropletCreateImage{Slug: md.Image}
+		if id, err := strconv.Atoi(md.Image); err == nil {
+			image = godo.DropletCreateImage{ID: id}
+		}
+
+		d, err := s.c.Droplets().Create(&godo.DropletCreateRequest{
+			Name:              md.Name,
+			Region:            md.Region,
+			Size:              md.Size,
+			Image:             image,
+			SSHKeys:           keys,
+			Backups:           md.Backups,
+			IPv6:              md.IPv6,
+			PrivateNetworking: md.PrivateNetworking,
+			UserData:          userData,
+		}, true)
+		if err != nil {
+			return err
+		}
+
+		s.droplets[md.Name] = d
+		if s.tag == "" {
+			return nil
+		}
+
+		return s.tagDroplet(d)
+	})
+
+	return nil
+}
+
+// planDropletDrift notes differences apply won't fix and tags the droplet
+// if needed.
+func (p *planner) planDropletDrift(md manifestDroplet, d *do.Droplet) {
+	what := "droplet " + md.Name
+
+	if d.Region != nil && d.Region.Slug != md.Region {
+		p.plan.add(opNote, what, fmt.Sprintf("region is %s, manifest has %s, apply doesn't move droplets",
+			d.Region.Slug, md.Region), nil)
+	}
+
+	size := d.SizeSlug
+	if size == "" && d.Size != nil {
+		size = d.Size.Slug
+	}
+	if size != "" && size != md.Size {
+		p.plan.add(opNote, what, fmt.Sprintf("size is %s, manifest has %s, apply doesn't resize droplets",
+			size, md.Size), nil)
+	}
+
+	if _, ok := p.tagged[d.ID]; p.m.Tag != "" && !ok {
+		p.plan.add(opUpdate, what, "add tag "+p.m.Tag, func(s *applyState) error {
+			return s.tagDroplet(d)
+		})
+	}
+}
+
+// sshKeys resolves a manifest droplet's keys. Keys created by apply are
+// found by name, anything else is resolved like droplet create --ssh-keys.
+func (s *applyState) sshKeys(refs []string) ([]godo.DropletCreateSSHKey, error) {
+	var keys []godo.DropletCreateSSHKey
+	var other []string
+	for _, ref := range refs {
+		if id, ok := s.keys[ref]; ok {
+			keys = append(keys, godo.DropletCreateSSHKey{ID: id})
+			continue
+		}
+
+		other = append(other, ref)
+	}
+
+	resolved, err := resolveSSHKeys(s.c.Resolver(), extractSSHKeys(other))
+	if err != nil {
+		return nil, err
+	}
+
+	return append(keys, resolved...), nil
+}
+
+// recordValue is a record's wanted data. Data from a droplet which doesn't
+// exist yet is only known once the droplet has been created.
+type recordValue struct {
+	data    string
+	droplet string
+	known   bool
+}
+
+func (p *planner) recordValue(recordType, data, droplet string) recordValue {
+	if droplet == "" {
+		return recordValue{data: data, known: true}
+	}
+
+	if ip, err := p.state.dropletAddress(droplet, recordType); err == nil {
+		return recordValue{data: ip, droplet: droplet, known: true}
+	}
+
+	return recordValue{droplet: droplet}
+}
+
+func (v recordValue) String() string {
+	if v.known {
+		return v.data
+	}
+
+	return "address of " + v.droplet
+}
+
+func (v recordValue) resolve(s *applyState, recordType string) (string, error) {
+	if v.droplet == "" {
+		return v.data, nil
+	}
+
+	return s.dropletAddress(v.droplet, recordType)
+}
+
+func (p *planner) planDomains() error {
+	if len(p.m.Domains) == 0 {
+		return nil
+	}
+
+	live, err := p.c.Domains().List()
+	if err != nil {
+		return err
+	}
+
+	exists := map[string]bool{}
+	for _, d := range live {
+		exists[d.Name] = true
+	}
+
+	for _, md := range p.m.Domains {
+		apex := p.recordValue("A", md.IPAddress, md.Droplet)
+		records := md.Records
+
+		if exists[md.Name] {
+			// the domain's address is its apex A record.
+			records = append([]manifestRecord{{Type: "A", Name: "@", Data: md.IPAddress, Droplet: md.Droplet}}, records...)
+			if err := p.planRecords(md.Name, records); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := md.Name
+		p.plan.add(opCreate, "domain "+name, apex.String(), func(s *applyState) error {
+			ip, err := apex.resolve(s, "A")
+			if err != nil {
+				return err
+			}
+
+			_, err = s.c.Domains().Create(&godo.DomainCreateRequest{Name: name, IPAddress: ip})
+			return err
+		})
+
+		for _, r := range records {
+			p.planRecordCreate(name, r)
+		}
+	}
+
+	return nil
+}
+
+func recordKey(recordType, name string) string {
+	return strings.ToUpper(recordType) + " " + strings.ToLower(name)
+}
+
+// planRecords matches a domain's manifest records with its live records by
+// type and name. When a type and name has several records they are matched
+// by data too. Live records which aren't in the manifest are left alone.
+func (p *planner) planRecords(domain string, records []manifestRecord) error {
+	live, err := p.c.Domains().Records(domain)
+	if err != nil {
+		return err
+	}
+
+	liveByKey := map[string][]do.DomainRecord{}
+	for _, r := range live {
+		k := recordKey(r.Type, r.Name)
+		liveByKey[k] = append(liveByKey[k], r)
+	}
+
+	wantByKey := map[string]int{}
+	for _, r := range records {
+		wantByKey[recordKey(r.Type, r.Name)]++
+	}
+
+	used := map[int]bool{}
+	for _, r := range records {
+		k := recordKey(r.Type, r.Name)
+		value := p.recordValue(r.Type, r.Data, r.Droplet)
+		candidates := liveByKey[k]
+
+		var match *do.DomainRecord
+		if wantByKey[k] == 1 && len(candidates) == 1 {
+			match = &candidates[0]
+		} else {
+			for i := range candidates {
+				if !used[candidates[i].ID] && value.known && candidates[i].Data == value.data {
+					match = &candidates[i]
+					break
+				}
+			}
+		}
+
+		if match == nil {
+			p.planRecordCreate(domain, r)
+			continue
+		}
+
+		used[match.ID] = true
+		if value.known && match.Data == value.data && match.Priority == r.Priority &&
+			match.Port == r.Port && match.Weight == r.Weight {
+			continue
+		}
+
+		id, r := match.ID, r
+		detail := recordDiff(match, r, value)
+		p.plan.add(opUpdate, fmt.Sprintf("record %s %s %s", domain, strings.ToUpper(r.Type), r.Name), detail,
+			func(s *applyState) error {
+				data, err := value.resolve(s, r.Type)
+				if err != nil {
+					return err
+				}
+
+				_, err = s.c.Domains().EditRecord(domain, id, recordRequest(r, data))
+				return err
+			})
+	}
+
+	return nil
+}
+
+// recordDiff describes how a live record differs from the manifest.
+func recordDiff(live *do.DomainRecord, r manifestRecord, value recordValue) string {
+	var diffs []string
+	if !value.known || live.Data != value.data {
+		diffs = append(diffs, fmt.Sprintf("%s -> %s", live.Data, value))
+	}
+
+	fields := []struct {
+		name       string
+		live, want int
+	}{
+		{"priority", live.Priority, r.Priority},
+		{"port", live.Port, r.Port},
+		{"weight", live.Weight, r.Weight},
+	}
+	for _, f := range fields {
+		if f.live != f.want {
+			diffs = append(diffs, fmt.Sprintf("%s %d -> %d", f.name, f.live, f.want))
+		}
+	}
+
+	return strings.Join(diffs, ", ")
+}
+
+func (p *planner) planRecordCreate(domain string, r manifestRecord) {
+	value := p.recordValue(r.Type, r.Data, r.Droplet)
+	p.plan.add(opCreate, fmt.Sprintf("record %s %s %s", domain, strings.ToUpper(r.Type), r.Name), value.String(),
+		func(s *applyState) error {
+			data, err := value.resolve(s, r.Type)
+			if err != nil {
+				return err
+			}
+
+			_, err = s.c.Domains().CreateRecord(domain, recordRequest(r, data))
+			return err
+		})
+}
+
+func recordRequest(r manifestRecord, data string) *godo.DomainRecordEditRequest {
+	return &godo.DomainRecordEditRequest{
+		Type:     strings.ToUpper(r.Type),
+		Name:     r.Name,
+		Data:     data,
+		Priority: r.Priority,
+		Port:     r.Port,
+		Weight:   r.Weight,
+	}
+}
+
+func (p *planner) planFloatingIPs() error {
+	for _, mf := range p.m.FloatingIPs {
+		fip, err := p.c.FloatingIPs().Get(mf.IP)
+		if err != nil {
+			return fmt.Errorf("floating ip %s: %v", mf.IP, err)
+		}
+
+		what := "floating ip " + mf.IP
+		ip, droplet := mf.IP, mf.Droplet
+
+		if droplet == "" {
+			if fip.Droplet == nil {
+				continue
+			}
+
+			p.plan.add(opUpdate, what, "unassign from "+fip.Droplet.Name, func(s *applyState) error {
+				a, err := s.c.FloatingIPActions().Unassign(ip)
+				if err != nil {
+					return err
+				}
+
+				return s.waitForAction(a)
+			})
+			continue
+		}
+
+		if d, ok := p.state.droplets[droplet]; ok && fip.Droplet != nil && fip.Droplet.ID == d.ID {
+			continue
+		}
+
+		p.plan.add(opUpdate, what, "assign to "+droplet, func(s *applyState) error {
+			d, ok := s.droplets[droplet]
+			if !ok {
+				return fmt.Errorf("droplet %q doesn't exist", droplet)
+			}
+
+			a, err := s.c.FloatingIPActions().Assign(ip, d.ID)
+			if err != nil {
+				return err
+			}
+
+			return s.waitForAction(a)
+		})
+	}
+
+	return nil
+}
+
+func (p *planner) planPrune() error {
+	if !p.prune {
+		return nil
+	}
+
+	wanted := map[string]bool{}
+	for _, md := range p.m.Droplets {
+		wanted[md.Name] = true
+	}
+
+	var ids []int
+	for id := range p.tagged {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	for _, id := range ids {
+		id, d := id, p.tagged[id]
+		if wanted[d.Name] {
+			continue
+		}
+
+		p.plan.add(opDelete, "droplet "+describeDroplet(&d), "", func(s *applyState) error {
+			return s.c.Droplets().Delete(id)
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,278 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+const testManifest = `
+tag: web
+ssh_keys:
+  - name: laptop
+    public_key: ssh-rsa AAAA laptop
+droplets:
+  - name: a-droplet
+    region: test0
+    size: 512mb
+    image: ubuntu-14-04-x64
+    ssh_keys: [laptop]
+domains:
+  - name: example.com
+    droplet: a-droplet
+    records:
+      - type: CNAME
+        name: www
+        data: "@"
+floating_ips:
+  - ip: 127.0.0.1
+    droplet: a-droplet
+`
+
+func TestPlanCommand(t *testing.T) {
+	assert.NotNil(t, Plan())
+	assert.NotNil(t, Apply())
+}
+
+func TestPlan_CreateAll(t *testing.T) {
+	withTempFile(t, testManifest, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			unassigned := &do.FloatingIP{FloatingIP: &godo.FloatingIP{IP: "127.0.0.1"}}
+
+			tm.tags.On("List").Return(do.Tags{}, nil)
+			tm.keys.On("List").Return(do.SSHKeys{}, nil)
+			tm.droplets.On("List").Return(do.Droplets{}, nil)
+			tm.domains.On("List").Return(do.Domains{}, nil)
+			tm.floatingIPs.On("Get", "127.0.0.1").Return(unassigned, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+
+			err := RunPlan(config)
+			assert.NoError(t, err)
+			assert.Equal(t, `+ tag web
++ ssh key laptop
++ droplet a-droplet: test0, 512mb, ubuntu-14-04-x64
++ domain example.com: address of a-droplet
++ record example.com CNAME www: @
+~ floating ip 127.0.0.1: assign to a-droplet
+
+5 to create, 1 to update, 0 to delete
+`, out.String())
+		})
+	})
+}
+
+func TestApply_CreateAll(t *testing.T) {
+	withTempFile(t, testManifest, func(path string) {
+		withActionWaiterStubs(false, func(_ *bytes.Buffer, _ *[]time.Duration) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				unassigned := &do.FloatingIP{FloatingIP: &godo.FloatingIP{IP: "127.0.0.1"}}
+				key := &do.SSHKey{Key: &godo.Key{ID: 7, Name: "laptop"}}
+				dcr := &godo.DropletCreateRequest{
+					Name:    "a-droplet",
+					Region:  "test0",
+					Size:    "512mb",
+					Image:   godo.DropletCreateImage{Slug: "ubuntu-14-04-x64"},
+					SSHKeys: []godo.DropletCreateSSHKey{{ID: 7}},
+				}
+				trr := &godo.TagResourcesRequest{
+					Resources: []godo.Resource{{ID: "1", Type: godo.DropletResourceType}},
+				}
+				record := &godo.DomainRecordEditRequest{Type: "CNAME", Name: "www", Data: "@"}
+
+				tm.tags.On("List").Return(do.Tags{}, nil)
+				tm.keys.On("List").Return(do.SSHKeys{}, nil)
+				tm.droplets.On("List").Return(do.Droplets{}, nil)
+				tm.domains.On("List").Return(do.Domains{}, nil)
+				tm.floatingIPs.On("Get", "127.0.0.1").Return(unassigned, nil)
+
+				tm.tags.On("Create", &godo.TagCreateRequest{Name: "web"}).Return(&do.Tag{Tag: &godo.Tag{Name: "web"}}, nil)
+				tm.keys.On("Create", &godo.KeyCreateRequest{Name: "laptop", PublicKey: "ssh-rsa AAAA laptop"}).Return(key, nil)
+				tm.droplets.On("Create", dcr, true).Return(&testDroplet, nil)
+				tm.tags.On("TagResources", "web", trr).Return(nil)
+				tm.domains.On("Create", &godo.DomainCreateRequest{Name: "example.com", IPAddress: "8.8.8.8"}).Return(&do.Domain{}, nil)
+				tm.domains.On("CreateRecord", "example.com", record).Return(&do.DomainRecord{}, nil)
+				tm.floatingIPActions.On("Assign", "127.0.0.1", 1).Return(testActionWithStatus(2, "in-progress"), nil)
+				tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+
+				var out bytes.Buffer
+				config.Out = &out
+				config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+				config.Doit.Set(config.NS, doit.ArgForce, true)
+
+				err := RunApply(config)
+				assert.NoError(t, err)
+				assert.Contains(t, out.String(), "~ floating ip 127.0.0.1: assign to a-droplet\n")
+			})
+		})
+	})
+}
+
+func TestPlan_NoChanges(t *testing.T) {
+	manifest := `
+droplets:
+  - name: a-droplet
+    region: test0
+    size: 512mb
+    image: ubuntu-14-04-x64
+domains:
+  - name: example.com
+    droplet: a-droplet
+`
+
+	withTempFile(t, manifest, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			sized := *testDroplet.Droplet
+			sized.SizeSlug = "512mb"
+			d := do.Droplet{Droplet: &sized}
+			apex := do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: 1, Type: "A", Name: "@", Data: "8.8.8.8"}}
+
+			tm.droplets.On("List").Return(do.Droplets{d}, nil)
+			tm.domains.On("List").Return(do.Domains{{Domain: &godo.Domain{Name: "example.com"}}}, nil)
+			tm.domains.On("Records", "example.com").Return(do.DomainRecords{apex}, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+
+			err := RunPlan(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "no changes, infrastructure matches the manifest\n", out.String())
+		})
+	})
+}
+
+func TestPlan_DriftAndRecordUpdate(t *testing.T) {
+	manifest := `
+droplets:
+  - name: a-droplet
+    region: nyc1
+    size: 1gb
+    image: ubuntu-14-04-x64
+domains:
+  - name: example.com
+    ip_address: 1.2.3.4
+    records:
+      - {type: MX, name: "@", data: mx1.example.com., priority: 10}
+      - {type: MX, name: "@", data: mx2.example.com., priority: 20}
+`
+
+	withTempFile(t, manifest, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			records := do.DomainRecords{
+				{DomainRecord: &godo.DomainRecord{ID: 1, Type: "A", Name: "@", Data: "8.8.8.8"}},
+				{DomainRecord: &godo.DomainRecord{ID: 2, Type: "MX", Name: "@", Data: "mx1.example.com.", Priority: 5}},
+			}
+
+			tm.droplets.On("List").Return(testDropletList, nil)
+			tm.domains.On("List").Return(do.Domains{{Domain: &godo.Domain{Name: "example.com"}}}, nil)
+			tm.domains.On("Records", "example.com").Return(records, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+
+			err := RunPlan(config)
+			assert.NoError(t, err)
+			assert.Equal(t, `! droplet a-droplet: region is test0, manifest has nyc1, apply doesn't move droplets
+~ record example.com A @: 8.8.8.8 -> 1.2.3.4
+~ record example.com MX @: priority 5 -> 10
++ record example.com MX @: mx2.example.com.
+
+1 to create, 2 to update, 0 to delete
+`, out.String())
+		})
+	})
+}
+
+func TestApply_Prune(t *testing.T) {
+	manifest := `
+tag: web
+droplets:
+  - name: a-droplet
+    region: test0
+    size: 512mb
+    image: ubuntu-14-04-x64
+`
+
+	withTempFile(t, manifest, func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			sized := *testDroplet.Droplet
+			sized.SizeSlug = "512mb"
+			d := do.Droplet{Droplet: &sized}
+
+			tm.tags.On("List").Return(do.Tags{{Tag: &godo.Tag{Name: "web"}}}, nil)
+			tm.droplets.On("ListByTag", "web").Return(do.Droplets{d, anotherTestDroplet}, nil)
+			tm.droplets.On("List").Return(do.Droplets{d, anotherTestDroplet}, nil)
+			tm.droplets.On("Delete", 3).Return(nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+			config.Doit.Set(config.NS, doit.ArgPrune, true)
+			config.Doit.Set(config.NS, doit.ArgForce, true)
+
+			err := RunApply(config)
+			assert.NoError(t, err)
+			assert.Equal(t, `- droplet another-droplet (3, 8.8.8.9)
+
+0 to create, 0 to update, 1 to delete
+applied 1 changes
+`, out.String())
+		})
+	})
+}
+
+func TestPlan_PruneNeedsTag(t *testing.T) {
+	withTempFile(t, "droplets: []\n", func(path string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			config.Doit.Set(config.NS, doit.ArgManifestFile, path)
+			config.Doit.Set(config.NS, doit.ArgPrune, true)
+
+			err := RunPlan(config)
+			assert.Error(t, err)
+		})
+	})
+}
+
+func TestManifest_Validate(t *testing.T) {
+	cases := []struct {
+		name     string
+		manifest string
+	}{
+		{"droplet missing size", "droplets: [{name: a, region: nyc1, image: ubuntu}]"},
+		{"duplicate droplet", "droplets: [{name: a, region: r, size: s, image: i}, {name: a, region: r, size: s, image: i}]"},
+		{"key without public key", "ssh_keys: [{name: laptop}]"},
+		{"domain without address", "domains: [{name: example.com}]"},
+		{"unknown record droplet", "domains: [{name: example.com, ip_address: 1.2.3.4, records: [{type: A, name: www, droplet: b}]}]"},
+		{"droplet for cname", "droplets: [{name: a, region: r, size: s, image: i}]\ndomains: [{name: example.com, ip_address: 1.2.3.4, records: [{type: CNAME, name: www, droplet: a}]}]"},
+		{"unknown floating ip droplet", "floating_ips: [{ip: 127.0.0.1, droplet: b}]"},
+	}
+
+	for _, c := range cases {
+		withTempFile(t, c.manifest, func(path string) {
+			_, err := loadManifest(path)
+			assert.Error(t, err, c.name)
+		})
+	}
+}
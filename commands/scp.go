@@ -0,0 +1,447 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/pkg/ssh"
+	"github.com/mitchellh/ioprogress"
+	"github.com/pkg/sftp"
+)
+
+// dialSFTP opens an sftp session to a host. It is replaced in tests.
+var dialSFTP = func(user, host, keyPath string, port int, acceptNew bool) (*sftp.Client, io.Closer, error) {
+	r := &ssh.Runner{
+		User:    user,
+		Host:    host,
+		KeyPath: keyPath,
+		Port:    port,
+		Options: ssh.Options{AcceptNewHostKeys: acceptNew},
+	}
+
+	return r.SFTP()
+}
+
+// SCP creates the scp command.
+func SCP() *Command {
+	cmd := CmdBuilder(nil, RunSCP, "scp <source> [<source> ...] <destination>",
+		"copy files to and from droplets, given as [user@]<droplet-id | name | GLOB | re:REGEX>:path", Writer,
+		docCategories("droplet"))
+	AddBoolFlagP(cmd, doit.ArgRecursive, "r", false, "copy directories recursively")
+	AddStringFlag(cmd, doit.ArgSSHUser, "", "ssh user, defaults to root or the image's usual user")
+	AddStringFlag(cmd, doit.ArgsSSHKeyPath, defaultSSHKeyPath(), "path to private ssh key")
+	AddIntFlag(cmd, doit.ArgsSSHPort, 22, "port sshd is running on")
+	AddBoolFlag(cmd, doit.ArgAcceptNewHostKeys, false, acceptNewHostKeysHelp)
+
+	return cmd
+}
+
+// scpPath is a local path, or a path on the droplets matching host.
+type scpPath struct {
+	user string
+	host string
+	path string
+}
+
+func (p scpPath) remote() bool {
+	return p.host != ""
+}
+
+func parseSCPPath(s string) scpPath {
+	// the colon ending the host is looked for after any user, and after the
+	// prefix of a regex, which can hold slashes and backslashes.
+	start := 0
+	if i := strings.Index(s, "@"); i >= 0 && !strings.ContainsAny(s[:i], `:/\`) {
+		start = i + 1
+	}
+
+	regex := strings.HasPrefix(s[start:], "re:")
+	if regex {
+		start += len("re:")
+	}
+
+	i := strings.Index(s[start:], ":")
+	if i < 0 {
+		return scpPath{path: s}
+	}
+	i += start
+
+	if i == start || (!regex && strings.ContainsAny(s[:i], `/\`)) {
+		return scpPath{path: s}
+	}
+
+	// a single letter before the colon is a drive on windows.
+	if i == 1 && runtime.GOOS == "windows" {
+		return scpPath{path: s}
+	}
+
+	p := scpPath{host: s[:i], path: s[i+1:]}
+	if j := strings.LastIndex(p.host[:start], "@"); j >= 0 {
+		p.user, p.host = p.host[:j], p.host[j+1:]
+	}
+
+	return p
+}
+
+// scpCopier copies files over sftp.
+type scpCopier struct {
+	user      string
+	keyPath   string
+	port      int
+	acceptNew bool
+	recursive bool
+}
+
+// RunSCP copies local files to droplets, or files on droplets to a local
+// path. A destination matching several droplets receives the files on each
+// of them.
+func RunSCP(c *CmdConfig) error {
+	if len(c.Args) < 2 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	s := &scpCopier{}
+	var err error
+
+	if s.user, err = c.Doit.GetString(c.NS, doit.ArgSSHUser); err != nil {
+		return err
+	}
+
+	if s.keyPath, err = c.Doit.GetString(c.NS, doit.ArgsSSHKeyPath); err != nil {
+		return err
+	}
+
+	if s.port, err = c.Doit.GetInt(c.NS, doit.ArgsSSHPort); err != nil {
+		return err
+	}
+
+	if s.acceptNew, err = c.Doit.GetBool(c.NS, doit.ArgAcceptNewHostKeys); err != nil {
+		return err
+	}
+
+	if s.recursive, err = c.Doit.GetBool(c.NS, doit.ArgRecursive); err != nil {
+		return err
+	}
+
+	var srcs []scpPath
+	remoteSrcs := 0
+	for _, a := range c.Args[:len(c.Args)-1] {
+		p := parseSCPPath(a)
+		if p.remote() {
+			remoteSrcs++
+		}
+		srcs = append(srcs, p)
+	}
+
+	dst := parseSCPPath(c.Args[len(c.Args)-1])
+
+	switch {
+	case dst.remote() && remoteSrcs == 0:
+		return s.upload(c, srcs, dst)
+	case !dst.remote() && remoteSrcs == len(srcs):
+		return s.download(c, srcs, dst)
+	case dst.remote():
+		return errors.New("copying from one droplet to another isn't supported")
+	default:
+		return errors.New("the sources or the destination have to be on a droplet, but not both")
+	}
+}
+
+func (s *scpCopier) upload(c *CmdConfig, srcs []scpPath, dst scpPath) error {
+	droplets, err := sshDroplets(c, dst.host)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range droplets {
+		client, conn, err := s.open(d, dst.user)
+		if err != nil {
+			return fmt.Errorf("unable to connect to %s: %v", d.Name, err)
+		}
+
+		for _, src := range srcs {
+			if err = s.uploadPath(client, d.Name, src.path, dst.path); err != nil {
+				break
+			}
+		}
+
+		_ = client.Close()
+		_ = conn.Close()
+
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *scpCopier) download(c *CmdConfig, srcs []scpPath, dst scpPath) error {
+	if len(srcs) > 1 {
+		fi, err := os.Stat(dst.path)
+		if err != nil || !fi.IsDir() {
+			return fmt.Errorf("%s isn't a directory", dst.path)
+		}
+	}
+
+	for _, src := range srcs {
+		droplets, err := sshDroplets(c, src.host)
+		if err != nil {
+			return err
+		}
+
+		if len(droplets) != 1 {
+			return fmt.Errorf("%s matches %d droplets, files can only be copied from one", src.host, len(droplets))
+		}
+
+		d := droplets[0]
+		client, conn, err := s.open(d, src.user)
+		if err != nil {
+			return fmt.Errorf("unable to connect to %s: %v", d.Name, err)
+		}
+
+		err = s.downloadPath(client, d.Name, src.path, dst.path)
+
+		_ = client.Close()
+		_ = conn.Close()
+
+		if err != nil {
+			return fmt.Errorf("%s: %v", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func (s *scpCopier) open(d do.Droplet, user string) (*sftp.Client, io.Closer, error) {
+	ip, err := d.PublicIPv4()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if ip == "" {
+		return nil, nil, errors.New(sshNoAddress)
+	}
+
+	if user == "" {
+		user = s.user
+	}
+
+	if user == "" {
+		user = defaultSSHUser(&d)
+	}
+
+	return dialSFTP(user, ip, s.keyPath, s.port, s.acceptNew)
+}
+
+// uploadPath copies a local file or directory to a remote path. Like scp,
+// a remote path which is a directory receives the source inside it.
+func (s *scpCopier) uploadPath(client *sftp.Client, name, local, remote string) error {
+	fi, err := os.Stat(local)
+	if err != nil {
+		return err
+	}
+
+	if fi.IsDir() && !s.recursive {
+		return fmt.Errorf("%s is a directory, use -r to copy it", local)
+	}
+
+	target := remote
+	if remote == "" || strings.HasSuffix(remote, "/") {
+		target = path.Join(remote, filepath.Base(local))
+	} else if rfi, err := client.Stat(remote); err == nil && rfi.IsDir() {
+		target = path.Join(remote, filepath.Base(local))
+	}
+
+	if !fi.IsDir() {
+		return s.uploadFile(client, name, local, target, fi)
+	}
+
+	return filepath.Walk(local, func(p string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(local, p)
+		if err != nil {
+			return err
+		}
+
+		rp := path.Join(target, filepath.ToSlash(rel))
+
+		switch {
+		case fi.IsDir():
+			return remoteMkdir(client, rp)
+		case fi.Mode().IsRegular():
+			return s.uploadFile(client, name, p, rp, fi)
+		}
+
+		return nil
+	})
+}
+
+func (s *scpCopier) uploadFile(client *sftp.Client, name, local, remote string, fi os.FileInfo) error {
+	f, err := os.Open(local)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rf, err := client.Create(remote)
+	if err != nil {
+		return fmt.Errorf("unable to create %s: %v", remote, err)
+	}
+
+	if _, err := io.Copy(rf, scpProgress(f, fi.Size(), name+":"+remote)); err != nil {
+		_ = rf.Close()
+		return err
+	}
+
+	// writes can be buffered until the file is closed.
+	if err := rf.Close(); err != nil {
+		return fmt.Errorf("unable to write %s: %v", remote, err)
+	}
+
+	return client.Chmod(remote, fi.Mode().Perm())
+}
+
+// remoteMkdir creates a remote directory unless it already exists.
+func remoteMkdir(client *sftp.Client, p string) error {
+	if fi, err := client.Stat(p); err == nil {
+		if fi.IsDir() {
+			return nil
+		}
+		return fmt.Errorf("%s exists and isn't a directory", p)
+	}
+
+	return client.Mkdir(p)
+}
+
+// downloadPath copies a remote file or directory to a local path. Like
+// scp, a local path which is a directory receives the source inside it.
+func (s *scpCopier) downloadPath(client *sftp.Client, name, remote, local string) error {
+	if remote == "" {
+		remote = "."
+	}
+	remote = path.Clean(remote)
+
+	rfi, err := client.Stat(remote)
+	if err != nil {
+		return fmt.Errorf("%s: %v", remote, err)
+	}
+
+	if rfi.IsDir() && !s.recursive {
+		return fmt.Errorf("%s is a directory, use -r to copy it", remote)
+	}
+
+	target := local
+	if fi, err := os.Stat(local); err == nil && fi.IsDir() {
+		target = filepath.Join(local, path.Base(remote))
+	}
+
+	if !rfi.IsDir() {
+		return s.downloadFile(client, name, remote, target, rfi)
+	}
+
+	walker := client.Walk(remote)
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return err
+		}
+
+		fi := walker.Stat()
+		lp := filepath.Join(target, filepath.FromSlash(remoteRel(remote, walker.Path())))
+
+		switch {
+		case fi.IsDir():
+			if err := os.MkdirAll(lp, 0755); err != nil {
+				return err
+			}
+		case fi.Mode().IsRegular():
+			if err := s.downloadFile(client, name, walker.Path(), lp, fi); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *scpCopier) downloadFile(client *sftp.Client, name, remote, local string, fi os.FileInfo) error {
+	rf, err := client.Open(remote)
+	if err != nil {
+		return fmt.Errorf("unable to open %s: %v", remote, err)
+	}
+
+	f, err := os.OpenFile(local, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		_ = rf.Close()
+		return err
+	}
+
+	if _, err := io.Copy(f, scpProgress(rf, fi.Size(), name+":"+remote)); err != nil {
+		_ = f.Close()
+		_ = rf.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = rf.Close()
+		return err
+	}
+
+	return rf.Close()
+}
+
+// remoteRel returns p relative to the remote directory root.
+func remoteRel(root, p string) string {
+	switch {
+	case p == root:
+		return ""
+	case root == ".":
+		return p
+	case root == "/":
+		return strings.TrimPrefix(p, "/")
+	}
+
+	return strings.TrimPrefix(p, root+"/")
+}
+
+// scpProgress wraps r with a progress bar when progress can be redrawn in
+// place, otherwise the name of the file is written once.
+func scpProgress(r io.Reader, size int64, name string) io.Reader {
+	if !progressIsTerminal() {
+		fmt.Fprintln(progressOut, name)
+		return r
+	}
+
+	bar := ioprogress.DrawTextFormatBar(20)
+	return &ioprogress.Reader{
+		Reader: r,
+		Size:   size,
+		DrawFunc: ioprogress.DrawTerminalf(progressOut, func(progress, total int64) string {
+			return fmt.Sprintf("%s %s %s", name, bar(progress, total), ioprogress.DrawTextFormatBytes(progress, total))
+		}),
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+)
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// withSFTP replaces dialSFTP with an in process sftp server working on the
+// local file system, and gives fn a scratch directory and the hosts dialed.
+func withSFTP(t *testing.T, fn func(dir string, hosts func() []string)) {
+	withTempDir(t, func(dir string) {
+		og, ogOut, ogTerm := dialSFTP, progressOut, progressIsTerminal
+		defer func() { dialSFTP, progressOut, progressIsTerminal = og, ogOut, ogTerm }()
+		progressOut = ioutil.Discard
+		progressIsTerminal = func() bool { return false }
+
+		var mu sync.Mutex
+		var hosts []string
+		dialSFTP = func(user, host, keyPath string, port int, acceptNew bool) (*sftp.Client, io.Closer, error) {
+			mu.Lock()
+			hosts = append(hosts, user+"@"+host)
+			mu.Unlock()
+
+			cr, sw := io.Pipe()
+			sr, cw := io.Pipe()
+
+			server, err := sftp.NewServer(sr, sw)
+			if err != nil {
+				return nil, nil, err
+			}
+			go server.Serve()
+
+			client, err := sftp.NewClientPipe(cr, cw)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return client, nopCloser{}, nil
+		}
+
+		fn(dir, func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(hosts)
+			return hosts
+		})
+	})
+}
+
+func writeTestFiles(t *testing.T, dir string, files map[string]string) {
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(p), 0755))
+		assert.NoError(t, ioutil.WriteFile(p, []byte(content), 0640))
+	}
+}
+
+func assertFileContent(t *testing.T, p, expected string) {
+	b, err := ioutil.ReadFile(p)
+	if assert.NoError(t, err) {
+		assert.Equal(t, expected, string(b))
+	}
+}
+
+func TestSCPCommand(t *testing.T) {
+	cmd := SCP()
+	assert.NotNil(t, cmd)
+	assertCommandNames(t, cmd)
+}
+
+func Test_parseSCPPath(t *testing.T) {
+	cases := []struct {
+		s string
+		e scpPath
+	}{
+		{s: "local.tar", e: scpPath{path: "local.tar"}},
+		{s: "./a:b", e: scpPath{path: "./a:b"}},
+		{s: "web-1:/tmp/", e: scpPath{host: "web-1", path: "/tmp/"}},
+		{s: "core@web-*:", e: scpPath{user: "core", host: "web-*", path: ""}},
+		{s: `re:web-\d+:/etc/hosts`, e: scpPath{host: `re:web-\d+`, path: "/etc/hosts"}},
+		{s: `core@re:^(web|db)/\d$:logs`, e: scpPath{user: "core", host: `re:^(web|db)/\d$`, path: "logs"}},
+		{s: ":x", e: scpPath{path: ":x"}},
+	}
+
+	for _, c := range cases {
+		assert.Equal(t, c.e, parseSCPPath(c.s))
+	}
+}
+
+func TestSCP_UploadToManyDroplets(t *testing.T) {
+	withSFTP(t, func(dir string, hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("List").Return(testDropletList, nil)
+
+			writeTestFiles(t, dir, map[string]string{"local.tar": "tarball"})
+			remote := filepath.Join(dir, "remote")
+			assert.NoError(t, os.Mkdir(remote, 0755))
+
+			config.Args = append(config.Args, filepath.Join(dir, "local.tar"), "*droplet:"+remote)
+
+			err := RunSCP(config)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{"root@8.8.8.8", "root@8.8.8.9"}, hosts())
+			assertFileContent(t, filepath.Join(remote, "local.tar"), "tarball")
+
+			fi, err := os.Stat(filepath.Join(remote, "local.tar"))
+			assert.NoError(t, err)
+			assert.Equal(t, os.FileMode(0640), fi.Mode().Perm())
+		})
+	})
+}
+
+func TestSCP_UploadDirectory(t *testing.T) {
+	withSFTP(t, func(dir string, hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+
+			writeTestFiles(t, dir, map[string]string{
+				"site/index.html":    "index",
+				"site/css/main.css":  "css",
+				"site/js/app/app.js": "js",
+			})
+			remote := filepath.Join(dir, "www")
+
+			config.Args = append(config.Args, filepath.Join(dir, "site"), "deploy@1:"+remote)
+			config.Doit.Set(config.NS, doit.ArgRecursive, true)
+
+			err := RunSCP(config)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{"deploy@8.8.8.8"}, hosts())
+			assertFileContent(t, filepath.Join(remote, "index.html"), "index")
+			assertFileContent(t, filepath.Join(remote, "css", "main.css"), "css")
+			assertFileContent(t, filepath.Join(remote, "js", "app", "app.js"), "js")
+		})
+	})
+}
+
+func TestSCP_DirectoryNeedsRecursive(t *testing.T) {
+	withSFTP(t, func(dir string, hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, dir, "1:/tmp")
+
+			err := RunSCP(config)
+			assert.EqualError(t, err, "a-droplet: "+dir+" is a directory, use -r to copy it")
+		})
+	})
+}
+
+func TestSCP_Download(t *testing.T) {
+	withSFTP(t, func(dir string, hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("List").Return(testDropletList, nil)
+
+			writeTestFiles(t, dir, map[string]string{
+				"remote/logs/app.log":     "app",
+				"remote/logs/old/app.log": "old",
+			})
+			local := filepath.Join(dir, "local")
+			assert.NoError(t, os.Mkdir(local, 0755))
+
+			config.Args = append(config.Args, "another-droplet:"+filepath.Join(dir, "remote", "logs"), local)
+			config.Doit.Set(config.NS, doit.ArgRecursive, true)
+
+			err := RunSCP(config)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{"root@8.8.8.9"}, hosts())
+			assertFileContent(t, filepath.Join(local, "logs", "app.log"), "app")
+			assertFileContent(t, filepath.Join(local, "logs", "old", "app.log"), "old")
+		})
+	})
+}
+
+func TestSCP_DownloadFromManyDroplets(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+
+		config.Args = append(config.Args, "*droplet:/etc/hosts", ".")
+
+		err := RunSCP(config)
+		assert.EqualError(t, err, "*droplet matches 2 droplets, files can only be copied from one")
+	})
+}
+
+func TestSCP_BetweenDroplets(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "a-droplet:/etc/hosts", "another-droplet:/tmp")
+
+		err := RunSCP(config)
+		assert.EqualError(t, err, "copying from one droplet to another isn't supported")
+	})
+}
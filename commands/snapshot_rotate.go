@@ -0,0 +1,283 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/template"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/resolver"
+	"github.com/bryanl/doit/pkg/units"
+)
+
+const defaultSnapshotNameTemplate = `{{.Droplet}}-{{.Now.Format "20060102-1504"}}`
+
+// timeNow returns the current time. It is replaced in tests.
+var timeNow = time.Now
+
+// snapshotName is what a snapshot name template is executed with.
+type snapshotName struct {
+	Droplet string
+	ID      int
+	Now     time.Time
+}
+
+// snapshotRotation describes how to rotate a droplet's snapshots.
+type snapshotRotation struct {
+	tmpl      *template.Template
+	match     *resolver.Pattern
+	keep      int
+	olderThan time.Duration
+	dryRun    bool
+}
+
+// RunDropletSnapshotRotate snapshots droplets and deletes their old
+// snapshots. Only snapshots with names matching --match are deleted. They
+// are deleted if there are more than --keep newer matching snapshots, or
+// if they are older than --older-than. The snapshot just taken is never
+// deleted. Deleting snapshots is confirmed like any other delete, so
+// scheduled rotations need --force.
+func RunDropletSnapshotRotate(c *CmdConfig) error {
+	r, err := newSnapshotRotation(c)
+	if err != nil {
+		return err
+	}
+
+	droplets, err := snapshotRotateDroplets(c)
+	if err != nil {
+		return err
+	}
+
+	for _, d := range droplets {
+		if err := r.rotate(c, d); err != nil {
+			return fmt.Errorf("%s: %v", d.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func newSnapshotRotation(c *CmdConfig) (*snapshotRotation, error) {
+	text, err := c.Doit.GetString(c.NS, doit.ArgNameTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if text == "" {
+		text = defaultSnapshotNameTemplate
+	}
+
+	tmpl, err := template.New("name").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %v", doit.ArgNameTemplate, err)
+	}
+
+	ref, err := c.Doit.GetString(c.NS, doit.ArgMatch)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		return nil, fmt.Errorf("--%s is needed to pick the snapshots to rotate", doit.ArgMatch)
+	}
+
+	match, err := resolver.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	keep, err := c.Doit.GetInt(c.NS, doit.ArgKeep)
+	if err != nil {
+		return nil, err
+	}
+
+	olderThanStr, err := c.Doit.GetString(c.NS, doit.ArgOlderThan)
+	if err != nil {
+		return nil, err
+	}
+
+	var olderThan time.Duration
+	if olderThanStr != "" {
+		olderThan, err = units.ParseDuration(olderThanStr)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if keep < 1 && olderThan <= 0 {
+		return nil, fmt.Errorf("one of --%s or --%s is needed", doit.ArgKeep, doit.ArgOlderThan)
+	}
+
+	dryRun, err := c.Doit.GetBool(c.NS, doit.ArgDryRun)
+	if err != nil {
+		return nil, err
+	}
+
+	return &snapshotRotation{
+		tmpl:      tmpl,
+		match:     match,
+		keep:      keep,
+		olderThan: olderThan,
+		dryRun:    dryRun,
+	}, nil
+}
+
+// snapshotRotateDroplets returns the droplets selected by the arguments
+// or by --tag.
+func snapshotRotateDroplets(c *CmdConfig) (do.Droplets, error) {
+	tag, err := c.Doit.GetString(c.NS, doit.ArgTagName)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := c.Droplets()
+
+	switch {
+	case tag != "" && len(c.Args) > 0:
+		return nil, fmt.Errorf("droplets can be given as arguments or with --%s, not both", doit.ArgTagName)
+	case tag != "":
+		return ds.ListByTag(tag)
+	case len(c.Args) == 0:
+		return nil, doit.NewMissingArgsErr(c.NS)
+	}
+
+	// droplets selected by id need their name for the snapshot name.
+	r := c.Resolver()
+	droplets, err := r.Droplets(c.Args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.LoadDroplets(droplets)
+}
+
+func (r *snapshotRotation) rotate(c *CmdConfig, d do.Droplet) error {
+	now := timeNow()
+
+	var buf bytes.Buffer
+	if err := r.tmpl.Execute(&buf, snapshotName{Droplet: d.Name, ID: d.ID, Now: now}); err != nil {
+		return err
+	}
+	name := buf.String()
+
+	if r.dryRun {
+		fmt.Fprintf(c.Out, "%s: would snapshot as %s\n", d.Name, name)
+	} else {
+		a, err := c.DropletActions().Snapshot(d.ID, name)
+		if err != nil {
+			return err
+		}
+
+		w, err := newActionWaiter(c, 5)
+		if err != nil {
+			return err
+		}
+
+		if _, err := w.wait(a.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.Out, "%s: created snapshot %s\n", d.Name, name)
+	}
+
+	snapshots, err := c.Droplets().Snapshots(d.ID)
+	if err != nil {
+		return err
+	}
+
+	expired := r.expired(snapshots, name, now)
+	if len(expired) == 0 {
+		return nil
+	}
+
+	var described []string
+	for _, s := range expired {
+		described = append(described, fmt.Sprintf("%s (%d, %s)", s.Name, s.ID, s.Created))
+	}
+
+	ok, err := confirmDestructive(c, "delete", "snapshots of "+d.Name, staticDescription(described...))
+	if err != nil || !ok {
+		return err
+	}
+
+	for _, s := range expired {
+		if err := c.Images().Delete(s.ID); err != nil {
+			return err
+		}
+		fmt.Fprintf(c.Out, "%s: deleted snapshot %s (%d, %s)\n", d.Name, s.Name, s.ID, s.Created)
+	}
+
+	return nil
+}
+
+// expired returns the snapshots to delete. current is the name of the
+// snapshot just taken, which counts towards --keep even if a dry run means
+// it doesn't exist.
+func (r *snapshotRotation) expired(snapshots do.Images, current string, now time.Time) do.Images {
+	var matched datedImages
+	currentSeen := false
+	for _, s := range snapshots {
+		if !r.match.Match(s.Name) {
+			continue
+		}
+
+		created, _ := time.Parse(time.RFC3339, s.Created)
+		matched = append(matched, datedImage{image: s, created: created})
+		if s.Name == current {
+			currentSeen = true
+		}
+	}
+
+	sort.Stable(matched)
+
+	kept := 0
+	if !currentSeen && r.match.Match(current) {
+		kept++
+	}
+
+	var out do.Images
+	for _, m := range matched {
+		if m.image.Name == current {
+			kept++
+			continue
+		}
+
+		tooMany := r.keep > 0 && kept >= r.keep
+		tooOld := r.olderThan > 0 && !m.created.IsZero() && now.Sub(m.created) > r.olderThan
+		if tooMany || tooOld {
+			out = append(out, m.image)
+			continue
+		}
+
+		kept++
+	}
+
+	return out
+}
+
+type datedImage struct {
+	image   do.Image
+	created time.Time
+}
+
+// datedImages sorts images newest first.
+type datedImages []datedImage
+
+func (d datedImages) Len() int           { return len(d) }
+func (d datedImages) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+func (d datedImages) Less(i, j int) bool { return d[i].created.After(d[j].created) }
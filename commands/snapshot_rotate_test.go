@@ -0,0 +1,159 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRotateNow = time.Date(2016, 6, 30, 2, 0, 0, 0, time.UTC)
+
+func testSnapshot(id int, name, created string) do.Image {
+	return do.Image{Image: &godo.Image{ID: id, Name: name, Type: "snapshot", Created: created}}
+}
+
+func withRotateClock(fn func()) {
+	og := timeNow
+	defer func() { timeNow = og }()
+
+	timeNow = func() time.Time { return testRotateNow }
+	fn()
+}
+
+func TestDropletSnapshotRotate_Keep(t *testing.T) {
+	withRotateClock(func() {
+		withActionWaiterStubs(false, func(_ *bytes.Buffer, _ *[]time.Duration) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				snapshots := do.Images{
+					testSnapshot(10, "nightly-20160627", "2016-06-27T02:00:00Z"),
+					testSnapshot(11, "nightly-20160628", "2016-06-28T02:00:00Z"),
+					testSnapshot(12, "nightly-20160629", "2016-06-29T02:00:00Z"),
+					testSnapshot(13, "nightly-20160630", "2016-06-30T02:05:00Z"),
+					testSnapshot(14, "before-upgrade", "2016-01-01T00:00:00Z"),
+				}
+
+				tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+				tm.dropletActions.On("Snapshot", 1, "nightly-20160630").Return(testActionWithStatus(2, "in-progress"), nil)
+				tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+				tm.droplets.On("Snapshots", 1).Return(snapshots, nil)
+				tm.images.On("Delete", 10).Return(nil)
+				tm.images.On("Delete", 11).Return(nil)
+
+				var out bytes.Buffer
+				config.Out = &out
+				config.Args = append(config.Args, "1")
+				config.Doit.Set(config.NS, doit.ArgNameTemplate, `nightly-{{.Now.Format "20060102"}}`)
+				config.Doit.Set(config.NS, doit.ArgMatch, "nightly-*")
+				config.Doit.Set(config.NS, doit.ArgKeep, 2)
+				config.Doit.Set(config.NS, doit.ArgForce, true)
+
+				err := RunDropletSnapshotRotate(config)
+				assert.NoError(t, err)
+				assert.Equal(t, `a-droplet: created snapshot nightly-20160630
+a-droplet: deleted snapshot nightly-20160628 (11, 2016-06-28T02:00:00Z)
+a-droplet: deleted snapshot nightly-20160627 (10, 2016-06-27T02:00:00Z)
+`, out.String())
+			})
+		})
+	})
+}
+
+func TestDropletSnapshotRotate_OlderThanDryRun(t *testing.T) {
+	withRotateClock(func() {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			snapshots := do.Images{
+				testSnapshot(10, "a-droplet-1", "2016-05-01T00:00:00Z"),
+				testSnapshot(11, "a-droplet-2", "2016-06-15T00:00:00Z"),
+			}
+
+			tm.droplets.On("ListByTag", "web").Return(do.Droplets{testDroplet}, nil)
+			tm.droplets.On("Snapshots", 1).Return(snapshots, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Doit.Set(config.NS, doit.ArgTagName, "web")
+			config.Doit.Set(config.NS, doit.ArgNameTemplate, "{{.Droplet}}-new")
+			config.Doit.Set(config.NS, doit.ArgMatch, "re:^a-droplet-")
+			config.Doit.Set(config.NS, doit.ArgOlderThan, "30d")
+			config.Doit.Set(config.NS, doit.ArgDryRun, true)
+
+			err := RunDropletSnapshotRotate(config)
+			assert.NoError(t, err)
+			assert.Equal(t, `a-droplet: would snapshot as a-droplet-new
+would delete snapshots of a-droplet:
+  a-droplet-1 (10, 2016-05-01T00:00:00Z)
+`, out.String())
+		})
+	})
+}
+
+func TestDropletSnapshotRotate_NeedsForce(t *testing.T) {
+	withRotateClock(func() {
+		withActionWaiterStubs(false, func(_ *bytes.Buffer, _ *[]time.Duration) {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				snapshots := do.Images{
+					testSnapshot(10, "a-droplet-1", "2016-05-01T00:00:00Z"),
+				}
+
+				tm.droplets.On("Get", 1).Return(&testDroplet, nil)
+				tm.dropletActions.On("Snapshot", 1, "a-droplet-new").Return(testActionWithStatus(2, "completed"), nil)
+				tm.actions.On("Get", 2).Return(testActionWithStatus(2, "completed"), nil)
+				tm.droplets.On("Snapshots", 1).Return(snapshots, nil)
+
+				config.Out = ioutil.Discard
+				config.Args = append(config.Args, "1")
+				config.Doit.Set(config.NS, doit.ArgNameTemplate, "{{.Droplet}}-new")
+				config.Doit.Set(config.NS, doit.ArgMatch, "a-droplet-*")
+				config.Doit.Set(config.NS, doit.ArgKeep, 1)
+
+				err := RunDropletSnapshotRotate(config)
+				assert.EqualError(t, err, "a-droplet: refusing to delete snapshots of a-droplet without --force when not running interactively")
+			})
+		})
+	})
+}
+
+func TestDropletSnapshotRotate_Invalid(t *testing.T) {
+	cases := []struct {
+		name string
+		set  map[string]interface{}
+		args []string
+	}{
+		{"no match", map[string]interface{}{doit.ArgKeep: 2}, []string{"1"}},
+		{"no keep or older than", map[string]interface{}{doit.ArgMatch: "*"}, []string{"1"}},
+		{"bad duration", map[string]interface{}{doit.ArgMatch: "*", doit.ArgOlderThan: "soon"}, []string{"1"}},
+		{"no droplets", map[string]interface{}{doit.ArgMatch: "*", doit.ArgKeep: 1}, nil},
+		{"args and tag", map[string]interface{}{doit.ArgMatch: "*", doit.ArgKeep: 1, doit.ArgTagName: "web"}, []string{"1"}},
+	}
+
+	for _, c := range cases {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			for k, v := range c.set {
+				config.Doit.Set(config.NS, k, v)
+			}
+			config.Args = append(config.Args, c.args...)
+
+			err := RunDropletSnapshotRotate(config)
+			assert.Error(t, err, c.name)
+		})
+	}
+}
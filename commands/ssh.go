@@ -16,6 +16,7 @@ package commands
 import (
 	"errors"
 	"fmt"
+	"net"
 	"os/user"
 	"path/filepath"
 	"regexp"
@@ -24,10 +25,15 @@ import (
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/resolver"
+	"github.com/bryanl/doit/pkg/ssh"
 )
 
 const (
 	sshNoAddress = "could not find droplet address"
+
+	acceptNewHostKeysHelp = "add the host keys of droplets seen for the first time to known_hosts without asking, " +
+		"new hosts are refused when not interactive otherwise"
 )
 
 var (
@@ -37,21 +43,36 @@ var (
 
 // SSH creates the ssh commands heirarchy
 func SSH() *Command {
-	usr, err := user.Current()
-	checkErr(err)
-
-	path := filepath.Join(usr.HomeDir, ".ssh", "id_rsa")
+	path := defaultSSHKeyPath()
 
-	cmdSSH := CmdBuilder(nil, RunSSH, "ssh <droplet-id | host>", "ssh to droplet", Writer,
+	cmdSSH := CmdBuilder(nil, RunSSH, "ssh <droplet-id | name | ip | GLOB | re:REGEX> [-- command]", "ssh to droplet, or run a command on it", Writer,
 		docCategories("droplet"))
-	AddStringFlag(cmdSSH, doit.ArgSSHUser, "root", "ssh user")
+	AddStringFlag(cmdSSH, doit.ArgSSHUser, "", "ssh user, defaults to root or the image's usual user")
 	AddStringFlag(cmdSSH, doit.ArgsSSHKeyPath, path, "path to private ssh key")
 	AddIntFlag(cmdSSH, doit.ArgsSSHPort, 22, "port sshd is running on")
+	AddStringSliceFlag(cmdSSH, doit.ArgLocalForward, []string{},
+		"forward a local port to the droplet's network as [bind_address:]port:host:hostport")
+	AddStringSliceFlag(cmdSSH, doit.ArgRemoteForward, []string{},
+		"forward a port on the droplet to the local network as [bind_address:]port:host:hostport")
+	AddIntFlag(cmdSSH, doit.ArgSOCKS, 0, "run a SOCKS5 proxy through the droplet on this local port")
+	AddBoolFlag(cmdSSH, doit.ArgBackground, false, "keep forwards up without opening a shell until interrupted")
+	AddBoolFlag(cmdSSH, doit.ArgSSHInternal, false,
+		"use the built in ssh client, which checks known_hosts and uses ssh-agent, instead of the ssh command")
+	AddStringSliceFlag(cmdSSH, doit.ArgIdentityFile, []string{}, "another private ssh key to try, can be given more than once")
+	AddStringFlag(cmdSSH, doit.ArgKnownHostsFile, "", "known_hosts file to check the droplet's host key in, defaults to ~/.ssh/known_hosts")
+	AddBoolFlag(cmdSSH, doit.ArgAcceptNewHostKeys, false, acceptNewHostKeysHelp)
+	AddBoolFlag(cmdSSH, doit.ArgPrivate, false, "connect to the droplet's private address")
+	AddBoolFlag(cmdSSH, doit.ArgIPv6Address, false, "connect to the droplet's public IPv6 address")
+	AddStringFlag(cmdSSH, doit.ArgJump, "",
+		"connect through a bastion, given as [user@]<droplet-id | name | host>[:port], a host ending in a dot is never looked up as a droplet, "+
+			"set compute.ssh.jump in the config file to always use one")
 
 	return cmdSSH
 }
 
 // RunSSH finds a droplet to ssh to given input parameters (name or id).
+// Any arguments after the droplet are run as a command instead of opening
+// an interactive shell.
 func RunSSH(c *CmdConfig) error {
 	if len(c.Args) == 0 {
 		return doit.NewMissingArgsErr(c.NS)
@@ -78,6 +99,19 @@ func RunSSH(c *CmdConfig) error {
 		return err
 	}
 
+	opts, err := sshOptions(c)
+	if err != nil {
+		return err
+	}
+
+	if opts.Background && len(c.Args) > 1 {
+		return fmt.Errorf("--%s can't be used with a command", doit.ArgBackground)
+	}
+
+	if opts.Record != "" && (opts.Background || len(c.Args) > 1) {
+		return fmt.Errorf("--%s needs an interactive session", doit.ArgRecord)
+	}
+
 	var droplet *do.Droplet
 
 	ds := c.Droplets()
@@ -91,12 +125,7 @@ func RunSSH(c *CmdConfig) error {
 
 		droplet = doDroplet
 	} else {
-		// dropletID is a string
-		droplets, err := ds.List()
-		if err != nil {
-			return err
-		}
-
+		// dropletID is a name, IP, glob or regular expression
 		shi := extractHostInfo(dropletID)
 
 		user = shi.user
@@ -104,41 +133,217 @@ func RunSSH(c *CmdConfig) error {
 			port = i
 		}
 
-		for _, d := range droplets {
-			if d.Name == shi.host {
-				droplet = &d
-				break
-			}
-			if strconv.Itoa(d.ID) == shi.host {
-				droplet = &d
-				break
-			}
-		}
-
-		if droplet == nil {
-			return errors.New("could not find droplet")
+		doDroplet, err := c.Resolver().Droplet(shi.host)
+		if err != nil {
+			return err
 		}
 
+		droplet = doDroplet
 	}
 
 	if user == "" {
 		user = defaultSSHUser(droplet)
 	}
 
-	ip, err := droplet.PublicIPv4()
+	ip, err := sshAddress(c, droplet)
 	if err != nil {
 		return err
 	}
 
-	if ip == "" {
-		return errors.New("could not find droplet address")
+	jump, err := c.Doit.GetString(c.NS, doit.ArgJump)
+	if err != nil {
+		return err
+	}
+
+	if opts.Jump, err = sshJumpHost(c, jump); err != nil {
+		return err
+	}
+
+	runner := c.Doit.SSH(user, ip, keyPath, port, opts)
+	if len(c.Args) > 1 {
+		return runner.Exec(strings.Join(c.Args[1:], " "))
 	}
 
-	runner := c.Doit.SSH(user, ip, keyPath, port)
 	return runner.Run()
 }
 
+// sshAddress picks the droplet address to connect to from --private and
+// --ipv6.
+func sshAddress(c *CmdConfig, droplet *do.Droplet) (string, error) {
+	private, err := c.Doit.GetBool(c.NS, doit.ArgPrivate)
+	if err != nil {
+		return "", err
+	}
+
+	ipv6, err := c.Doit.GetBool(c.NS, doit.ArgIPv6Address)
+	if err != nil {
+		return "", err
+	}
+
+	iface := do.InterfacePublic
+	switch {
+	case private && ipv6:
+		return "", fmt.Errorf("--%s and --%s can't be used together", doit.ArgPrivate, doit.ArgIPv6Address)
+	case private:
+		iface = do.InterfacePrivate
+	case ipv6:
+		iface = do.InterfacePublicIPv6
+	}
+
+	ip := droplet.IPs()[iface]
+	switch {
+	case ip != "":
+		return ip, nil
+	case iface == do.InterfacePublic:
+		return "", errors.New(sshNoAddress)
+	}
+
+	return "", fmt.Errorf("%s has no %s address", droplet.Name, iface)
+}
+
+// sshJumpHost turns a --jump value into [user@]host[:port] for ssh. A host
+// which isn't an address is looked up as a droplet and its public address
+// used. Hosts which match no droplet, or end in a dot, are passed to ssh as
+// host names.
+func sshJumpHost(c *CmdConfig, spec string) (string, error) {
+	if spec == "" {
+		return "", nil
+	}
+
+	user, host, port := "", spec, ""
+	if i := strings.LastIndex(host, "@"); i >= 0 {
+		user, host = host[:i], host[i+1:]
+	}
+
+	if h, p, err := net.SplitHostPort(host); err == nil {
+		host, port = h, p
+	}
+
+	if net.ParseIP(host) == nil && !strings.HasSuffix(host, ".") {
+		droplets, err := sshDroplets(c, host)
+		if _, ok := err.(*resolver.NotFoundError); ok {
+			droplets = nil
+		} else if err != nil {
+			return "", err
+		}
+
+		if len(droplets) > 1 {
+			return "", fmt.Errorf("jump host %s matches %d droplets", host, len(droplets))
+		}
+
+		if len(droplets) == 1 {
+			d := droplets[0]
+			ip, err := d.PublicIPv4()
+			if err != nil {
+				return "", err
+			}
+
+			if ip == "" {
+				return "", fmt.Errorf("jump droplet %s has no public address", d.Name)
+			}
+
+			host = ip
+			if user == "" {
+				user = defaultSSHUser(&d)
+			}
+		}
+	}
+
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+
+	if user != "" {
+		host = user + "@" + host
+	}
+
+	return host, nil
+}
+
+// sshOptions builds the optional ssh settings from flags.
+func sshOptions(c *CmdConfig) (ssh.Options, error) {
+	var opts ssh.Options
+	var err error
+
+	if opts.LocalForwards, err = sshForwards(c, doit.ArgLocalForward); err != nil {
+		return opts, err
+	}
+
+	if opts.RemoteForwards, err = sshForwards(c, doit.ArgRemoteForward); err != nil {
+		return opts, err
+	}
+
+	if opts.SOCKSPort, err = c.Doit.GetInt(c.NS, doit.ArgSOCKS); err != nil {
+		return opts, err
+	}
+
+	if opts.Background, err = c.Doit.GetBool(c.NS, doit.ArgBackground); err != nil {
+		return opts, err
+	}
+
+	if opts.Internal, err = c.Doit.GetBool(c.NS, doit.ArgSSHInternal); err != nil {
+		return opts, err
+	}
+
+	if opts.Record, err = c.Doit.GetString(c.NS, doit.ArgRecord); err != nil {
+		return opts, err
+	}
+
+	if opts.IdentityFiles, err = c.Doit.GetStringSlice(c.NS, doit.ArgIdentityFile); err != nil {
+		return opts, err
+	}
+
+	if opts.KnownHostsFile, err = c.Doit.GetString(c.NS, doit.ArgKnownHostsFile); err != nil {
+		return opts, err
+	}
+
+	if opts.AcceptNewHostKeys, err = c.Doit.GetBool(c.NS, doit.ArgAcceptNewHostKeys); err != nil {
+		return opts, err
+	}
+
+	forwards := len(opts.LocalForwards) + len(opts.RemoteForwards)
+	if opts.Background && forwards == 0 && opts.SOCKSPort == 0 {
+		return opts, fmt.Errorf("--%s needs a forward or --%s", doit.ArgBackground, doit.ArgSOCKS)
+	}
+
+	return opts, nil
+}
+
+// sshForwards returns the forwards given by a flag after checking them.
+func sshForwards(c *CmdConfig, flag string) ([]string, error) {
+	specs, err := c.Doit.GetStringSlice(c.NS, flag)
+	if err != nil {
+		return nil, err
+	}
+
+	var forwards []string
+	for _, spec := range specs {
+		if spec == "" {
+			continue
+		}
+
+		if _, err := ssh.ParseForward(spec); err != nil {
+			return nil, fmt.Errorf("--%s: %v", flag, err)
+		}
+
+		forwards = append(forwards, spec)
+	}
+
+	return forwards, nil
+}
+
+func defaultSSHKeyPath() string {
+	usr, err := user.Current()
+	checkErr(err)
+
+	return filepath.Join(usr.HomeDir, ".ssh", "id_rsa")
+}
+
 func defaultSSHUser(droplet *do.Droplet) string {
+	if droplet.Image == nil {
+		return "root"
+	}
+
 	slug := strings.ToLower(droplet.Image.Slug)
 	if strings.Contains(slug, "coreos") {
 		return "core"
@@ -166,3 +371,15 @@ func extractHostInfo(in string) sshHostInfo {
 		port: r["m3"],
 	}
 }
+
+// sshDroplets resolves a host argument to droplets. Droplets selected by id
+// are fetched so their names and addresses are known.
+func sshDroplets(c *CmdConfig, host string) (do.Droplets, error) {
+	r := c.Resolver()
+	droplets, err := r.Droplets(host)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.LoadDroplets(droplets)
+}
@@ -0,0 +1,340 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/pkg/runner"
+	"github.com/bryanl/doit/pkg/ssh"
+)
+
+const (
+	sshExecOK      = "ok"
+	sshExecFailed  = "failed"
+	sshExecError   = "error"
+	sshExecSkipped = "skipped"
+)
+
+// remoteExec runs a command on a remote host without a terminal. It is
+// replaced in tests.
+var remoteExec = func(user, host, keyPath string, port int, acceptNew bool, cmd string, stdout, stderr io.Writer) error {
+	r := &ssh.Runner{
+		User:    user,
+		Host:    host,
+		KeyPath: keyPath,
+		Port:    port,
+		Options: ssh.Options{AcceptNewHostKeys: acceptNew},
+	}
+
+	return r.ExecOutput(cmd, stdout, stderr)
+}
+
+// SSHExec creates the ssh-exec command.
+func SSHExec() *Command {
+	cmd := CmdBuilder(nil, RunSSHExec,
+		"ssh-exec [<droplet-id | name | GLOB | re:REGEX>] -- <command>",
+		"run a command on many droplets at once", Writer,
+		displayerType(&sshExecReport{}), docCategories("droplet"))
+	AddStringFlag(cmd, doit.ArgTagName, "", "run on the droplets with this tag instead of a droplet argument")
+	AddStringFlag(cmd, doit.ArgSSHUser, "", "ssh user, defaults to root or the image's usual user")
+	AddStringFlag(cmd, doit.ArgsSSHKeyPath, defaultSSHKeyPath(), "path to private ssh key")
+	AddIntFlag(cmd, doit.ArgsSSHPort, 22, "port sshd is running on")
+	AddBoolFlag(cmd, doit.ArgAcceptNewHostKeys, false, acceptNewHostKeysHelp)
+	AddIntFlag(cmd, doit.ArgParallel, 10, "number of droplets to run the command on at once")
+	AddBoolFlag(cmd, doit.ArgFailFast, false, "don't start the command on more droplets once it has failed on one")
+
+	return cmd
+}
+
+// sshExecResult is the outcome of running a command on one droplet.
+type sshExecResult struct {
+	Droplet    string  `json:"droplet"`
+	ID         int     `json:"id"`
+	Status     string  `json:"status"`
+	ExitStatus int     `json:"exit_status"`
+	Duration   float64 `json:"duration_seconds"`
+	Error      string  `json:"error,omitempty"`
+	Stdout     string  `json:"stdout,omitempty"`
+	Stderr     string  `json:"stderr,omitempty"`
+}
+
+// sshExec runs a command on a set of droplets.
+type sshExec struct {
+	command   string
+	user      string
+	keyPath   string
+	port      int
+	acceptNew bool
+	parallel  int
+	failFast  bool
+
+	// capture keeps output in the results instead of streaming it.
+	capture bool
+	stdout  io.Writer
+	stderr  io.Writer
+	outMu   sync.Mutex
+
+	mu     sync.Mutex
+	failed bool
+}
+
+// RunSSHExec runs a command on every droplet selected by an argument or by
+// --tag. Output lines are prefixed with the droplet's name, and a summary
+// of exit statuses is displayed once the command has finished everywhere.
+func RunSSHExec(c *CmdConfig) error {
+	droplets, args, err := sshExecDroplets(c)
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("a command to run is needed after --")
+	}
+
+	e := &sshExec{command: strings.Join(args, " "), stdout: c.Out, stderr: progressOut}
+
+	if e.user, err = c.Doit.GetString(c.NS, doit.ArgSSHUser); err != nil {
+		return err
+	}
+
+	if e.keyPath, err = c.Doit.GetString(c.NS, doit.ArgsSSHKeyPath); err != nil {
+		return err
+	}
+
+	if e.port, err = c.Doit.GetInt(c.NS, doit.ArgsSSHPort); err != nil {
+		return err
+	}
+
+	if e.acceptNew, err = c.Doit.GetBool(c.NS, doit.ArgAcceptNewHostKeys); err != nil {
+		return err
+	}
+
+	if e.parallel, err = c.Doit.GetInt(c.NS, doit.ArgParallel); err != nil {
+		return err
+	}
+
+	if e.parallel < 1 {
+		e.parallel = 1
+	}
+
+	if e.failFast, err = c.Doit.GetBool(c.NS, doit.ArgFailFast); err != nil {
+		return err
+	}
+
+	output, err := c.Doit.GetString(doit.NSRoot, doit.ArgOutput)
+	if err != nil {
+		return err
+	}
+	e.capture = output == "json"
+
+	results := e.run(droplets)
+
+	if err := c.Display(&sshExecReport{results: results}); err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, r := range results {
+		if r.Status != sshExecOK {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("command failed on %d of %d droplets", failed, len(results))
+	}
+
+	return nil
+}
+
+// sshExecDroplets returns the selected droplets and the command arguments.
+// With --tag every argument is part of the command, otherwise the first
+// one selects the droplets.
+func sshExecDroplets(c *CmdConfig) (do.Droplets, []string, error) {
+	tag, err := c.Doit.GetString(c.NS, doit.ArgTagName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if tag != "" {
+		droplets, err := c.Droplets().ListByTag(tag)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if len(droplets) == 0 {
+			return nil, nil, fmt.Errorf("no droplets are tagged %s", tag)
+		}
+
+		return droplets, c.Args, nil
+	}
+
+	if len(c.Args) == 0 {
+		return nil, nil, doit.NewMissingArgsErr(c.NS)
+	}
+
+	droplets, err := sshDroplets(c, c.Args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return droplets, c.Args[1:], nil
+}
+
+// run runs the command on droplets, at most e.parallel at once. Results
+// are in the same order as droplets.
+func (e *sshExec) run(droplets do.Droplets) []sshExecResult {
+	results := make([]sshExecResult, len(droplets))
+	sem := make(chan struct{}, e.parallel)
+
+	var wg sync.WaitGroup
+	for i := range droplets {
+		wg.Add(1)
+		sem <- struct{}{}
+
+		go func(i int) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			results[i] = e.runOne(droplets[i])
+		}(i)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (e *sshExec) runOne(d do.Droplet) sshExecResult {
+	result := sshExecResult{Droplet: d.Name, ID: d.ID}
+
+	e.mu.Lock()
+	skip := e.failFast && e.failed
+	e.mu.Unlock()
+
+	if skip {
+		result.Status = sshExecSkipped
+		return result
+	}
+
+	user := e.user
+	if user == "" {
+		user = defaultSSHUser(&d)
+	}
+
+	var stdout, stderr bytes.Buffer
+	var outw, errw io.Writer = &stdout, &stderr
+	if !e.capture {
+		ow := &prefixWriter{mu: &e.outMu, out: e.stdout, prefix: d.Name + ": "}
+		ew := &prefixWriter{mu: &e.outMu, out: e.stderr, prefix: d.Name + ": "}
+		defer ow.Flush()
+		defer ew.Flush()
+		outw, errw = ow, ew
+	}
+
+	start := timeNow()
+
+	ip, err := d.PublicIPv4()
+	if err == nil && ip == "" {
+		err = fmt.Errorf(sshNoAddress)
+	}
+
+	if err == nil {
+		err = remoteExec(user, ip, e.keyPath, e.port, e.acceptNew, e.command, outw, errw)
+	}
+
+	result.Duration = timeNow().Sub(start).Seconds()
+	result.Stdout = stdout.String()
+	result.Stderr = stderr.String()
+
+	switch ee := err.(type) {
+	case nil:
+		result.Status = sshExecOK
+	case *runner.ExitError:
+		result.Status = sshExecFailed
+		result.ExitStatus = ee.Status
+	default:
+		result.Status = sshExecError
+		result.ExitStatus = -1
+		result.Error = err.Error()
+	}
+
+	if err != nil {
+		e.mu.Lock()
+		e.failed = true
+		e.mu.Unlock()
+	}
+
+	return result
+}
+
+// prefixWriter writes complete lines to out with a prefix. Writers sharing
+// mu never interleave their lines.
+type prefixWriter struct {
+	mu     *sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+
+		if err := w.writeLine(w.buf[:i+1]); err != nil {
+			return 0, err
+		}
+		w.buf = w.buf[i+1:]
+	}
+
+	return len(p), nil
+}
+
+// Flush writes a final line which didn't end with a newline.
+func (w *prefixWriter) Flush() error {
+	if len(w.buf) == 0 {
+		return nil
+	}
+
+	line := append(w.buf, '\n')
+	w.buf = nil
+	return w.writeLine(line)
+}
+
+func (w *prefixWriter) writeLine(line []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+	return err
+}
+
+// durationString formats seconds for display to the millisecond.
+func durationString(seconds float64) string {
+	return (time.Duration(seconds*1000) * time.Millisecond).String()
+}
@@ -0,0 +1,161 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/pkg/runner"
+	"github.com/stretchr/testify/assert"
+)
+
+// withRemoteExec replaces remoteExec with fn and records the hosts it ran
+// on.
+func withRemoteExec(fn func(host, cmd string, stdout, stderr io.Writer) error, tFn func(hosts func() []string)) {
+	og, ogOut := remoteExec, progressOut
+	defer func() { remoteExec, progressOut = og, ogOut }()
+	progressOut = ioutil.Discard
+
+	var mu sync.Mutex
+	var hosts []string
+	remoteExec = func(user, host, keyPath string, port int, acceptNew bool, cmd string, stdout, stderr io.Writer) error {
+		mu.Lock()
+		hosts = append(hosts, host)
+		mu.Unlock()
+
+		return fn(host, cmd, stdout, stderr)
+	}
+
+	withRotateClock(func() {
+		tFn(func() []string {
+			mu.Lock()
+			defer mu.Unlock()
+			sort.Strings(hosts)
+			return hosts
+		})
+	})
+}
+
+func TestSSHExecCommand(t *testing.T) {
+	cmd := SSHExec()
+	assert.NotNil(t, cmd)
+	assertCommandNames(t, cmd)
+}
+
+func TestSSHExec_Tag(t *testing.T) {
+	exec := func(host, cmd string, stdout, stderr io.Writer) error {
+		fmt.Fprintf(stdout, "%s says\nhello", host)
+		return nil
+	}
+
+	withRemoteExec(exec, func(hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("ListByTag", "web").Return(testDropletList, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "systemctl", "restart app")
+			config.Doit.Set(config.NS, doit.ArgTagName, "web")
+
+			err := RunSSHExec(config)
+			assert.NoError(t, err)
+
+			assert.Equal(t, []string{"8.8.8.8", "8.8.8.9"}, hosts())
+			assert.Contains(t, out.String(), "a-droplet: 8.8.8.8 says\na-droplet: hello\n")
+			assert.Contains(t, out.String(), "another-droplet: 8.8.8.9 says\nanother-droplet: hello\n")
+		})
+	})
+}
+
+func TestSSHExec_JSON(t *testing.T) {
+	exec := func(host, cmd string, stdout, stderr io.Writer) error {
+		if host == "8.8.8.9" {
+			fmt.Fprint(stderr, "no such unit")
+			return &runner.ExitError{Status: 5}
+		}
+
+		fmt.Fprintf(stdout, "ran %s", cmd)
+		return nil
+	}
+
+	withRemoteExec(exec, func(hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("List").Return(testDropletList, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "*droplet", "uptime")
+			config.Doit.Set(doit.NSRoot, doit.ArgOutput, "json")
+
+			err := RunSSHExec(config)
+			assert.EqualError(t, err, "command failed on 1 of 2 droplets")
+
+			var results []sshExecResult
+			assert.NoError(t, json.Unmarshal(out.Bytes(), &results))
+			assert.Equal(t, []sshExecResult{
+				{Droplet: "a-droplet", ID: 1, Status: sshExecOK, Stdout: "ran uptime"},
+				{Droplet: "another-droplet", ID: 3, Status: sshExecFailed, ExitStatus: 5, Stderr: "no such unit"},
+			}, results)
+		})
+	})
+}
+
+func TestSSHExec_FailFast(t *testing.T) {
+	exec := func(host, cmd string, stdout, stderr io.Writer) error {
+		return fmt.Errorf("connection refused")
+	}
+
+	withRemoteExec(exec, func(hosts func() []string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.droplets.On("ListByTag", "web").Return(testDropletList, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, "uptime")
+			config.Doit.Set(config.NS, doit.ArgTagName, "web")
+			config.Doit.Set(config.NS, doit.ArgParallel, 1)
+			config.Doit.Set(config.NS, doit.ArgFailFast, true)
+
+			err := RunSSHExec(config)
+			assert.EqualError(t, err, "command failed on 2 of 2 droplets")
+			assert.Equal(t, []string{"8.8.8.8"}, hosts())
+
+			lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+			if assert.Len(t, lines, 3) {
+				assert.Regexp(t, `^a-droplet\s+error\s+-1\s+0s\s+connection refused$`, lines[1])
+				assert.Regexp(t, `^another-droplet\s+skipped\s+0\s+0s\s*$`, lines[2])
+			}
+		})
+	})
+}
+
+func TestSSHExec_MissingCommand(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testDropletList, nil)
+
+		config.Args = append(config.Args, "a-droplet")
+
+		err := RunSSHExec(config)
+		assert.EqualError(t, err, "a command to run is needed after --")
+	})
+}
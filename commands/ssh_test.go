@@ -19,7 +19,9 @@ import (
 	"testing"
 
 	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
 	"github.com/bryanl/doit/pkg/runner"
+	"github.com/bryanl/doit/pkg/ssh"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -36,8 +38,8 @@ func TestSSHComand(t *testing.T) {
 	assertCommandNames(t, cmd)
 }
 
-func (s *sshMock) cmd() func(u, h, kp string, p int) runner.Runner {
-	return func(u, h, kp string, p int) runner.Runner {
+func (s *sshMock) cmd() func(u, h, kp string, p int, o ssh.Options) runner.Runner {
+	return func(u, h, kp string, p int, o ssh.Options) runner.Runner {
 		s.didRun = true
 		s.user = u
 		s.host = h
@@ -63,6 +65,220 @@ func TestSSH_ID(t *testing.T) {
 	})
 }
 
+func TestSSH_Exec(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		r := &doit.MockRunner{Err: &runner.ExitError{Status: 2}}
+		config.Doit.(*TestConfig).SSHFn = func(u, h, kp string, p int, o ssh.Options) runner.Runner {
+			return r
+		}
+
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID), "uptime", "-p")
+
+		err := RunSSH(config)
+		assert.Equal(t, &runner.ExitError{Status: 2}, err)
+		assert.Equal(t, "uptime -p", r.Cmd)
+	})
+}
+
+func TestSSH_Forwards(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		var opts ssh.Options
+		config.Doit.(*TestConfig).SSHFn = func(u, h, kp string, p int, o ssh.Options) runner.Runner {
+			opts = o
+			return &doit.MockRunner{}
+		}
+
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgLocalForward, []string{"5432:localhost:5432"})
+		config.Doit.Set(config.NS, doit.ArgRemoteForward, []string{"0.0.0.0:8080:localhost:3000"})
+		config.Doit.Set(config.NS, doit.ArgSOCKS, 1080)
+		config.Doit.Set(config.NS, doit.ArgBackground, true)
+
+		err := RunSSH(config)
+		assert.NoError(t, err)
+		assert.Equal(t, ssh.Options{
+			LocalForwards:  []string{"5432:localhost:5432"},
+			RemoteForwards: []string{"0.0.0.0:8080:localhost:3000"},
+			SOCKSPort:      1080,
+			Background:     true,
+		}, opts)
+	})
+}
+
+func TestSSH_InvalidForward(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgLocalForward, []string{"5432"})
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, `--local-forward: invalid forward "5432", expected [bind_address:]port:host:hostport`)
+	})
+}
+
+func TestSSH_BackgroundNeedsForward(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgBackground, true)
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, "--background needs a forward or --socks")
+	})
+}
+
+func TestSSH_Record(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgRecord, "session.cast")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "session.cast", opts.Record)
+		})
+	})
+}
+
+func TestSSH_RecordNeedsShell(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID), "uptime")
+		config.Doit.Set(config.NS, doit.ArgRecord, "session.cast")
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, "--record needs an interactive session")
+	})
+}
+
+// withSSHRunner records the host and options ssh is run with.
+func withSSHRunner(config *CmdConfig, fn func(host *string, opts *ssh.Options)) {
+	var host string
+	var opts ssh.Options
+	config.Doit.(*TestConfig).SSHFn = func(u, h, kp string, p int, o ssh.Options) runner.Runner {
+		host, opts = h, o
+		return &doit.MockRunner{}
+	}
+
+	fn(&host, &opts)
+}
+
+func TestSSH_Private(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgPrivate, true)
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "172.16.1.2", *host)
+		})
+	})
+}
+
+func TestSSH_NoIPv6(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+		config.Doit.Set(config.NS, doit.ArgIPv6Address, true)
+
+		err := RunSSH(config)
+		assert.EqualError(t, err, "a-droplet has no public_ipv6 address")
+	})
+}
+
+func TestSSH_JumpDroplet(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("List").Return(testPrivateDropletList, nil).Once()
+		tm.droplets.On("List").Return(testDropletList, nil).Once()
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, testPrivateDroplet.Name)
+			config.Doit.Set(config.NS, doit.ArgPrivate, true)
+			config.Doit.Set(config.NS, doit.ArgJump, "another-droplet:2222")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "172.16.1.2", *host)
+			assert.Equal(t, "root@8.8.8.9:2222", opts.Jump)
+		})
+	})
+}
+
+func TestSSH_JumpHost(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+		tm.droplets.On("List").Return(testDropletList, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgJump, "admin@bastion.example.com")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "admin@bastion.example.com", opts.Jump)
+		})
+	})
+}
+
+func TestSSH_JumpDottedDroplet(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		bastion := *anotherTestDroplet.Droplet
+		bastion.Name = "bastion.example.com"
+		droplets := do.Droplets{testDroplet, {Droplet: &bastion}}
+
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+		tm.droplets.On("List").Return(droplets, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgJump, "bastion.example.com")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "root@8.8.8.9", opts.Jump)
+		})
+	})
+}
+
+func TestSSH_JumpHostTrailingDot(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgJump, "admin@bastion.example.com.")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "admin@bastion.example.com.", opts.Jump)
+		})
+	})
+}
+
+func TestSSH_KeyFiles(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		tm.droplets.On("Get", testDroplet.ID).Return(&testDroplet, nil)
+
+		withSSHRunner(config, func(host *string, opts *ssh.Options) {
+			config.Args = append(config.Args, strconv.Itoa(testDroplet.ID))
+			config.Doit.Set(config.NS, doit.ArgIdentityFile, []string{"/keys/a", "/keys/b"})
+			config.Doit.Set(config.NS, doit.ArgKnownHostsFile, "/keys/known_hosts")
+
+			err := RunSSH(config)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"/keys/a", "/keys/b"}, opts.IdentityFiles)
+			assert.Equal(t, "/keys/known_hosts", opts.KnownHostsFile)
+		})
+	})
+}
+
 func TestSSH_InvalidID(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		err := RunSSH(config)
@@ -77,7 +293,7 @@ func TestSSH_UnknownDroplet(t *testing.T) {
 		config.Args = append(config.Args, "missing")
 
 		err := RunSSH(config)
-		assert.EqualError(t, err, "could not find droplet")
+		assert.EqualError(t, err, `unable to find droplet matching "missing"`)
 	})
 }
 
@@ -0,0 +1,259 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	dossh "github.com/bryanl/doit/pkg/ssh"
+	"github.com/digitalocean/godo"
+	"gopkg.in/yaml.v2"
+)
+
+// syncKey is a key the account should have.
+type syncKey struct {
+	name   string
+	key    dossh.AuthorizedKey
+	source string
+}
+
+// RunKeySync makes the account's ssh keys match an authorized_keys file, a
+// directory of .pub files or a YAML file with an ssh_keys list like a
+// manifest's. Keys are matched by fingerprint. Missing keys are uploaded,
+// keys with a different name are renamed and, with --prune, keys which
+// aren't in the source are deleted.
+func RunKeySync(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	source := c.Args[0]
+
+	prune, err := c.Doit.GetBool(c.NS, doit.ArgPrune)
+	if err != nil {
+		return err
+	}
+
+	keys, err := loadSyncKeys(source)
+	if err != nil {
+		return err
+	}
+
+	p, err := newKeySyncPlan(c, keys, prune)
+	if err != nil {
+		return err
+	}
+
+	state := &applyState{c: c, keys: map[string]int{}}
+	return runPlan(c, p, state, "sync", "ssh keys", "no changes, ssh keys match "+source)
+}
+
+// newKeySyncPlan compares the wanted keys with the account's.
+func newKeySyncPlan(c *CmdConfig, keys []syncKey, prune bool) (*infraPlan, error) {
+	live, err := c.Keys().List()
+	if err != nil {
+		return nil, err
+	}
+
+	byFingerprint := map[string]do.SSHKey{}
+	for _, k := range live {
+		byFingerprint[k.Fingerprint] = k
+	}
+
+	p := &infraPlan{}
+	wanted := map[string]bool{}
+	for _, k := range keys {
+		fp := k.key.Fingerprint()
+		wanted[fp] = true
+
+		l, ok := byFingerprint[fp]
+		if !ok {
+			name, publicKey := k.name, k.key.String()
+			p.add(opCreate, "ssh key "+name, fp, func(s *applyState) error {
+				_, err := s.c.Keys().Create(&godo.KeyCreateRequest{Name: name, PublicKey: publicKey})
+				return err
+			})
+			continue
+		}
+
+		if l.Name == k.name {
+			continue
+		}
+
+		id, name := l.ID, k.name
+		p.add(opUpdate, "ssh key "+l.Name, "rename to "+name, func(s *applyState) error {
+			_, err := s.c.Keys().Update(fmt.Sprint(id), &godo.KeyUpdateRequest{Name: name})
+			return err
+		})
+	}
+
+	if !prune {
+		return p, nil
+	}
+
+	for _, l := range live {
+		if wanted[l.Fingerprint] {
+			continue
+		}
+
+		id := l.ID
+		p.add(opDelete, "ssh key "+l.Name, l.Fingerprint, func(s *applyState) error {
+			return s.c.Keys().Delete(fmt.Sprint(id))
+		})
+	}
+
+	return p, nil
+}
+
+// loadSyncKeys reads the wanted keys from a directory of .pub files, a
+// YAML file or an authorized_keys file. Keys are named by their comment,
+// or for .pub files without one, the file name.
+func loadSyncKeys(path string) ([]syncKey, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []syncKey
+	switch ext := strings.ToLower(filepath.Ext(path)); {
+	case fi.IsDir():
+		keys, err = loadPubKeyDir(path)
+	case ext == ".yaml" || ext == ".yml":
+		keys, err = loadKeysYAML(path)
+	default:
+		keys, err = loadAuthorizedKeys(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	seen := map[string]string{}
+	for _, k := range keys {
+		if k.name == "" {
+			return nil, fmt.Errorf("%s: key %s has no comment to name it by", k.source, k.key.Fingerprint())
+		}
+
+		fp := k.key.Fingerprint()
+		if other, ok := seen[fp]; ok {
+			return nil, fmt.Errorf("%s and %s are the same key", other, k.source)
+		}
+		seen[fp] = k.source
+	}
+
+	return keys, nil
+}
+
+func loadAuthorizedKeys(path string) ([]syncKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := dossh.ParseAuthorizedKeys(b)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	var keys []syncKey
+	for _, k := range parsed {
+		keys = append(keys, syncKey{name: k.Comment, key: k, source: path})
+	}
+
+	return keys, nil
+}
+
+func loadPubKeyDir(dir string) ([]syncKey, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pub"))
+	if err != nil {
+		return nil, err
+	}
+
+	var keys []syncKey
+	for _, f := range files {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		k, err := parseOneKey(f, b)
+		if err != nil {
+			return nil, err
+		}
+
+		name := k.Comment
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(f), ".pub")
+		}
+
+		keys = append(keys, syncKey{name: name, key: k, source: f})
+	}
+
+	return keys, nil
+}
+
+func loadKeysYAML(path string) ([]syncKey, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m struct {
+		SSHKeys []manifestKey `yaml:"ssh_keys"`
+	}
+	if err := yaml.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %v", path, err)
+	}
+
+	var keys []syncKey
+	for i, mk := range m.SSHKeys {
+		if mk.Name == "" {
+			return nil, fmt.Errorf("%s: ssh key %d has no name", path, i+1)
+		}
+
+		publicKey, err := mk.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%s: ssh key %q: %v", path, mk.Name, err)
+		}
+
+		source := fmt.Sprintf("%s: ssh key %q", path, mk.Name)
+		k, err := parseOneKey(source, []byte(publicKey))
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, syncKey{name: mk.Name, key: k, source: source})
+	}
+
+	return keys, nil
+}
+
+// parseOneKey parses data which should hold a single public key.
+func parseOneKey(source string, b []byte) (dossh.AuthorizedKey, error) {
+	keys, err := dossh.ParseAuthorizedKeys(b)
+	if err != nil {
+		return dossh.AuthorizedKey{}, fmt.Errorf("%s: %v", source, err)
+	}
+
+	if len(keys) != 1 {
+		return dossh.AuthorizedKey{}, fmt.Errorf("%s: expected one public key, found %d", source, len(keys))
+	}
+
+	return keys[0], nil
+}
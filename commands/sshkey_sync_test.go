@@ -0,0 +1,162 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/bryanl/doit/do"
+	dossh "github.com/bryanl/doit/pkg/ssh"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncTestKey generates a public key with a comment and returns its
+// authorized_keys line and fingerprint.
+func syncTestKey(t *testing.T, keyType, comment string) (string, string) {
+	kp, err := dossh.GenerateKey(keyType, 0, comment)
+	assert.NoError(t, err)
+
+	return strings.TrimSpace(string(kp.PublicKey)), kp.Fingerprint
+}
+
+func TestKeySync_AuthorizedKeys(t *testing.T) {
+	alice, aliceFP := syncTestKey(t, dossh.KeyTypeECDSA, "alice@laptop")
+	bob, bobFP := syncTestKey(t, dossh.KeyTypeED25519, "bob@desktop")
+	carol, carolFP := syncTestKey(t, dossh.KeyTypeECDSA, "carol")
+	_, oldFP := syncTestKey(t, dossh.KeyTypeECDSA, "")
+
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "authorized_keys")
+		contents := "# team keys\n" + alice + "\n\n" +
+			`from="10.0.0.0/8",command="echo hi" ` + bob + "\n" + carol + "\n"
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			live := do.SSHKeys{
+				{Key: &godo.Key{ID: 1, Name: "alice", Fingerprint: aliceFP}},
+				{Key: &godo.Key{ID: 2, Name: "carol", Fingerprint: carolFP}},
+				{Key: &godo.Key{ID: 3, Name: "old", Fingerprint: oldFP}},
+			}
+			tm.keys.On("List").Return(live, nil)
+			tm.keys.On("Update", "1", &godo.KeyUpdateRequest{Name: "alice@laptop"}).Return(&live[0], nil)
+			tm.keys.On("Create", &godo.KeyCreateRequest{Name: "bob@desktop", PublicKey: bob}).Return(&testKey, nil)
+			tm.keys.On("Delete", "3").Return(nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, path)
+			config.Doit.Set(config.NS, doit.ArgPrune, true)
+			config.Doit.Set(config.NS, doit.ArgForce, true)
+
+			err := RunKeySync(config)
+			assert.NoError(t, err)
+
+			expected := "~ ssh key alice: rename to alice@laptop\n" +
+				"+ ssh key bob@desktop: " + bobFP + "\n" +
+				"- ssh key old: " + oldFP + "\n" +
+				"\n1 to create, 1 to update, 1 to delete\n" +
+				"applied 3 changes\n"
+			assert.Equal(t, expected, out.String())
+		})
+	})
+}
+
+func TestKeySync_PubDirectory(t *testing.T) {
+	laptop, laptopFP := syncTestKey(t, dossh.KeyTypeECDSA, "")
+	_, otherFP := syncTestKey(t, dossh.KeyTypeECDSA, "")
+
+	withTempDir(t, func(dir string) {
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "laptop.pub"), []byte(laptop+"\n"), 0644))
+		assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("not a key"), 0644))
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.keys.On("List").Return(do.SSHKeys{
+				{Key: &godo.Key{ID: 1, Name: "laptop", Fingerprint: laptopFP}},
+				{Key: &godo.Key{ID: 2, Name: "other", Fingerprint: otherFP}},
+			}, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, dir)
+
+			err := RunKeySync(config)
+			assert.NoError(t, err)
+			assert.Equal(t, "no changes, ssh keys match "+dir+"\n", out.String())
+		})
+	})
+}
+
+func TestKeySync_YAMLDryRun(t *testing.T) {
+	deploy, deployFP := syncTestKey(t, dossh.KeyTypeECDSA, "ci")
+
+	withTempDir(t, func(dir string) {
+		pubPath := filepath.Join(dir, "deploy.pub")
+		assert.NoError(t, ioutil.WriteFile(pubPath, []byte(deploy+"\n"), 0644))
+
+		path := filepath.Join(dir, "keys.yaml")
+		contents := "ssh_keys:\n" +
+			"  - name: deploy\n" +
+			"    public_key_file: " + pubPath + "\n"
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.keys.On("List").Return(do.SSHKeys{}, nil)
+
+			var out bytes.Buffer
+			config.Out = &out
+			config.Args = append(config.Args, path)
+			config.Doit.Set(config.NS, doit.ArgDryRun, true)
+
+			err := RunKeySync(config)
+			assert.NoError(t, err)
+
+			expected := "+ ssh key deploy: " + deployFP + "\n" +
+				"\n1 to create, 0 to update, 0 to delete\n" +
+				"would sync ssh keys:\n  1 to create, 0 to update, 0 to delete\n"
+			assert.Equal(t, expected, out.String())
+		})
+	})
+}
+
+func TestLoadSyncKeys_Invalid(t *testing.T) {
+	key, _ := syncTestKey(t, dossh.KeyTypeECDSA, "me")
+	uncommented, _ := syncTestKey(t, dossh.KeyTypeECDSA, "")
+
+	withTempDir(t, func(dir string) {
+		cases := []struct {
+			contents string
+			err      string
+		}{
+			{key + "\n" + key + "\n", "%[1]s and %[1]s are the same key"},
+			{uncommented + "\n", "%[1]s: key .* has no comment to name it by"},
+			{"ssh-rsa bm90IGEga2V5\n", "%[1]s: line 1: unable to parse public key"},
+		}
+
+		path := filepath.Join(dir, "authorized_keys")
+		for _, c := range cases {
+			assert.NoError(t, ioutil.WriteFile(path, []byte(c.contents), 0600))
+
+			_, err := loadSyncKeys(path)
+			if assert.Error(t, err) {
+				assert.Regexp(t, "^"+strings.Replace(c.err, "%[1]s", path, -1)+"$", err.Error())
+			}
+		}
+	})
+}
@@ -14,15 +14,25 @@ limitations under the License.
 package commands
 
 import (
+	"fmt"
 	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
+	dossh "github.com/bryanl/doit/pkg/ssh"
 	"github.com/digitalocean/godo"
 	"github.com/spf13/cobra"
 	"golang.org/x/crypto/ssh"
 )
 
+// keyFileRE matches characters which aren't used in generated key file
+// names.
+var keyFileRE = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
 // SSHKeys creates the ssh key commands heirarchy.
 func SSHKeys() *Command {
 	cmd := &Command{
@@ -39,7 +49,7 @@ func SSHKeys() *Command {
 	CmdBuilder(cmd, RunKeyList, "list", "list ssh keys", Writer,
 		aliasOpt("ls"), displayerType(&key{}), docCategories("sshkeys"))
 
-	CmdBuilder(cmd, RunKeyGet, "get <key-id|key-fingerprint>", "get ssh key", Writer,
+	CmdBuilder(cmd, RunKeyGet, "get <key-id|key-fingerprint|key-name>", "get ssh key", Writer,
 		aliasOpt("g"), displayerType(&key{}), docCategories("sshkeys"))
 
 	cmdSSHKeysCreate := CmdBuilder(cmd, RunKeyCreate, "create <key-name>", "create ssh key", Writer,
@@ -50,10 +60,22 @@ func SSHKeys() *Command {
 		aliasOpt("i"), displayerType(&key{}), docCategories("sshkeys"))
 	AddStringFlag(cmdSSHKeysImport, doit.ArgKeyPublicKeyFile, "", "Public key file", requiredOpt())
 
-	CmdBuilder(cmd, RunKeyDelete, "delete <key-id|key-fingerprint>", "delete ssh key", Writer,
-		aliasOpt("d"), docCategories("sshkeys"))
+	cmdSSHKeysGenerate := CmdBuilder(cmd, RunKeyGenerate, "generate <key-name>",
+		"generate a key pair under ~/.ssh and upload the public key", Writer,
+		aliasOpt("gen"), displayerType(&key{}), docCategories("sshkeys"))
+	AddStringFlag(cmdSSHKeysGenerate, doit.ArgKeyType, dossh.KeyTypeRSA, "key type: rsa, ecdsa or ed25519")
+	AddIntFlag(cmdSSHKeysGenerate, doit.ArgKeyBits, 0, "key size, defaults to 4096 for rsa and 256 for ecdsa")
+	AddStringFlag(cmdSSHKeysGenerate, doit.ArgOutFile, "", "private key file, defaults to ~/.ssh/<key-name>")
+
+	cmdSSHKeysSync := CmdBuilder(cmd, RunKeySync, "sync <authorized_keys | directory | keys.yaml>",
+		"make the account's ssh keys match an authorized_keys file, .pub files or a team keys file", Writer,
+		docCategories("sshkeys"), destructiveOpt())
+	AddBoolFlag(cmdSSHKeysSync, doit.ArgPrune, false, "Delete account keys which aren't in the source")
+
+	CmdBuilder(cmd, RunKeyDelete, "delete <key-id|key-fingerprint|key-name>", "delete ssh key", Writer,
+		aliasOpt("d"), destructiveOpt(), docCategories("sshkeys"))
 
-	cmdSSHKeysUpdate := CmdBuilder(cmd, RunKeyUpdate, "update <key-id|key-fingerprint>", "update ssh key", Writer,
+	cmdSSHKeysUpdate := CmdBuilder(cmd, RunKeyUpdate, "update <key-id|key-fingerprint|key-name>", "update ssh key", Writer,
 		aliasOpt("u"), displayerType(&key{}), docCategories("sshkeys"))
 	AddStringFlag(cmdSSHKeysUpdate, doit.ArgKeyName, "", "Key name", requiredOpt())
 
@@ -75,15 +97,11 @@ func RunKeyList(c *CmdConfig) error {
 
 // RunKeyGet retrieves a key.
 func RunKeyGet(c *CmdConfig) error {
-	ks := c.Keys()
-
 	if len(c.Args) != 1 {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	rawKey := c.Args[0]
-	k, err := ks.Get(rawKey)
-
+	k, err := c.Resolver().Key(c.Args[0])
 	if err != nil {
 		return err
 	}
@@ -164,6 +182,105 @@ func RunKeyImport(c *CmdConfig) error {
 	return c.Display(item)
 }
 
+// RunKeyGenerate creates a key pair, writes it under ~/.ssh and uploads
+// the public key. Existing key files are never overwritten.
+func RunKeyGenerate(c *CmdConfig) error {
+	if len(c.Args) != 1 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	name := c.Args[0]
+
+	keyType, err := c.Doit.GetString(c.NS, doit.ArgKeyType)
+	if err != nil {
+		return err
+	}
+
+	bits, err := c.Doit.GetInt(c.NS, doit.ArgKeyBits)
+	if err != nil {
+		return err
+	}
+
+	path, err := c.Doit.GetString(c.NS, doit.ArgOutFile)
+	if err != nil {
+		return err
+	}
+
+	if path == "" {
+		usr, err := user.Current()
+		if err != nil {
+			return err
+		}
+
+		path = filepath.Join(usr.HomeDir, ".ssh", keyFileRE.ReplaceAllString(name, "_"))
+	}
+
+	for _, f := range []string{path, path + ".pub"} {
+		if _, err := os.Stat(f); err == nil {
+			return fmt.Errorf("%s already exists", f)
+		}
+	}
+
+	kp, err := dossh.GenerateKey(keyType, bits, name)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	if err := writeNewFile(path, kp.PrivateKey, 0600); err != nil {
+		return err
+	}
+
+	if err := writeNewFile(path+".pub", kp.PublicKey, 0644); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	kcr := &godo.KeyCreateRequest{
+		Name:      name,
+		PublicKey: string(kp.PublicKey),
+	}
+
+	r, err := c.Keys().Create(kcr)
+	if err != nil {
+		// the files are removed so the command can simply be run again.
+		_ = os.Remove(path)
+		_ = os.Remove(path + ".pub")
+		return err
+	}
+
+	fmt.Fprintf(progressOut, "Your private key has been saved in %s\n", path)
+	fmt.Fprintf(progressOut, "Your public key has been saved in %s.pub\n", path)
+	fmt.Fprintf(progressOut, "The key fingerprint is %s\n", kp.Fingerprint)
+
+	item := &key{keys: do.SSHKeys{*r}}
+	return c.Display(item)
+}
+
+// writeNewFile writes a file which mustn't already exist.
+func writeNewFile(path string, b []byte, perm os.FileMode) error {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Write(b); err != nil {
+		_ = f.Close()
+		_ = os.Remove(path)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		_ = os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
 // RunKeyDelete deletes a key.
 func RunKeyDelete(c *CmdConfig) error {
 	ks := c.Keys()
@@ -172,7 +289,26 @@ func RunKeyDelete(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	rawKey := c.Args[0]
+	r := c.Resolver()
+	rawKey, err := r.KeyID(c.Args[0])
+	if err != nil {
+		return err
+	}
+
+	describe := func() ([]string, error) {
+		k, err := r.Key(rawKey)
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{fmt.Sprintf("%s (%d, %s)", k.Name, k.ID, k.Fingerprint)}, nil
+	}
+
+	ok, err := confirmDestructive(c, "delete", "ssh key", describe)
+	if err != nil || !ok {
+		return err
+	}
+
 	return ks.Delete(rawKey)
 }
 
@@ -184,7 +320,10 @@ func RunKeyUpdate(c *CmdConfig) error {
 		return doit.NewMissingArgsErr(c.NS)
 	}
 
-	rawKey := c.Args[0]
+	rawKey, err := c.Resolver().KeyID(c.Args[0])
+	if err != nil {
+		return err
+	}
 
 	name, err := c.Doit.GetString(c.NS, doit.ArgKeyName)
 	if err != nil {
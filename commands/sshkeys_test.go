@@ -14,26 +14,34 @@ limitations under the License.
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/bryanl/doit"
 	"github.com/bryanl/doit/do"
 	"github.com/digitalocean/godo"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"golang.org/x/crypto/ssh"
 )
 
 var (
 	testKey     = do.SSHKey{Key: &godo.Key{ID: 1, Fingerprint: "fingerprint"}}
 	testKeyList = do.SSHKeys{testKey}
+
+	// testMD5Key has a fingerprint in the form keys are looked up by.
+	testMD5Key = do.SSHKey{Key: &godo.Key{ID: 1, Fingerprint: "3b:16:bf:e4:8b:00:8b:b8:59:8c:a9:d3:f0:19:45:fa"}}
 )
 
 func TestSSHKeysCommand(t *testing.T) {
 	cmd := SSHKeys()
 	assert.NotNil(t, cmd)
-	assertCommandNames(t, cmd, "create", "delete", "get", "import", "list", "update")
+	assertCommandNames(t, cmd, "create", "delete", "generate", "get", "import", "list", "sync", "update")
 }
 
 func TestKeysList(t *testing.T) {
@@ -58,9 +66,21 @@ func TestKeysGetByID(t *testing.T) {
 
 func TestKeysGetByFingerprint(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
-		tm.keys.On("Get", testKey.Fingerprint).Return(&testKey, nil)
+		tm.keys.On("Get", testMD5Key.Fingerprint).Return(&testMD5Key, nil)
 
-		config.Args = append(config.Args, testKey.Fingerprint)
+		config.Args = append(config.Args, testMD5Key.Fingerprint)
+
+		err := RunKeyGet(config)
+		assert.NoError(t, err)
+	})
+}
+
+func TestKeysGetByName(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		named := do.SSHKey{Key: &godo.Key{ID: 2, Name: "laptop"}}
+		tm.keys.On("List").Return(do.SSHKeys{testKey, named}, nil)
+
+		config.Args = append(config.Args, "laptop")
 
 		err := RunKeyGet(config)
 		assert.NoError(t, err)
@@ -86,6 +106,7 @@ func TestKeysDeleteByID(t *testing.T) {
 		tm.keys.On("Delete", "1").Return(nil)
 
 		config.Args = append(config.Args, "1")
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunKeyDelete(config)
 		assert.NoError(t, err)
@@ -94,9 +115,10 @@ func TestKeysDeleteByID(t *testing.T) {
 
 func TestKeysDeleteByFingerprint(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
-		tm.keys.On("Delete", "fingerprint").Return(nil)
+		tm.keys.On("Delete", testMD5Key.Fingerprint).Return(nil)
 
-		config.Args = append(config.Args, "fingerprint")
+		config.Args = append(config.Args, testMD5Key.Fingerprint)
+		config.Doit.Set(config.NS, doit.ArgForce, true)
 
 		err := RunKeyDelete(config)
 		assert.NoError(t, err)
@@ -122,9 +144,9 @@ func TestKeysUpdateByID(t *testing.T) {
 func TestKeysUpdateByFingerprint(t *testing.T) {
 	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
 		kur := &godo.KeyUpdateRequest{Name: "the key"}
-		tm.keys.On("Update", "fingerprint", kur).Return(&testKey, nil)
+		tm.keys.On("Update", testMD5Key.Fingerprint, kur).Return(&testKey, nil)
 
-		config.Args = append(config.Args, "fingerprint")
+		config.Args = append(config.Args, testMD5Key.Fingerprint)
 
 		config.Doit.Set(config.NS, doit.ArgKeyName, "the key")
 
@@ -153,3 +175,90 @@ func TestSSHPublicKeyImportWithName(t *testing.T) {
 		assert.NoError(t, err)
 	})
 }
+
+func TestKeysGenerate(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		og := progressOut
+		defer func() { progressOut = og }()
+
+		var out bytes.Buffer
+		progressOut = &out
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			path := filepath.Join(dir, ".ssh", "laptop")
+
+			var uploaded string
+			tm.keys.On("Create", mock.MatchedBy(func(kcr *godo.KeyCreateRequest) bool {
+				uploaded = kcr.PublicKey
+				return kcr.Name == "laptop" && strings.HasPrefix(kcr.PublicKey, "ecdsa-sha2-nistp256 ")
+			})).Return(&testKey, nil)
+
+			config.Args = append(config.Args, "laptop")
+			config.Doit.Set(config.NS, doit.ArgKeyType, "ecdsa")
+			config.Doit.Set(config.NS, doit.ArgOutFile, path)
+
+			err := RunKeyGenerate(config)
+			assert.NoError(t, err)
+
+			b, err := ioutil.ReadFile(path)
+			assert.NoError(t, err)
+			signer, err := ssh.ParsePrivateKey(b)
+			assert.NoError(t, err)
+
+			pub, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(uploaded))
+			assert.NoError(t, err)
+			assert.Equal(t, "laptop", comment)
+			assert.Equal(t, signer.PublicKey().Marshal(), pub.Marshal())
+
+			for f, mode := range map[string]os.FileMode{path: 0600, path + ".pub": 0644, filepath.Dir(path): 0700} {
+				fi, err := os.Stat(f)
+				if assert.NoError(t, err) {
+					assert.Equal(t, mode, fi.Mode().Perm(), f)
+				}
+			}
+
+			assert.Contains(t, out.String(), "The key fingerprint is ")
+		})
+	})
+}
+
+func TestKeysGenerate_Exists(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "laptop")
+		assert.NoError(t, ioutil.WriteFile(path+".pub", []byte("existing"), 0644))
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			config.Args = append(config.Args, "laptop")
+			config.Doit.Set(config.NS, doit.ArgKeyType, "ed25519")
+			config.Doit.Set(config.NS, doit.ArgOutFile, path)
+
+			err := RunKeyGenerate(config)
+			assert.EqualError(t, err, path+".pub already exists")
+
+			_, err = os.Stat(path)
+			assert.True(t, os.IsNotExist(err))
+		})
+	})
+}
+
+func TestKeysGenerate_UploadFails(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "laptop")
+
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			tm.keys.On("Create", mock.Anything).Return(nil, errors.New("unauthorized"))
+
+			config.Args = append(config.Args, "laptop")
+			config.Doit.Set(config.NS, doit.ArgKeyType, "ed25519")
+			config.Doit.Set(config.NS, doit.ArgOutFile, path)
+
+			err := RunKeyGenerate(config)
+			assert.EqualError(t, err, "unauthorized")
+
+			for _, f := range []string{path, path + ".pub"} {
+				_, err = os.Stat(f)
+				assert.True(t, os.IsNotExist(err), f)
+			}
+		})
+	})
+}
@@ -0,0 +1,316 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/bryanl/doit"
+	"gopkg.in/yaml.v2"
+)
+
+// maxUserDataSize is the largest user data a droplet accepts.
+const maxUserDataSize = 64 * 1024
+
+// userDataTypes maps the first line of a user data part to the content
+// type cloud-init expects for it in a multipart document.
+var userDataTypes = []struct {
+	prefix      string
+	contentType string
+}{
+	{"#cloud-config", "text/cloud-config"},
+	{"#!", "text/x-shellscript"},
+	{"#include", "text/x-include-url"},
+	{"#cloud-boothook", "text/cloud-boothook"},
+	{"#upstart-job", "text/upstart-job"},
+	{"#part-handler", "text/part-handler"},
+}
+
+// userDataPart is one piece of user data and where it came from.
+type userDataPart struct {
+	name    string
+	content string
+}
+
+func (p userDataPart) contentType() string {
+	for _, t := range userDataTypes {
+		if strings.HasPrefix(p.content, t.prefix) {
+			return t.contentType
+		}
+	}
+
+	return "text/plain"
+}
+
+// userDataOpt adds the flags used by userDataFromFlags to a command.
+func userDataOpt() cmdOption {
+	return func(c *Command) {
+		AddStringFlag(c, doit.ArgUserData, "", "User data, can't be used with --user-data-file")
+		AddStringSliceFlag(c, doit.ArgUserDataFile, []string{},
+			"User data file, repeat to build a multipart document")
+		AddStringFlag(c, doit.ArgUserDataTemplate, "",
+			"User data Go template, rendered with --var, --var-file and environment values")
+		AddStringSliceFlag(c, doit.ArgVar, []string{}, "Template variable as key=value, repeat for more")
+		AddStringFlag(c, doit.ArgVarFile, "", "YAML file of template variables")
+	}
+}
+
+// userDataFromFlags collects the user data parts given by --user-data,
+// --user-data-file and --user-data-template. --user-data used to win over
+// --user-data-file, so giving both is an error rather than quietly
+// combining them.
+func userDataFromFlags(c *CmdConfig) ([]userDataPart, error) {
+	var parts []userDataPart
+
+	userData, err := c.Doit.GetString(c.NS, doit.ArgUserData)
+	if err != nil {
+		return nil, err
+	}
+
+	if userData != "" {
+		parts = append(parts, userDataPart{name: "user-data", content: userData})
+	}
+
+	files, err := c.Doit.GetStringSlice(c.NS, doit.ArgUserDataFile)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range files {
+		if f == "" {
+			continue
+		}
+
+		if userData != "" {
+			return nil, fmt.Errorf("--%s and --%s can't be used together, put the user data in a file to combine them",
+				doit.ArgUserData, doit.ArgUserDataFile)
+		}
+
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return nil, err
+		}
+
+		parts = append(parts, userDataPart{name: filepath.Base(f), content: string(b)})
+	}
+
+	tmpl, err := c.Doit.GetString(c.NS, doit.ArgUserDataTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	if tmpl == "" {
+		return parts, nil
+	}
+
+	pairs, err := c.Doit.GetStringSlice(c.NS, doit.ArgVar)
+	if err != nil {
+		return nil, err
+	}
+
+	varFile, err := c.Doit.GetString(c.NS, doit.ArgVarFile)
+	if err != nil {
+		return nil, err
+	}
+
+	vars, err := templateVars(pairs, varFile, os.Environ())
+	if err != nil {
+		return nil, err
+	}
+
+	rendered, err := renderUserDataTemplate(tmpl, vars)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(parts, userDataPart{name: filepath.Base(tmpl), content: rendered}), nil
+}
+
+// templateVars builds the values a user data template is rendered with.
+// Environment values are overridden by the var file, which is overridden
+// by key=value pairs.
+func templateVars(pairs []string, varFile string, environ []string) (map[string]interface{}, error) {
+	vars := map[string]interface{}{}
+
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	if varFile != "" {
+		b, err := ioutil.ReadFile(varFile)
+		if err != nil {
+			return nil, err
+		}
+
+		var fileVars map[string]interface{}
+		if err := yaml.Unmarshal(b, &fileVars); err != nil {
+			return nil, fmt.Errorf("unable to parse %s: %v", varFile, err)
+		}
+
+		for k, v := range fileVars {
+			vars[k] = v
+		}
+	}
+
+	for _, kv := range pairs {
+		i := strings.Index(kv, "=")
+		if i < 1 {
+			return nil, fmt.Errorf("invalid --%s %q, expected key=value", doit.ArgVar, kv)
+		}
+
+		vars[kv[:i]] = kv[i+1:]
+	}
+
+	return vars, nil
+}
+
+// renderUserDataTemplate renders a template file. Referring to a variable
+// which hasn't been set is an error.
+func renderUserDataTemplate(path string, vars map[string]interface{}) (string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	t, err := template.New(filepath.Base(path)).Option("missingkey=error").Parse(string(b))
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, vars); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// assembleUserData joins user data parts. A single part is used as is,
+// several are combined into a MIME multipart document for cloud-init.
+func assembleUserData(parts []userDataPart) (string, error) {
+	switch len(parts) {
+	case 0:
+		return "", nil
+	case 1:
+		return parts[0].content, nil
+	}
+
+	// the boundary is derived from the content so the same parts always
+	// give the same document.
+	h := sha1.New()
+	for _, p := range parts {
+		fmt.Fprintf(h, "%s\x00%s\x00", p.name, p.content)
+	}
+	boundary := fmt.Sprintf("==doctl-%x==", h.Sum(nil))
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "Content-Type: multipart/mixed; boundary=\"%s\"\r\nMIME-Version: 1.0\r\n\r\n", boundary)
+
+	w := multipart.NewWriter(&buf)
+	if err := w.SetBoundary(boundary); err != nil {
+		return "", err
+	}
+
+	for _, p := range parts {
+		header := textproto.MIMEHeader{}
+		header.Set("Content-Type", fmt.Sprintf("%s; charset=\"utf-8\"", p.contentType()))
+		header.Set("MIME-Version", "1.0")
+		header.Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%s\"", p.name))
+
+		pw, err := w.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+
+		if _, err := pw.Write([]byte(p.content)); err != nil {
+			return "", err
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// validateUserData checks the syntax of cloud-config parts and the size of
+// the assembled user data.
+func validateUserData(parts []userDataPart) (string, error) {
+	for _, p := range parts {
+		switch p.contentType() {
+		case "text/cloud-config":
+			var config map[string]interface{}
+			if err := yaml.Unmarshal([]byte(p.content), &config); err != nil {
+				return "", fmt.Errorf("%s: invalid cloud-config: %v", p.name, err)
+			}
+		case "text/plain":
+			line := strings.SplitN(p.content, "\n", 2)[0]
+			return "", fmt.Errorf("%s: cloud-init doesn't understand user data starting with %q", p.name, line)
+		}
+	}
+
+	userData, err := assembleUserData(parts)
+	if err != nil {
+		return "", err
+	}
+
+	if len(userData) > maxUserDataSize {
+		return "", fmt.Errorf("user data is %d bytes, the limit is %d", len(userData), maxUserDataSize)
+	}
+
+	return userData, nil
+}
+
+// RunDropletValidateUserData checks user data without creating a droplet.
+// Files given as arguments are added to the ones given with
+// --user-data-file.
+func RunDropletValidateUserData(c *CmdConfig) error {
+	parts, err := userDataFromFlags(c)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range c.Args {
+		b, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+
+		parts = append(parts, userDataPart{name: filepath.Base(f), content: string(b)})
+	}
+
+	if len(parts) == 0 {
+		return doit.NewMissingArgsErr(c.NS)
+	}
+
+	userData, err := validateUserData(parts)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(c.Out, "user data is valid: %d part(s), %d bytes\n", len(parts), len(userData))
+	return nil
+}
@@ -0,0 +1,179 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package commands
+
+import (
+	"bytes"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bryanl/doit"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+func withUserDataFiles(t *testing.T, files map[string]string, fn func(dir string)) {
+	withTempDir(t, func(dir string) {
+		for name, content := range files {
+			assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+		}
+
+		fn(dir)
+	})
+}
+
+func TestDropletValidateUserData(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		var out bytes.Buffer
+		config.Out = &out
+		config.Args = append(config.Args, "../testdata/cloud-config.yml")
+
+		err := RunDropletValidateUserData(config)
+		assert.NoError(t, err)
+		assert.Equal(t, "user data is valid: 1 part(s), 797 bytes\n", out.String())
+	})
+}
+
+func TestDropletValidateUserData_Invalid(t *testing.T) {
+	files := map[string]string{
+		"broken.yml": "#cloud-config\npackages: [git\n",
+		"plain.txt":  "hello\n",
+		"large.sh":   "#!/bin/sh\n" + strings.Repeat("#", maxUserDataSize),
+	}
+
+	withUserDataFiles(t, files, func(dir string) {
+		for name, expected := range map[string]string{
+			"broken.yml": "broken.yml: invalid cloud-config",
+			"plain.txt":  `plain.txt: cloud-init doesn't understand user data starting with "hello"`,
+			"large.sh":   "user data is 65546 bytes, the limit is 65536",
+		} {
+			withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+				config.Args = append(config.Args, filepath.Join(dir, name))
+
+				err := RunDropletValidateUserData(config)
+				if assert.Error(t, err) {
+					assert.Contains(t, err.Error(), expected)
+				}
+			})
+		}
+	})
+}
+
+func TestTemplateVars(t *testing.T) {
+	withUserDataFiles(t, map[string]string{"vars.yml": "region: nyc3\nsize: 1gb\n"}, func(dir string) {
+		vars, err := templateVars([]string{"size=2gb", "empty="}, filepath.Join(dir, "vars.yml"),
+			[]string{"HOME=/root", "region=env"})
+		assert.NoError(t, err)
+		assert.Equal(t, map[string]interface{}{
+			"HOME":   "/root",
+			"region": "nyc3",
+			"size":   "2gb",
+			"empty":  "",
+		}, vars)
+
+		_, err = templateVars([]string{"novalue"}, "", nil)
+		assert.Error(t, err)
+	})
+}
+
+func TestDropletCreateUserDataTemplate(t *testing.T) {
+	files := map[string]string{
+		"cloud-config.tmpl": "#cloud-config\nhostname: {{.host}}\n",
+		"setup.sh":          "#!/bin/sh\necho hi\n",
+	}
+
+	withUserDataFiles(t, files, func(dir string) {
+		withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+			parts := []userDataPart{
+				{name: "setup.sh", content: files["setup.sh"]},
+				{name: "cloud-config.tmpl", content: "#cloud-config\nhostname: web-1\n"},
+			}
+			userData, err := assembleUserData(parts)
+			assert.NoError(t, err)
+
+			dcr := &godo.DropletCreateRequest{Name: "droplet", Region: "dev0", Size: "1gb", Image: godo.DropletCreateImage{Slug: "image"}, SSHKeys: []godo.DropletCreateSSHKey{}, UserData: userData}
+			tm.droplets.On("Create", dcr, false).Return(&testDroplet, nil)
+
+			config.Args = append(config.Args, "droplet")
+			config.Doit.Set(config.NS, doit.ArgRegionSlug, "dev0")
+			config.Doit.Set(config.NS, doit.ArgSizeSlug, "1gb")
+			config.Doit.Set(config.NS, doit.ArgImage, "image")
+			config.Doit.Set(config.NS, doit.ArgUserDataFile, []string{filepath.Join(dir, "setup.sh")})
+			config.Doit.Set(config.NS, doit.ArgUserDataTemplate, filepath.Join(dir, "cloud-config.tmpl"))
+			config.Doit.Set(config.NS, doit.ArgVar, []string{"host=web-1"})
+
+			err = RunDropletCreate(config)
+			assert.NoError(t, err)
+		})
+	})
+}
+
+func TestDropletCreateUserDataAndFile(t *testing.T) {
+	withTestClient(t, func(config *CmdConfig, tm *tcMocks) {
+		config.Args = append(config.Args, "droplet")
+		config.Doit.Set(config.NS, doit.ArgUserData, "#cloud-config")
+		config.Doit.Set(config.NS, doit.ArgUserDataFile, []string{"../testdata/cloud-config.yml"})
+
+		err := RunDropletCreate(config)
+		assert.EqualError(t, err, "--user-data and --user-data-file can't be used together, put the user data in a file to combine them")
+	})
+}
+
+func TestRenderUserDataTemplate_MissingVar(t *testing.T) {
+	withUserDataFiles(t, map[string]string{"t.tmpl": "hostname: {{.host}}\n"}, func(dir string) {
+		_, err := renderUserDataTemplate(filepath.Join(dir, "t.tmpl"), map[string]interface{}{})
+		assert.Error(t, err)
+	})
+}
+
+func TestAssembleUserData_Multipart(t *testing.T) {
+	parts := []userDataPart{
+		{name: "config.yml", content: "#cloud-config\npackages: [git]\n"},
+		{name: "setup.sh", content: "#!/bin/sh\necho hi\n"},
+	}
+
+	userData, err := assembleUserData(parts)
+	assert.NoError(t, err)
+
+	again, err := assembleUserData(parts)
+	assert.NoError(t, err)
+	assert.Equal(t, userData, again)
+
+	msg, err := mail.ReadMessage(strings.NewReader(userData))
+	assert.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	assert.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	r := multipart.NewReader(msg.Body, params["boundary"])
+	for _, expected := range []struct{ contentType, filename, content string }{
+		{`text/cloud-config; charset="utf-8"`, "config.yml", parts[0].content},
+		{`text/x-shellscript; charset="utf-8"`, "setup.sh", parts[1].content},
+	} {
+		p, err := r.NextPart()
+		assert.NoError(t, err)
+		assert.Equal(t, expected.contentType, p.Header.Get("Content-Type"))
+		assert.Equal(t, expected.filename, p.FileName())
+
+		b, err := ioutil.ReadAll(p)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.content, string(b))
+	}
+}
@@ -30,6 +30,8 @@ const (
 	InterfacePublic InterfaceType = "public"
 	// InterfacePrivate is a private interface.
 	InterfacePrivate InterfaceType = "private"
+	// InterfacePublicIPv6 is a public IPv6 interface.
+	InterfacePublicIPv6 InterfaceType = "public_ipv6"
 )
 
 // Droplet is a wrapper for godo.Droplet
@@ -40,6 +42,24 @@ type Droplet struct {
 // Droplets is a slice of Droplet.
 type Droplets []Droplet
 
+// IPs returns the droplet's addresses by interface. Interfaces without an
+// address are left out.
+func (d *Droplet) IPs() DropletIPTable {
+	t := DropletIPTable{}
+
+	if ip, _ := d.PublicIPv4(); ip != "" {
+		t[InterfacePublic] = ip
+	}
+	if ip, _ := d.PrivateIPv4(); ip != "" {
+		t[InterfacePrivate] = ip
+	}
+	if ip, _ := d.PublicIPv6(); ip != "" {
+		t[InterfacePublicIPv6] = ip
+	}
+
+	return t
+}
+
 // Kernel is a wrapper for godo.Kernel
 type Kernel struct {
 	*godo.Kernel
@@ -51,6 +71,7 @@ type Kernels []Kernel
 // DropletsService is an interface for interacting with DigitalOcean's droplet api.
 type DropletsService interface {
 	List() (Droplets, error)
+	ListByTag(string) (Droplets, error)
 	Get(int) (*Droplet, error)
 	Create(*godo.DropletCreateRequest, bool) (*Droplet, error)
 	CreateMultiple(*godo.DropletMultiCreateRequest) (Droplets, error)
@@ -104,6 +125,35 @@ func (ds *dropletsService) List() (Droplets, error) {
 	return list, nil
 }
 
+func (ds *dropletsService) ListByTag(tag string) (Droplets, error) {
+	f := func(opt *godo.ListOptions) ([]interface{}, *godo.Response, error) {
+		list, resp, err := ds.client.Droplets.ListByTag(tag, opt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		si := make([]interface{}, len(list))
+		for i := range list {
+			si[i] = list[i]
+		}
+
+		return si, resp, err
+	}
+
+	si, err := PaginateResp(f)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make(Droplets, len(si))
+	for i := range si {
+		a := si[i].(godo.Droplet)
+		list[i] = Droplet{Droplet: &a}
+	}
+
+	return list, nil
+}
+
 func (ds *dropletsService) Get(id int) (*Droplet, error) {
 	d, _, err := ds.client.Droplets.Get(id)
 	if err != nil {
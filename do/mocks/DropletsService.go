@@ -44,6 +44,27 @@ func (_m *DropletsService) List() (do.Droplets, error) {
 	return r0, r1
 }
 
+// ListByTag provides a mock function with given fields: _a0
+func (_m *DropletsService) ListByTag(_a0 string) (do.Droplets, error) {
+	ret := _m.Called(_a0)
+
+	var r0 do.Droplets
+	if rf, ok := ret.Get(0).(func(string) do.Droplets); ok {
+		r0 = rf(_a0)
+	} else {
+		r0 = ret.Get(0).(do.Droplets)
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(_a0)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Get provides a mock function with given fields: _a0
 func (_m *DropletsService) Get(_a0 int) (*do.Droplet, error) {
 	ret := _m.Called(_a0)
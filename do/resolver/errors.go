@@ -0,0 +1,55 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NotFoundError is returned when a reference matches no resources.
+type NotFoundError struct {
+	Kind string
+	Ref  string
+}
+
+var _ error = &NotFoundError{}
+
+func (e *NotFoundError) Error() string {
+	return fmt.Sprintf("unable to find %s matching %q", e.Kind, e.Ref)
+}
+
+// AmbiguousError is returned when a reference which has to select a single
+// resource matches more than one.
+type AmbiguousError struct {
+	Kind    string
+	Ref     string
+	Matches []string
+}
+
+var _ error = &AmbiguousError{}
+
+func (e *AmbiguousError) Error() string {
+	return fmt.Sprintf("%q matches %d %ss, use an id to pick one: %s",
+		e.Ref, len(e.Matches), e.Kind, strings.Join(e.Matches, ", "))
+}
+
+func newAmbiguousError(kind, ref string, cs []candidate) error {
+	var matches []string
+	for _, c := range cs {
+		matches = append(matches, c.label)
+	}
+
+	return &AmbiguousError{Kind: kind, Ref: ref, Matches: matches}
+}
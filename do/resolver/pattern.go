@@ -0,0 +1,130 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gobwas/glob"
+)
+
+const (
+	// RegexPrefix marks a reference as a regular expression.
+	RegexPrefix = "re:"
+
+	// globChars are the characters which make a reference a glob.
+	globChars = "*?[{"
+)
+
+// Pattern is a parsed reference to one or more resources. A reference is
+// either exact (an id, name, slug, fingerprint or IP), a glob, or a regular
+// expression prefixed with "re:".
+type Pattern struct {
+	ref   string
+	exact bool
+	match func(string) bool
+}
+
+// Parse parses a reference.
+func Parse(ref string) (*Pattern, error) {
+	p := &Pattern{ref: ref}
+
+	switch {
+	case ref == "":
+		return nil, fmt.Errorf("reference can't be blank")
+	case strings.HasPrefix(ref, RegexPrefix):
+		re, err := regexp.Compile(strings.TrimPrefix(ref, RegexPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %q: %v", ref, err)
+		}
+		p.match = re.MatchString
+	case strings.ContainsAny(ref, globChars):
+		g, err := glob.Compile(ref)
+		if err != nil {
+			return nil, fmt.Errorf("unknown glob %q", ref)
+		}
+		p.match = g.Match
+	default:
+		p.exact = true
+		p.match = func(s string) bool {
+			return s == ref
+		}
+	}
+
+	return p, nil
+}
+
+// String returns the reference the pattern was parsed from.
+func (p *Pattern) String() string {
+	return p.ref
+}
+
+// Exact returns true if the pattern is not a glob or regular expression.
+func (p *Pattern) Exact() bool {
+	return p.exact
+}
+
+// Match returns true if any of the values match the pattern.
+func (p *Pattern) Match(values ...string) bool {
+	for _, v := range values {
+		if v != "" && p.match(v) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// candidate is a resource which a pattern can select. ids are only
+// matched exactly, names are also matched by globs and regular expressions.
+type candidate struct {
+	ids   []string
+	names []string
+	label string
+	value interface{}
+}
+
+// matches returns true if the pattern selects the candidate.
+func (p *Pattern) matches(c candidate) bool {
+	return (p.exact && p.Match(c.ids...)) || p.Match(c.names...)
+}
+
+// filter returns the candidates selected by the pattern. Exact matches on
+// ids take precedence over matches on names, so a droplet named "3" can't
+// hide the droplet with id 3.
+func (p *Pattern) filter(cs []candidate) []candidate {
+	var matched []candidate
+
+	if p.exact {
+		for _, c := range cs {
+			if p.Match(c.ids...) {
+				matched = append(matched, c)
+			}
+		}
+
+		if len(matched) > 0 {
+			return matched
+		}
+	}
+
+	for _, c := range cs {
+		if p.Match(c.names...) {
+			matched = append(matched, c)
+		}
+	}
+
+	return matched
+}
@@ -0,0 +1,608 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+/*
+Package resolver finds resources from the references users type on the
+command line. Droplets, images, snapshots, ssh keys, floating IPs and
+domain records can be referred to by id, or by name, slug, fingerprint or
+IP as appropriate. Domain records can also be selected by name and type,
+like www:A, or for MX and SRV records name, type and priority, like @:MX:10.
+Any reference can also be a glob, or a regular expression prefixed with
+"re:".
+*/
+package resolver
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bryanl/doit/do"
+	"github.com/digitalocean/godo"
+)
+
+const (
+	kindDroplet    = "droplet"
+	kindImage      = "image"
+	kindSnapshot   = "snapshot"
+	kindKey        = "ssh key"
+	kindFloatingIP = "floating IP"
+	kindRecord     = "domain record"
+)
+
+// Resolver resolves references to resources. Lists are only fetched when a
+// reference can't be looked up directly, and are fetched at most once.
+type Resolver struct {
+	droplets    do.DropletsService
+	images      do.ImagesService
+	keys        do.KeysService
+	floatingIPs do.FloatingIPsService
+	domains     do.DomainsService
+
+	lists map[string][]candidate
+}
+
+// New builds an instance of Resolver.
+func New(ds do.DropletsService, is do.ImagesService, ks do.KeysService,
+	fis do.FloatingIPsService, dos do.DomainsService) *Resolver {
+	return &Resolver{
+		droplets:    ds,
+		images:      is,
+		keys:        ks,
+		floatingIPs: fis,
+		domains:     dos,
+		lists:       map[string][]candidate{},
+	}
+}
+
+// slugRE matches references which could be image slugs.
+var slugRE = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]*$`)
+
+type listFn func() ([]candidate, error)
+
+// Droplet returns the droplet ref refers to.
+func (r *Resolver) Droplet(ref string) (*do.Droplet, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return r.droplets.Get(id)
+	}
+
+	c, err := r.one(kindDroplet, kindDroplet, ref, r.listDroplets)
+	if err != nil {
+		return nil, err
+	}
+
+	d := c.value.(do.Droplet)
+	return &d, nil
+}
+
+// DropletID returns the id of the droplet ref refers to.
+func (r *Resolver) DropletID(ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+
+	d, err := r.Droplet(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return d.ID, nil
+}
+
+// Droplets returns the droplets any of refs refer to, in order and without
+// duplicates. Droplets referred to by id are not looked up, so only their
+// ID is set until they are loaded with LoadDroplets.
+func (r *Resolver) Droplets(refs ...string) (do.Droplets, error) {
+	cs, err := r.all(kindDroplet, kindDroplet, refs, func(ref string) (candidate, bool) {
+		id, err := strconv.Atoi(ref)
+		if err != nil {
+			return candidate{}, false
+		}
+
+		return dropletCandidate(do.Droplet{Droplet: &godo.Droplet{ID: id}}), true
+	}, r.listDroplets)
+	if err != nil {
+		return nil, err
+	}
+
+	var out do.Droplets
+	for _, c := range cs {
+		out = append(out, c.value.(do.Droplet))
+	}
+
+	return out, nil
+}
+
+// LoadDroplets fetches the droplets Droplets only knows the id of, so all
+// of the droplets returned are complete.
+func (r *Resolver) LoadDroplets(droplets do.Droplets) (do.Droplets, error) {
+	out := make(do.Droplets, len(droplets))
+	for i, d := range droplets {
+		if d.Name == "" {
+			fetched, err := r.droplets.Get(d.ID)
+			if err != nil {
+				return nil, err
+			}
+			d = *fetched
+		}
+
+		out[i] = d
+	}
+
+	return out, nil
+}
+
+// MatchDroplet returns true if p selects the droplet.
+func MatchDroplet(p *Pattern, d do.Droplet) bool {
+	return p.matches(dropletCandidate(d))
+}
+
+func (r *Resolver) listDroplets() ([]candidate, error) {
+	list, err := r.droplets.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list of droplets: %v", err)
+	}
+
+	var cs []candidate
+	for _, d := range list {
+		cs = append(cs, dropletCandidate(d))
+	}
+
+	return cs, nil
+}
+
+func dropletCandidate(d do.Droplet) candidate {
+	public, _ := d.PublicIPv4()
+	private, _ := d.PrivateIPv4()
+	v6, _ := d.PublicIPv6()
+
+	return candidate{
+		ids:   []string{strconv.Itoa(d.ID), public, private, v6},
+		names: []string{d.Name},
+		label: fmt.Sprintf("%s (%d)", d.Name, d.ID),
+		value: d,
+	}
+}
+
+// Image returns the image ref refers to. Images can be referred to by
+// id, slug or name. References which look like slugs are looked up
+// directly before falling back to searching the image list.
+func (r *Resolver) Image(ref string) (*do.Image, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return r.images.GetByID(id)
+	}
+
+	if slugRE.MatchString(ref) {
+		if i, err := r.images.GetBySlug(ref); err == nil {
+			return i, nil
+		}
+	}
+
+	c, err := r.one(kindImage, kindImage, ref, r.listImages)
+	if err != nil {
+		return nil, err
+	}
+
+	i := c.value.(do.Image)
+	return &i, nil
+}
+
+// ImageID returns the id of the image ref refers to.
+func (r *Resolver) ImageID(ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+
+	i, err := r.Image(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return i.ID, nil
+}
+
+// Snapshot returns the snapshot or backup ref refers to. Only the user's
+// snapshots and backups are searched by name.
+func (r *Resolver) Snapshot(ref string) (*do.Image, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return r.images.GetByID(id)
+	}
+
+	c, err := r.one(kindSnapshot, kindSnapshot, ref, r.listSnapshots)
+	if err != nil {
+		return nil, err
+	}
+
+	i := c.value.(do.Image)
+	return &i, nil
+}
+
+// SnapshotID returns the id of the snapshot or backup ref refers to.
+func (r *Resolver) SnapshotID(ref string) (int, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return id, nil
+	}
+
+	i, err := r.Snapshot(ref)
+	if err != nil {
+		return 0, err
+	}
+
+	return i.ID, nil
+}
+
+func (r *Resolver) listImages() ([]candidate, error) {
+	list, err := r.images.List(false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list of images: %v", err)
+	}
+
+	var cs []candidate
+	for _, i := range list {
+		cs = append(cs, imageCandidate(i))
+	}
+
+	return cs, nil
+}
+
+func (r *Resolver) listSnapshots() ([]candidate, error) {
+	list, err := r.images.ListUser(false)
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list of snapshots: %v", err)
+	}
+
+	var cs []candidate
+	for _, i := range list {
+		if i.Type == "snapshot" || i.Type == "backup" {
+			cs = append(cs, imageCandidate(i))
+		}
+	}
+
+	return cs, nil
+}
+
+func imageCandidate(i do.Image) candidate {
+	return candidate{
+		ids:   []string{strconv.Itoa(i.ID), i.Slug},
+		names: []string{i.Name, i.Slug},
+		label: fmt.Sprintf("%s (%d)", i.Name, i.ID),
+		value: i,
+	}
+}
+
+// Key returns the ssh key ref refers to. Keys can be referred to by id,
+// fingerprint or name.
+func (r *Resolver) Key(ref string) (*do.SSHKey, error) {
+	if isKeyID(ref) {
+		return r.keys.Get(ref)
+	}
+
+	c, err := r.one(kindKey, kindKey, ref, r.listKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	k := c.value.(do.SSHKey)
+	return &k, nil
+}
+
+// KeyID returns the id or fingerprint of the ssh key ref refers to, in the
+// form accepted by KeysService.
+func (r *Resolver) KeyID(ref string) (string, error) {
+	if isKeyID(ref) {
+		return ref, nil
+	}
+
+	k, err := r.Key(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return strconv.Itoa(k.ID), nil
+}
+
+// isKeyID returns true if ref is a key id or fingerprint.
+func isKeyID(ref string) bool {
+	if _, err := strconv.Atoi(ref); err == nil {
+		return true
+	}
+
+	return strings.Count(ref, ":") == 15
+}
+
+func (r *Resolver) listKeys() ([]candidate, error) {
+	list, err := r.keys.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list of ssh keys: %v", err)
+	}
+
+	var cs []candidate
+	for _, k := range list {
+		cs = append(cs, candidate{
+			ids:   []string{strconv.Itoa(k.ID), k.Fingerprint},
+			names: []string{k.Name},
+			label: fmt.Sprintf("%s (%d)", k.Name, k.ID),
+			value: k,
+		})
+	}
+
+	return cs, nil
+}
+
+// FloatingIP returns the floating IP ref refers to. Floating IPs can be
+// referred to by address, or by the name of the droplet they are assigned
+// to.
+func (r *Resolver) FloatingIP(ref string) (*do.FloatingIP, error) {
+	if net.ParseIP(ref) != nil {
+		return r.floatingIPs.Get(ref)
+	}
+
+	c, err := r.one(kindFloatingIP, kindFloatingIP, ref, r.listFloatingIPs)
+	if err != nil {
+		return nil, err
+	}
+
+	fip := c.value.(do.FloatingIP)
+	return &fip, nil
+}
+
+// FloatingIPAddress returns the address of the floating IP ref refers to.
+func (r *Resolver) FloatingIPAddress(ref string) (string, error) {
+	if net.ParseIP(ref) != nil {
+		return ref, nil
+	}
+
+	fip, err := r.FloatingIP(ref)
+	if err != nil {
+		return "", err
+	}
+
+	return fip.IP, nil
+}
+
+func (r *Resolver) listFloatingIPs() ([]candidate, error) {
+	list, err := r.floatingIPs.List()
+	if err != nil {
+		return nil, fmt.Errorf("unable to build list of floating IPs: %v", err)
+	}
+
+	var cs []candidate
+	for _, fip := range list {
+		c := candidate{
+			ids:   []string{fip.IP},
+			label: fip.IP,
+			value: fip,
+		}
+
+		if fip.Droplet != nil {
+			c.names = []string{fip.Droplet.Name}
+			c.label = fmt.Sprintf("%s (%s)", fip.IP, fip.Droplet.Name)
+		}
+
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+// Record returns the record in domain that ref refers to. Records can be
+// referred to by id, name, data or a selector like www:A or @:MX:10.
+func (r *Resolver) Record(domain, ref string) (*do.DomainRecord, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		return r.domains.Record(domain, id)
+	}
+
+	ref = recordSelector(ref)
+
+	c, err := r.one(kindRecord, kindRecord+":"+domain, ref, r.listRecords(domain))
+	if err != nil {
+		return nil, err
+	}
+
+	rec := c.value.(do.DomainRecord)
+	return &rec, nil
+}
+
+// Records returns the records in domain any of refs refer to, in order and
+// without duplicates. Records referred to by id are not looked up, so only
+// their ID is set until they are loaded with LoadRecords.
+func (r *Resolver) Records(domain string, refs ...string) (do.DomainRecords, error) {
+	selectors := make([]string, len(refs))
+	for i, ref := range refs {
+		selectors[i] = recordSelector(ref)
+	}
+
+	cs, err := r.all(kindRecord, kindRecord+":"+domain, selectors, func(ref string) (candidate, bool) {
+		id, err := strconv.Atoi(ref)
+		if err != nil {
+			return candidate{}, false
+		}
+
+		return recordCandidate(do.DomainRecord{DomainRecord: &godo.DomainRecord{ID: id}}), true
+	}, r.listRecords(domain))
+	if err != nil {
+		return nil, err
+	}
+
+	var out do.DomainRecords
+	for _, c := range cs {
+		out = append(out, c.value.(do.DomainRecord))
+	}
+
+	return out, nil
+}
+
+// LoadRecords fetches the records in domain Records only knows the id of,
+// so all of the records returned are complete.
+func (r *Resolver) LoadRecords(domain string, records do.DomainRecords) (do.DomainRecords, error) {
+	out := make(do.DomainRecords, len(records))
+	for i, rec := range records {
+		if rec.Type == "" {
+			fetched, err := r.domains.Record(domain, rec.ID)
+			if err != nil {
+				return nil, err
+			}
+			rec = *fetched
+		}
+
+		out[i] = rec
+	}
+
+	return out, nil
+}
+
+func (r *Resolver) listRecords(domain string) listFn {
+	return func() ([]candidate, error) {
+		list, err := r.domains.Records(domain)
+		if err != nil {
+			return nil, fmt.Errorf("unable to build list of records for %s: %v", domain, err)
+		}
+
+		var cs []candidate
+		for _, rec := range list {
+			cs = append(cs, recordCandidate(rec))
+		}
+
+		return cs, nil
+	}
+}
+
+// recordTypes are the record types a selector can name.
+var recordTypes = map[string]bool{
+	"A": true, "AAAA": true, "CAA": true, "CNAME": true, "MX": true,
+	"NS": true, "SOA": true, "SRV": true, "TXT": true,
+}
+
+// recordSelector upper cases the type in a name:TYPE or name:TYPE:priority
+// selector. Other references, such as IPv6 addresses, are left alone.
+func recordSelector(ref string) string {
+	parts := strings.Split(ref, ":")
+	if strings.HasPrefix(ref, RegexPrefix) || len(parts) < 2 || len(parts) > 3 ||
+		!recordTypes[strings.ToUpper(parts[1])] {
+		return ref
+	}
+
+	parts[1] = strings.ToUpper(parts[1])
+	return strings.Join(parts, ":")
+}
+
+// recordCandidate builds a candidate for a record. Besides its name, a
+// record can be selected by name:TYPE, and MX and SRV records also by
+// name:TYPE:priority.
+func recordCandidate(rec do.DomainRecord) candidate {
+	recordType := strings.ToUpper(rec.Type)
+	names := []string{rec.Name}
+	if rec.Name != "" && recordType != "" {
+		selector := rec.Name + ":" + recordType
+		names = append(names, selector)
+		if recordType == "MX" || recordType == "SRV" {
+			names = append(names, fmt.Sprintf("%s:%d", selector, rec.Priority))
+		}
+	}
+
+	return candidate{
+		ids:   []string{strconv.Itoa(rec.ID), rec.Data},
+		names: names,
+		label: fmt.Sprintf("%s %s %s (%d)", rec.Name, rec.Type, rec.Data, rec.ID),
+		value: rec,
+	}
+}
+
+// list returns the candidates for key, fetching them with fn the first
+// time they are needed.
+func (r *Resolver) list(key string, fn listFn) ([]candidate, error) {
+	if cs, ok := r.lists[key]; ok {
+		return cs, nil
+	}
+
+	cs, err := fn()
+	if err != nil {
+		return nil, err
+	}
+
+	r.lists[key] = cs
+
+	return cs, nil
+}
+
+// find returns the candidates ref selects. It is an error if nothing
+// matches, or if an exact reference matches more than one candidate.
+func (r *Resolver) find(kind, key, ref string, fn listFn) ([]candidate, error) {
+	p, err := Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	cs, err := r.list(key, fn)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := p.filter(cs)
+	if len(matched) == 0 {
+		return nil, &NotFoundError{Kind: kind, Ref: ref}
+	}
+
+	if p.Exact() && len(matched) > 1 {
+		return nil, newAmbiguousError(kind, ref, matched)
+	}
+
+	return matched, nil
+}
+
+// one returns the single candidate ref selects.
+func (r *Resolver) one(kind, key, ref string, fn listFn) (candidate, error) {
+	matched, err := r.find(kind, key, ref, fn)
+	if err != nil {
+		return candidate{}, err
+	}
+
+	if len(matched) > 1 {
+		return candidate{}, newAmbiguousError(kind, ref, matched)
+	}
+
+	return matched[0], nil
+}
+
+// all returns the candidates any of refs select, without duplicates.
+// References direct can build a candidate for are not looked up.
+func (r *Resolver) all(kind, key string, refs []string, direct func(string) (candidate, bool), fn listFn) ([]candidate, error) {
+	var out []candidate
+	seen := map[string]bool{}
+
+	for _, ref := range refs {
+		var matched []candidate
+		if c, ok := direct(ref); ok {
+			matched = []candidate{c}
+		} else {
+			var err error
+			matched, err = r.find(kind, key, ref, fn)
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		for _, c := range matched {
+			if seen[c.ids[0]] {
+				continue
+			}
+
+			seen[c.ids[0]] = true
+			out = append(out, c)
+		}
+	}
+
+	return out, nil
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resolver
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/bryanl/doit/do"
+	"github.com/bryanl/doit/do/mocks"
+	"github.com/digitalocean/godo"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	testDroplets = do.Droplets{
+		{Droplet: &godo.Droplet{ID: 1, Name: "web-1", Networks: &godo.Networks{
+			V4: []godo.NetworkV4{{IPAddress: "8.8.8.8", Type: "public"}},
+		}}},
+		{Droplet: &godo.Droplet{ID: 2, Name: "web-2"}},
+		{Droplet: &godo.Droplet{ID: 3, Name: "db-1"}},
+	}
+
+	testImages = do.Images{
+		{Image: &godo.Image{ID: 10, Name: "Ubuntu 14.04 x64", Slug: "ubuntu-14-04-x64", Type: "snapshot"}},
+		{Image: &godo.Image{ID: 11, Name: "nightly-1", Type: "snapshot"}},
+		{Image: &godo.Image{ID: 12, Name: "nightly-2", Type: "backup"}},
+	}
+
+	testKeys = do.SSHKeys{
+		{Key: &godo.Key{ID: 20, Name: "laptop", Fingerprint: "aa:bb"}},
+	}
+
+	testFloatingIPs = do.FloatingIPs{
+		{FloatingIP: &godo.FloatingIP{IP: "45.55.96.47", Droplet: testDroplets[0].Droplet}},
+		{FloatingIP: &godo.FloatingIP{IP: "45.55.96.48"}},
+	}
+
+	testRecords = do.DomainRecords{
+		{DomainRecord: &godo.DomainRecord{ID: 30, Name: "www", Type: "A", Data: "8.8.8.8"}},
+		{DomainRecord: &godo.DomainRecord{ID: 31, Name: "www", Type: "AAAA", Data: "::1"}},
+		{DomainRecord: &godo.DomainRecord{ID: 32, Name: "mail", Type: "A", Data: "8.8.4.4"}},
+	}
+)
+
+type testServices struct {
+	droplets    mocks.DropletsService
+	images      mocks.ImagesService
+	keys        mocks.KeysService
+	floatingIPs mocks.FloatingIPsService
+	domains     mocks.DomainsService
+}
+
+func withTestResolver(t *testing.T, fn func(*Resolver, *testServices)) {
+	ts := &testServices{}
+	r := New(&ts.droplets, &ts.images, &ts.keys, &ts.floatingIPs, &ts.domains)
+
+	fn(r, ts)
+
+	assert.True(t, ts.droplets.AssertExpectations(t))
+	assert.True(t, ts.images.AssertExpectations(t))
+	assert.True(t, ts.keys.AssertExpectations(t))
+	assert.True(t, ts.floatingIPs.AssertExpectations(t))
+	assert.True(t, ts.domains.AssertExpectations(t))
+}
+
+func TestParse(t *testing.T) {
+	cases := []struct {
+		ref   string
+		exact bool
+		in    []string
+		out   []string
+	}{
+		{ref: "web-1", exact: true, in: []string{"web-1"}, out: []string{"web-10"}},
+		{ref: "web-*", in: []string{"web-1", "web-10"}, out: []string{"db-1"}},
+		{ref: "re:^web-\\d$", in: []string{"web-1"}, out: []string{"web-10"}},
+	}
+
+	for _, c := range cases {
+		p, err := Parse(c.ref)
+		assert.NoError(t, err)
+		assert.Equal(t, c.exact, p.Exact(), c.ref)
+
+		for _, s := range c.in {
+			assert.True(t, p.Match(s), "%s should match %s", c.ref, s)
+		}
+		for _, s := range c.out {
+			assert.False(t, p.Match(s), "%s shouldn't match %s", c.ref, s)
+		}
+	}
+
+	for _, ref := range []string{"", "re:(", "[a"} {
+		_, err := Parse(ref)
+		assert.Error(t, err, ref)
+	}
+}
+
+func TestResolver_Droplets(t *testing.T) {
+	cases := []struct {
+		refs     []string
+		expected []int
+	}{
+		{refs: []string{"web-1"}, expected: []int{1}},
+		{refs: []string{"8.8.8.8"}, expected: []int{1}},
+		{refs: []string{"web-*"}, expected: []int{1, 2}},
+		{refs: []string{"re:-1$"}, expected: []int{1, 3}},
+		{refs: []string{"db-1", "*"}, expected: []int{3, 1, 2}},
+	}
+
+	for _, c := range cases {
+		withTestResolver(t, func(r *Resolver, ts *testServices) {
+			ts.droplets.On("List").Return(testDroplets, nil).Once()
+
+			droplets, err := r.Droplets(c.refs...)
+			assert.NoError(t, err)
+
+			var ids []int
+			for _, d := range droplets {
+				ids = append(ids, d.ID)
+			}
+			assert.Equal(t, c.expected, ids, "refs %v", c.refs)
+		})
+	}
+}
+
+func TestResolver_DropletsByID(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		droplets, err := r.Droplets("5", "5")
+		assert.NoError(t, err)
+		assert.Len(t, droplets, 1)
+		assert.Equal(t, 5, droplets[0].ID)
+	})
+}
+
+func TestResolver_DropletErrors(t *testing.T) {
+	duplicate := do.Droplet{Droplet: &godo.Droplet{ID: 4, Name: "web-1"}}
+	list := append(do.Droplets{duplicate}, testDroplets...)
+
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.droplets.On("List").Return(list, nil).Once()
+
+		_, err := r.Droplets("missing")
+		assert.EqualError(t, err, `unable to find droplet matching "missing"`)
+		assert.IsType(t, &NotFoundError{}, err)
+
+		_, err = r.Droplets("web-1")
+		assert.EqualError(t, err, `"web-1" matches 2 droplets, use an id to pick one: web-1 (4), web-1 (1)`)
+		assert.IsType(t, &AmbiguousError{}, err)
+
+		_, err = r.Droplet("web-*")
+		assert.IsType(t, &AmbiguousError{}, err)
+	})
+}
+
+func TestResolver_Droplet(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.droplets.On("Get", 2).Return(&testDroplets[1], nil)
+
+		d, err := r.Droplet("2")
+		assert.NoError(t, err)
+		assert.Equal(t, "web-2", d.Name)
+	})
+}
+
+func TestResolver_Image(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.images.On("GetBySlug", "ubuntu-14-04-x64").Return(&testImages[0], nil)
+		ts.images.On("GetBySlug", "nightly-1").Return(nil, errors.New("not found"))
+		ts.images.On("List", false).Return(testImages, nil).Once()
+
+		id, err := r.ImageID("nightly-1")
+		assert.NoError(t, err)
+		assert.Equal(t, 11, id)
+
+		for _, ref := range []string{"ubuntu-14-04-x64", "Ubuntu 14.04 x64", "ubuntu-*"} {
+			id, err := r.ImageID(ref)
+			assert.NoError(t, err)
+			assert.Equal(t, 10, id)
+		}
+
+		id, err = r.ImageID("12")
+		assert.NoError(t, err)
+		assert.Equal(t, 12, id)
+	})
+}
+
+func TestResolver_Snapshot(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.images.On("ListUser", false).Return(testImages, nil).Once()
+
+		i, err := r.Snapshot("nightly-1")
+		assert.NoError(t, err)
+		assert.Equal(t, 11, i.ID)
+
+		id, err := r.SnapshotID("nightly-2")
+		assert.NoError(t, err)
+		assert.Equal(t, 12, id)
+
+		id, err = r.SnapshotID("13")
+		assert.NoError(t, err)
+		assert.Equal(t, 13, id)
+
+		_, err = r.Snapshot("nightly-*")
+		assert.IsType(t, &AmbiguousError{}, err)
+
+		_, err = r.Snapshot("Ubuntu 16.04 x64")
+		assert.IsType(t, &NotFoundError{}, err)
+	})
+}
+
+func TestResolver_Key(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.keys.On("List").Return(testKeys, nil).Once()
+
+		id, err := r.KeyID("laptop")
+		assert.NoError(t, err)
+		assert.Equal(t, "20", id)
+
+		id, err = r.KeyID("20")
+		assert.NoError(t, err)
+		assert.Equal(t, "20", id)
+
+		fp := "00:11:22:33:44:55:66:77:88:99:aa:bb:cc:dd:ee:ff"
+		id, err = r.KeyID(fp)
+		assert.NoError(t, err)
+		assert.Equal(t, fp, id)
+	})
+}
+
+func TestResolver_FloatingIP(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.floatingIPs.On("List").Return(testFloatingIPs, nil).Once()
+
+		ip, err := r.FloatingIPAddress("web-1")
+		assert.NoError(t, err)
+		assert.Equal(t, "45.55.96.47", ip)
+
+		ip, err = r.FloatingIPAddress("45.55.96.48")
+		assert.NoError(t, err)
+		assert.Equal(t, "45.55.96.48", ip)
+	})
+}
+
+func TestResolver_Records(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.domains.On("Records", "example.com").Return(testRecords, nil).Once()
+
+		records, err := r.Records("example.com", "mail", "::1", "33")
+		assert.NoError(t, err)
+
+		var ids []int
+		for _, rec := range records {
+			ids = append(ids, rec.ID)
+		}
+		assert.Equal(t, []int{32, 31, 33}, ids)
+
+		_, err = r.Record("example.com", "www")
+		assert.IsType(t, &AmbiguousError{}, err)
+	})
+}
+
+func TestResolver_RecordSelectors(t *testing.T) {
+	records := do.DomainRecords{
+		{DomainRecord: &godo.DomainRecord{ID: 40, Name: "www", Type: "A", Data: "8.8.8.8"}},
+		{DomainRecord: &godo.DomainRecord{ID: 41, Name: "www", Type: "AAAA", Data: "2001:db8::1"}},
+		{DomainRecord: &godo.DomainRecord{ID: 42, Name: "@", Type: "MX", Data: "mx1.example.com", Priority: 10}},
+		{DomainRecord: &godo.DomainRecord{ID: 43, Name: "@", Type: "MX", Data: "mx2.example.com", Priority: 20}},
+	}
+
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.domains.On("Records", "example.com").Return(records, nil).Once()
+
+		rec, err := r.Record("example.com", "www:a")
+		assert.NoError(t, err)
+		assert.Equal(t, 40, rec.ID)
+
+		rec, err = r.Record("example.com", "@:MX:20")
+		assert.NoError(t, err)
+		assert.Equal(t, 43, rec.ID)
+
+		rec, err = r.Record("example.com", "2001:db8::1")
+		assert.NoError(t, err)
+		assert.Equal(t, 41, rec.ID)
+
+		_, err = r.Record("example.com", "@:MX")
+		assert.IsType(t, &AmbiguousError{}, err)
+
+		_, err = r.Record("example.com", "www:TXT")
+		assert.IsType(t, &NotFoundError{}, err)
+
+		found, err := r.Records("example.com", "*:MX", "www:AAAA")
+		assert.NoError(t, err)
+
+		var ids []int
+		for _, rec := range found {
+			ids = append(ids, rec.ID)
+		}
+		assert.Equal(t, []int{42, 43, 41}, ids)
+	})
+}
+
+func TestResolver_LoadDroplets(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.droplets.On("List").Return(testDroplets, nil).Once()
+		ts.droplets.On("Get", 2).Return(&testDroplets[1], nil).Once()
+
+		droplets, err := r.Droplets("2", "db-1")
+		assert.NoError(t, err)
+
+		loaded, err := r.LoadDroplets(droplets)
+		assert.NoError(t, err)
+		assert.Equal(t, "web-2", loaded[0].Name)
+		assert.Equal(t, "db-1", loaded[1].Name)
+	})
+}
+
+func TestResolver_LoadRecords(t *testing.T) {
+	withTestResolver(t, func(r *Resolver, ts *testServices) {
+		ts.domains.On("Record", "example.com", 32).Return(&testRecords[2], nil).Once()
+
+		records, err := r.Records("example.com", "32")
+		assert.NoError(t, err)
+
+		loaded, err := r.LoadRecords("example.com", records)
+		assert.NoError(t, err)
+		assert.Equal(t, "mail", loaded[0].Name)
+	})
+}
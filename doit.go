@@ -150,7 +150,7 @@ func (glv *GithubLatestVersioner) LatestVersion() (string, error) {
 // Config is an interface that represent doit's config.
 type Config interface {
 	GetGodoClient(trace bool) *godo.Client
-	SSH(user, host, keyPath string, port int) runner.Runner
+	SSH(user, host, keyPath string, port int, opts ssh.Options) runner.Runner
 	Set(ns, key string, val interface{})
 	GetString(ns, key string) (string, error)
 	GetBool(ns, key string) (bool, error)
@@ -197,12 +197,13 @@ func (c *LiveConfig) GetGodoClient(trace bool) *godo.Client {
 }
 
 // SSH creates a ssh connection to a host.
-func (c *LiveConfig) SSH(user, host, keyPath string, port int) runner.Runner {
+func (c *LiveConfig) SSH(user, host, keyPath string, port int, opts ssh.Options) runner.Runner {
 	return &ssh.Runner{
 		User:    user,
 		Host:    host,
 		KeyPath: keyPath,
 		Port:    port,
+		Options: opts,
 	}
 
 }
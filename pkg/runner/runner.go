@@ -13,7 +13,20 @@ limitations under the License.
 
 package runner
 
+import "fmt"
+
 // Runner is an interface that Runs things.
 type Runner interface {
 	Run() error
+	Exec(cmd string) error
+}
+
+// ExitError is returned by Exec when a command ran but exited with a
+// non-zero status.
+type ExitError struct {
+	Status int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("command exited with status %d", e.Status)
 }
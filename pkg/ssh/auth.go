@@ -0,0 +1,250 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// readSecret prompts for a password or passphrase without echoing it. It
+// is replaced in tests.
+var readSecret = func(prompt string) ([]byte, error) {
+	fmt.Fprint(os.Stderr, prompt)
+	defer fmt.Fprintln(os.Stderr)
+
+	return terminal.ReadPassword(int(os.Stdin.Fd()))
+}
+
+// identityFiles returns the runner's key followed by the other identity
+// files and ssh's default ones, without duplicates or files which don't
+// exist.
+func (r *Runner) identityFiles() []string {
+	seen := map[string]bool{}
+	var files []string
+
+	candidates := append([]string{r.KeyPath}, r.IdentityFiles...)
+	if usr, err := user.Current(); err == nil {
+		for _, name := range []string{"id_rsa", "id_ecdsa", "id_ed25519", "id_dsa"} {
+			candidates = append(candidates, filepath.Join(usr.HomeDir, ".ssh", name))
+		}
+	}
+
+	for _, f := range candidates {
+		if f == "" || seen[f] {
+			continue
+		}
+		seen[f] = true
+
+		if _, err := os.Stat(f); err == nil {
+			files = append(files, f)
+		}
+	}
+
+	return files
+}
+
+// authMethods returns the ways to authenticate to the runner's host: keys
+// from ssh-agent and the identity files, then a password prompt when
+// interactive. Encrypted keys are skipped unless interactive. Identity
+// files which can't be used are recorded in skipped so a failure to
+// authenticate can say why. The returned closer releases the agent
+// connection once authentication is done.
+func authMethods(r *Runner, interactive bool, skipped *skippedKeys) ([]ssh.AuthMethod, io.Closer) {
+	var agentConn net.Conn
+	if sock := os.Getenv("SSH_AUTH_SOCK"); sock != "" {
+		if conn, err := net.Dial("unix", sock); err == nil {
+			agentConn = conn
+		}
+	}
+
+	signers := func() ([]ssh.Signer, error) {
+		var signers []ssh.Signer
+
+		if agentConn != nil {
+			if s, err := agent.NewClient(agentConn).Signers(); err == nil {
+				signers = append(signers, s...)
+			}
+		}
+
+		s, reasons := loadIdentities(r.identityFiles(), interactive)
+		skipped.set(reasons)
+
+		return append(signers, s...), nil
+	}
+
+	methods := []ssh.AuthMethod{ssh.PublicKeysCallback(signers)}
+	if interactive {
+		methods = append(methods, ssh.PasswordCallback(func() (string, error) {
+			password, err := readSecret(fmt.Sprintf("%s@%s's password: ", r.User, r.Host))
+			return string(password), err
+		}))
+	}
+
+	if agentConn == nil {
+		return methods, nopCloser{}
+	}
+
+	return methods, agentConn
+}
+
+// loadIdentities reads identity files, returning signers for the ones which
+// can be used and why each of the others was skipped.
+func loadIdentities(files []string, interactive bool) ([]ssh.Signer, []string) {
+	var signers []ssh.Signer
+	var skipped []string
+
+	for _, f := range files {
+		s, err := loadSigner(f, interactive)
+		switch {
+		case err != nil:
+			skipped = append(skipped, fmt.Sprintf("%s (%v)", f, err))
+		case s == nil:
+			skipped = append(skipped, fmt.Sprintf("%s (encrypted, run interactively to enter its passphrase)", f))
+		default:
+			signers = append(signers, s)
+		}
+	}
+
+	return signers, skipped
+}
+
+// skippedKeys are the identity files which couldn't be used to
+// authenticate, with the reasons why.
+type skippedKeys struct {
+	mu    sync.Mutex
+	files []string
+}
+
+func (k *skippedKeys) set(files []string) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	k.files = files
+}
+
+// wrap adds the skipped keys to an authentication error.
+func (k *skippedKeys) wrap(err error) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	if len(k.files) == 0 || !strings.Contains(err.Error(), "unable to authenticate") {
+		return err
+	}
+
+	return fmt.Errorf("%v, skipped keys: %s", err, strings.Join(k.files, ", "))
+}
+
+type nopCloser struct{}
+
+func (nopCloser) Close() error { return nil }
+
+// loadSigner reads a private key. An encrypted key is decrypted with a
+// passphrase prompt, which is put off until the key is used if its public
+// half is next to it. Without a prompt encrypted keys give a nil signer.
+func loadSigner(path string, interactive bool) (ssh.Signer, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	signer, err := ssh.ParsePrivateKey(b)
+	if err == nil {
+		return signer, nil
+	}
+
+	block, _ := pem.Decode(b)
+	if block != nil && block.Type == "OPENSSH PRIVATE KEY" {
+		return parseOpenSSHPrivateKey(block.Bytes)
+	}
+
+	if block == nil || !x509.IsEncryptedPEMBlock(block) {
+		return nil, err
+	}
+
+	if !interactive {
+		return nil, nil
+	}
+
+	if pb, err := ioutil.ReadFile(path + ".pub"); err == nil {
+		if pub, _, _, _, err := ssh.ParseAuthorizedKey(pb); err == nil {
+			return &encryptedSigner{path: path, block: block, pub: pub}, nil
+		}
+	}
+
+	return decryptSigner(path, block)
+}
+
+// decryptSigner asks for the passphrase of an encrypted key.
+func decryptSigner(path string, block *pem.Block) (ssh.Signer, error) {
+	passphrase, err := readSecret(fmt.Sprintf("Enter passphrase for key '%s': ", path))
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.DecryptPEMBlock(block, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decrypt key: %v", err)
+	}
+
+	key, err := ssh.ParseRawPrivateKey(pem.EncodeToMemory(&pem.Block{Type: block.Type, Bytes: der}))
+	if err != nil {
+		return nil, err
+	}
+
+	return ssh.NewSignerFromKey(key)
+}
+
+// encryptedSigner is an encrypted key which is decrypted the first time it
+// signs, so there is no passphrase prompt for keys the server won't take.
+type encryptedSigner struct {
+	path  string
+	block *pem.Block
+	pub   ssh.PublicKey
+
+	mu     sync.Mutex
+	signer ssh.Signer
+}
+
+func (s *encryptedSigner) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *encryptedSigner) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.signer == nil {
+		signer, err := decryptSigner(s.path, s.block)
+		if err != nil {
+			return nil, err
+		}
+		s.signer = signer
+	}
+
+	return s.signer.Sign(rand, data)
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// AuthorizedKey is a public key from an authorized_keys or .pub file. Keys
+// are kept in their wire format so every key type can be read, not just
+// the ones golang.org/x/crypto/ssh supports.
+type AuthorizedKey struct {
+	Type    string
+	Blob    []byte
+	Comment string
+}
+
+// String formats the key as an authorized_keys line without options.
+func (k AuthorizedKey) String() string {
+	s := k.Type + " " + base64.StdEncoding.EncodeToString(k.Blob)
+	if k.Comment != "" {
+		s += " " + k.Comment
+	}
+
+	return s
+}
+
+// Fingerprint is the key's MD5 fingerprint.
+func (k AuthorizedKey) Fingerprint() string {
+	return MD5Fingerprint(k.Blob)
+}
+
+// ParseAuthorizedKeys reads the keys in authorized_keys format data,
+// skipping blank lines, comments and key options.
+func ParseAuthorizedKeys(b []byte) ([]AuthorizedKey, error) {
+	var keys []AuthorizedKey
+
+	for i, line := range bytes.Split(b, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+
+		k, err := parseAuthorizedKey(string(line))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", i+1, err)
+		}
+
+		keys = append(keys, k)
+	}
+
+	return keys, nil
+}
+
+func parseAuthorizedKey(line string) (AuthorizedKey, error) {
+	if k, ok := parseKeyFields(line); ok {
+		return k, nil
+	}
+
+	// the line may start with options, which can have quoted spaces.
+	quoted := false
+	for i, r := range line {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\t') && !quoted:
+			if k, ok := parseKeyFields(strings.TrimSpace(line[i:])); ok {
+				return k, nil
+			}
+			return AuthorizedKey{}, fmt.Errorf("unable to parse public key")
+		}
+	}
+
+	return AuthorizedKey{}, fmt.Errorf("unable to parse public key")
+}
+
+// parseKeyFields parses "type base64 [comment]", checking the type matches
+// the one in the key.
+func parseKeyFields(s string) (AuthorizedKey, bool) {
+	fields := strings.Fields(s)
+	if len(fields) < 2 {
+		return AuthorizedKey{}, false
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	if err != nil {
+		return AuthorizedKey{}, false
+	}
+
+	var algo struct {
+		Type string
+		Rest []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(blob, &algo); err != nil || algo.Type != fields[0] {
+		return AuthorizedKey{}, false
+	}
+
+	return AuthorizedKey{
+		Type:    fields[0],
+		Blob:    blob,
+		Comment: strings.Join(fields[2:], " "),
+	}, true
+}
@@ -0,0 +1,54 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestParseAuthorizedKeys(t *testing.T) {
+	_, signer := testKey(t)
+	rsaLine := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(signer.PublicKey())))
+
+	kp, err := GenerateKey(KeyTypeED25519, 0, "")
+	assert.NoError(t, err)
+	edLine := strings.TrimSpace(string(kp.PublicKey))
+
+	data := "# keys\n\n" +
+		rsaLine + " alice at work\n" +
+		`no-pty,command="echo hello world" ` + edLine + "\n"
+
+	keys, err := ParseAuthorizedKeys([]byte(data))
+	assert.NoError(t, err)
+
+	if assert.Len(t, keys, 2) {
+		assert.Equal(t, "ssh-rsa", keys[0].Type)
+		assert.Equal(t, "alice at work", keys[0].Comment)
+		assert.Equal(t, signer.PublicKey().Marshal(), keys[0].Blob)
+		assert.Equal(t, rsaLine+" alice at work", keys[0].String())
+		assert.Equal(t, MD5Fingerprint(signer.PublicKey().Marshal()), keys[0].Fingerprint())
+
+		assert.Equal(t, "ssh-ed25519", keys[1].Type)
+		assert.Equal(t, "", keys[1].Comment)
+		assert.Equal(t, edLine, keys[1].String())
+		assert.Equal(t, kp.Fingerprint, keys[1].Fingerprint())
+	}
+
+	_, err = ParseAuthorizedKeys([]byte(rsaLine + "\nssh-dss AAAA\n"))
+	assert.EqualError(t, err, "line 2: unable to parse public key")
+}
@@ -0,0 +1,260 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Forward is a port forward in ssh's -L and -R form:
+// [bind_address:]port:host:hostport.
+type Forward struct {
+	BindAddress string
+	BindPort    int
+	Host        string
+	HostPort    int
+}
+
+// ParseForward parses a port forward specification.
+func ParseForward(spec string) (Forward, error) {
+	parts := strings.Split(spec, ":")
+
+	var f Forward
+	switch len(parts) {
+	case 3:
+		f.BindAddress = "localhost"
+	case 4:
+		f.BindAddress, parts = parts[0], parts[1:]
+	default:
+		return f, fmt.Errorf("invalid forward %q, expected [bind_address:]port:host:hostport", spec)
+	}
+
+	bindPort, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return f, fmt.Errorf("invalid forward %q: bad port %q", spec, parts[0])
+	}
+
+	hostPort, err := strconv.Atoi(parts[2])
+	if err != nil {
+		return f, fmt.Errorf("invalid forward %q: bad port %q", spec, parts[2])
+	}
+
+	f.BindPort, f.Host, f.HostPort = bindPort, parts[1], hostPort
+	return f, nil
+}
+
+func (f Forward) bindAddr() string {
+	return net.JoinHostPort(f.BindAddress, strconv.Itoa(f.BindPort))
+}
+
+func (f Forward) hostAddr() string {
+	return net.JoinHostPort(f.Host, strconv.Itoa(f.HostPort))
+}
+
+// startForwards starts the runner's port forwards and SOCKS proxy over
+// client. They stop when the client is closed.
+func startForwards(client *ssh.Client, r *Runner) error {
+	for _, spec := range r.LocalForwards {
+		f, err := ParseForward(spec)
+		if err != nil {
+			return err
+		}
+
+		l, err := net.Listen("tcp", f.bindAddr())
+		if err != nil {
+			return err
+		}
+
+		go acceptForwards(client, l, func(net.Conn) (net.Conn, error) {
+			return client.Dial("tcp", f.hostAddr())
+		})
+	}
+
+	for _, spec := range r.RemoteForwards {
+		f, err := ParseForward(spec)
+		if err != nil {
+			return err
+		}
+
+		l, err := client.Listen("tcp", f.bindAddr())
+		if err != nil {
+			return fmt.Errorf("unable to listen on %s on the remote host: %v", f.bindAddr(), err)
+		}
+
+		go acceptForwards(client, l, func(net.Conn) (net.Conn, error) {
+			return net.Dial("tcp", f.hostAddr())
+		})
+	}
+
+	if r.SOCKSPort > 0 {
+		l, err := net.Listen("tcp", net.JoinHostPort("localhost", strconv.Itoa(r.SOCKSPort)))
+		if err != nil {
+			return err
+		}
+
+		go acceptForwards(client, l, func(conn net.Conn) (net.Conn, error) {
+			addr, err := socksHandshake(conn)
+			if err != nil {
+				return nil, err
+			}
+
+			remote, err := client.Dial("tcp", addr)
+			socksReply(conn, err)
+			return remote, err
+		})
+	}
+
+	return nil
+}
+
+// acceptForwards accepts connections on l until client is closed, and
+// joins each to the connection returned by dial.
+func acceptForwards(client *ssh.Client, l net.Listener, dial func(net.Conn) (net.Conn, error)) {
+	go func() {
+		_ = client.Wait()
+		_ = l.Close()
+	}()
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			remote, err := dial(conn)
+			if err != nil {
+				return
+			}
+			defer remote.Close()
+
+			done := make(chan struct{}, 2)
+			go func() {
+				_, _ = io.Copy(remote, conn)
+				done <- struct{}{}
+			}()
+			go func() {
+				_, _ = io.Copy(conn, remote)
+				done <- struct{}{}
+			}()
+			<-done
+		}()
+	}
+}
+
+const (
+	socksVersion      = 5
+	socksNoAuth       = 0
+	socksConnect      = 1
+	socksAddrIPv4     = 1
+	socksAddrDomain   = 3
+	socksAddrIPv6     = 4
+	socksSucceeded    = 0
+	socksHostFailure  = 4
+	socksNotSupported = 7
+)
+
+var errSOCKSVersion = errors.New("only SOCKS5 is supported")
+
+// socksHandshake reads a SOCKS5 greeting and CONNECT request from conn and
+// returns the address the client wants to reach. Only connections without
+// authentication are supported.
+func socksHandshake(conn io.ReadWriter) (string, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(conn, hdr[:]); err != nil {
+		return "", err
+	}
+
+	if hdr[0] != socksVersion {
+		return "", errSOCKSVersion
+	}
+
+	methods := make([]byte, hdr[1])
+	if _, err := io.ReadFull(conn, methods); err != nil {
+		return "", err
+	}
+
+	if _, err := conn.Write([]byte{socksVersion, socksNoAuth}); err != nil {
+		return "", err
+	}
+
+	var req [4]byte
+	if _, err := io.ReadFull(conn, req[:]); err != nil {
+		return "", err
+	}
+
+	if req[0] != socksVersion {
+		return "", errSOCKSVersion
+	}
+
+	if req[1] != socksConnect {
+		_, _ = conn.Write([]byte{socksVersion, socksNotSupported, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+		return "", fmt.Errorf("unsupported SOCKS command %d", req[1])
+	}
+
+	var host string
+	switch req[3] {
+	case socksAddrIPv4, socksAddrIPv6:
+		size := net.IPv4len
+		if req[3] == socksAddrIPv6 {
+			size = net.IPv6len
+		}
+
+		ip := make(net.IP, size)
+		if _, err := io.ReadFull(conn, ip); err != nil {
+			return "", err
+		}
+		host = ip.String()
+	case socksAddrDomain:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return "", err
+		}
+
+		name := make([]byte, n[0])
+		if _, err := io.ReadFull(conn, name); err != nil {
+			return "", err
+		}
+		host = string(name)
+	default:
+		return "", fmt.Errorf("unsupported SOCKS address type %d", req[3])
+	}
+
+	var port [2]byte
+	if _, err := io.ReadFull(conn, port[:]); err != nil {
+		return "", err
+	}
+
+	return net.JoinHostPort(host, strconv.Itoa(int(binary.BigEndian.Uint16(port[:])))), nil
+}
+
+// socksReply tells a SOCKS client whether its connection was made.
+func socksReply(conn io.Writer, err error) {
+	status := byte(socksSucceeded)
+	if err != nil {
+		status = socksHostFailure
+	}
+
+	_, _ = conn.Write([]byte{socksVersion, status, 0, socksAddrIPv4, 0, 0, 0, 0, 0, 0})
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseForward(t *testing.T) {
+	f, err := ParseForward("5432:localhost:5432")
+	assert.NoError(t, err)
+	assert.Equal(t, Forward{BindAddress: "localhost", BindPort: 5432, Host: "localhost", HostPort: 5432}, f)
+
+	f, err = ParseForward("0.0.0.0:8080:10.0.0.5:80")
+	assert.NoError(t, err)
+	assert.Equal(t, Forward{BindAddress: "0.0.0.0", BindPort: 8080, Host: "10.0.0.5", HostPort: 80}, f)
+
+	for _, spec := range []string{"5432", "a:localhost:5432", "5432:localhost:b"} {
+		_, err := ParseForward(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+// socksConn is a connection with a scripted client side.
+type socksConn struct {
+	in  *bytes.Buffer
+	out bytes.Buffer
+}
+
+func (c *socksConn) Read(p []byte) (int, error)  { return c.in.Read(p) }
+func (c *socksConn) Write(p []byte) (int, error) { return c.out.Write(p) }
+
+func TestSOCKSHandshake(t *testing.T) {
+	cases := []struct {
+		req  []byte
+		addr string
+	}{
+		{req: []byte{5, 1, 0, 1, 10, 0, 0, 5, 0x15, 0x38}, addr: "10.0.0.5:5432"},
+		{req: append(append([]byte{5, 1, 0, 3, 9}, "localhost"...), 0, 80), addr: "localhost:80"},
+	}
+
+	for _, c := range cases {
+		conn := &socksConn{in: bytes.NewBuffer(append([]byte{5, 1, 0}, c.req...))}
+
+		addr, err := socksHandshake(conn)
+		assert.NoError(t, err)
+		assert.Equal(t, c.addr, addr)
+		assert.Equal(t, []byte{5, 0}, conn.out.Bytes())
+	}
+}
+
+func TestSOCKSHandshake_Unsupported(t *testing.T) {
+	conn := &socksConn{in: bytes.NewBuffer([]byte{4, 1, 0, 80, 10, 0, 0, 5, 0})}
+	_, err := socksHandshake(conn)
+	assert.Equal(t, errSOCKSVersion, err)
+
+	conn = &socksConn{in: bytes.NewBuffer([]byte{5, 1, 0, 5, 2, 0, 1, 10, 0, 0, 5, 0, 80})}
+	_, err = socksHandshake(conn)
+	assert.EqualError(t, err, "unsupported SOCKS command 2")
+}
+
+func TestExternalSSHArgs(t *testing.T) {
+	r := &Runner{
+		User:    "root",
+		Host:    "10.0.0.5",
+		KeyPath: "/id_rsa",
+		Port:    22,
+		Options: Options{
+			LocalForwards:     []string{"5432:localhost:5432"},
+			RemoteForwards:    []string{"8080:localhost:3000"},
+			SOCKSPort:         1080,
+			Jump:              "core@10.0.0.1",
+			IdentityFiles:     []string{"/id_ecdsa"},
+			KnownHostsFile:    "/known_hosts",
+			AcceptNewHostKeys: true,
+		},
+	}
+
+	assert.Equal(t, []string{
+		"-i", "/id_rsa", "-i", "/id_ecdsa", "-o", "UserKnownHostsFile=/known_hosts",
+		"-o", "StrictHostKeyChecking=accept-new", "-p", "22",
+		"-L", "5432:localhost:5432", "-R", "8080:localhost:3000", "-D", "1080",
+		"-J", "core@10.0.0.1",
+		"root@10.0.0.5",
+	}, externalSSHArgs(r))
+}
+
+func TestJumpRunner(t *testing.T) {
+	r := &Runner{User: "root", Host: "10.0.0.5", KeyPath: "/id_rsa", Port: 2200,
+		Options: Options{AcceptNewHostKeys: true}}
+
+	cases := []struct {
+		jump string
+		user string
+		host string
+		port int
+	}{
+		{jump: "bastion.example.com", user: "root", host: "bastion.example.com", port: 22},
+		{jump: "core@10.0.0.1:2222", user: "core", host: "10.0.0.1", port: 2222},
+		{jump: "[2001:db8::1]:2222", user: "root", host: "2001:db8::1", port: 2222},
+	}
+
+	for _, c := range cases {
+		r.Jump = c.jump
+		jr := jumpRunner(r)
+
+		assert.Equal(t, c.user, jr.User, c.jump)
+		assert.Equal(t, c.host, jr.Host, c.jump)
+		assert.Equal(t, c.port, jr.Port, c.jump)
+		assert.Equal(t, "/id_rsa", jr.KeyPath, c.jump)
+		assert.True(t, jr.AcceptNewHostKeys, c.jump)
+		assert.Empty(t, jr.Jump, c.jump)
+	}
+}
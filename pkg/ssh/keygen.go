@@ -0,0 +1,292 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Key types which can be generated.
+const (
+	KeyTypeRSA     = "rsa"
+	KeyTypeECDSA   = "ecdsa"
+	KeyTypeED25519 = "ed25519"
+)
+
+const (
+	keyAlgoED25519 = "ssh-ed25519"
+	defaultRSABits = 4096
+	minRSABits     = 2048
+)
+
+// KeyPair is a generated key in the formats ssh-keygen writes.
+type KeyPair struct {
+	// PrivateKey is the PEM encoded private key.
+	PrivateKey []byte
+	// PublicKey is the public key as an authorized_keys line.
+	PublicKey []byte
+	// Fingerprint is the MD5 fingerprint of the public key.
+	Fingerprint string
+}
+
+// GenerateKey creates a key of the given type. bits is the RSA key size
+// or the ECDSA curve size, and 0 picks the default for the type.
+func GenerateKey(keyType string, bits int, comment string) (*KeyPair, error) {
+	var (
+		block *pem.Block
+		pub   ssh.PublicKey
+	)
+
+	switch keyType {
+	case KeyTypeRSA:
+		if bits == 0 {
+			bits = defaultRSABits
+		}
+
+		if bits < minRSABits {
+			return nil, fmt.Errorf("rsa keys must be at least %d bits", minRSABits)
+		}
+
+		key, err := rsa.GenerateKey(rand.Reader, bits)
+		if err != nil {
+			return nil, err
+		}
+
+		block = &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		if pub, err = ssh.NewPublicKey(&key.PublicKey); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeECDSA:
+		var curve elliptic.Curve
+		switch bits {
+		case 0, 256:
+			curve = elliptic.P256()
+		case 384:
+			curve = elliptic.P384()
+		case 521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("ecdsa keys must be 256, 384 or 521 bits")
+		}
+
+		key, err := ecdsa.GenerateKey(curve, rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		der, err := x509.MarshalECPrivateKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		block = &pem.Block{Type: "EC PRIVATE KEY", Bytes: der}
+		if pub, err = ssh.NewPublicKey(&key.PublicKey); err != nil {
+			return nil, err
+		}
+
+	case KeyTypeED25519:
+		if bits != 0 {
+			return nil, fmt.Errorf("ed25519 keys have a fixed size")
+		}
+
+		pk, sk, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+
+		pub = rawPublicKey{keyAlgoED25519, ssh.Marshal(struct {
+			Type string
+			Key  []byte
+		}{keyAlgoED25519, pk})}
+
+		block = &pem.Block{Type: "OPENSSH PRIVATE KEY", Bytes: marshalED25519PrivateKey(pub.Marshal(), pk, sk, comment)}
+
+	default:
+		return nil, fmt.Errorf("unknown key type %q, expected %s, %s or %s",
+			keyType, KeyTypeRSA, KeyTypeECDSA, KeyTypeED25519)
+	}
+
+	return &KeyPair{
+		PrivateKey:  pem.EncodeToMemory(block),
+		PublicKey:   authorizedKeyLine(pub, comment),
+		Fingerprint: MD5Fingerprint(pub.Marshal()),
+	}, nil
+}
+
+// MD5Fingerprint formats the MD5 fingerprint of a public key in its wire
+// format, which is how DigitalOcean identifies keys.
+func MD5Fingerprint(pub []byte) string {
+	sum := md5.Sum(pub)
+
+	hex := make([]string, len(sum))
+	for i, b := range sum {
+		hex[i] = fmt.Sprintf("%02x", b)
+	}
+
+	return strings.Join(hex, ":")
+}
+
+// authorizedKeyLine formats a public key for authorized_keys with a
+// comment.
+func authorizedKeyLine(pub ssh.PublicKey, comment string) []byte {
+	line := ssh.MarshalAuthorizedKey(pub)
+	if comment == "" {
+		return line
+	}
+
+	return append(bytes.TrimSuffix(line, []byte("\n")), []byte(" "+comment+"\n")...)
+}
+
+// rawPublicKey is a public key known only by its wire format, for key
+// types golang.org/x/crypto/ssh doesn't support.
+type rawPublicKey struct {
+	algo string
+	blob []byte
+}
+
+func (k rawPublicKey) Type() string    { return k.algo }
+func (k rawPublicKey) Marshal() []byte { return k.blob }
+func (k rawPublicKey) Verify([]byte, *ssh.Signature) error {
+	return fmt.Errorf("ssh: can't verify %s signatures", k.algo)
+}
+
+// marshalED25519PrivateKey encodes an unencrypted key in OpenSSH's own
+// format, which is the only one ssh reads ed25519 keys from.
+func marshalED25519PrivateKey(pub []byte, pk ed25519.PublicKey, sk ed25519.PrivateKey, comment string) []byte {
+	var check [4]byte
+	_, _ = rand.Read(check[:])
+	checkInt := binary.BigEndian.Uint32(check[:])
+
+	private := ssh.Marshal(struct {
+		Check1, Check2 uint32
+		Type           string
+		Public         []byte
+		Private        []byte
+		Comment        string
+	}{checkInt, checkInt, keyAlgoED25519, pk, sk, comment})
+
+	for i := byte(1); len(private)%8 != 0; i++ {
+		private = append(private, i)
+	}
+
+	key := ssh.Marshal(struct {
+		Cipher     string
+		KDF        string
+		KDFOptions string
+		Keys       uint32
+		Public     []byte
+		Private    []byte
+	}{"none", "none", "", 1, pub, private})
+
+	return append([]byte("openssh-key-v1\x00"), key...)
+}
+
+// opensshKeyMagic starts a key in OpenSSH's own format.
+const opensshKeyMagic = "openssh-key-v1\x00"
+
+// parseOpenSSHPrivateKey reads an unencrypted ed25519 key in OpenSSH's own
+// format, the way GenerateKey writes them. Other keys in the format aren't
+// supported.
+func parseOpenSSHPrivateKey(b []byte) (ssh.Signer, error) {
+	if !bytes.HasPrefix(b, []byte(opensshKeyMagic)) {
+		return nil, errors.New("ssh: invalid OpenSSH private key")
+	}
+
+	var key struct {
+		Cipher     string
+		KDF        string
+		KDFOptions string
+		Keys       uint32
+		Public     []byte
+		Private    []byte
+	}
+	if err := ssh.Unmarshal(b[len(opensshKeyMagic):], &key); err != nil {
+		return nil, err
+	}
+
+	if key.Cipher != "none" {
+		return nil, errors.New("encrypted OpenSSH format keys aren't supported by the internal client")
+	}
+
+	if key.Keys != 1 {
+		return nil, fmt.Errorf("ssh: OpenSSH private key holds %d keys, expected 1", key.Keys)
+	}
+
+	var private struct {
+		Check1, Check2 uint32
+		Type           string
+		Rest           []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(key.Private, &private); err != nil {
+		return nil, err
+	}
+
+	if private.Check1 != private.Check2 {
+		return nil, errors.New("ssh: OpenSSH private key is corrupt")
+	}
+
+	if private.Type != keyAlgoED25519 {
+		return nil, fmt.Errorf("OpenSSH format %s keys aren't supported by the internal client, they can be converted with ssh-keygen -p -m PEM", private.Type)
+	}
+
+	var ed struct {
+		Public  []byte
+		Private []byte
+		Comment string
+		Pad     []byte `ssh:"rest"`
+	}
+	if err := ssh.Unmarshal(private.Rest, &ed); err != nil {
+		return nil, err
+	}
+
+	if len(ed.Private) != ed25519.PrivateKeySize {
+		return nil, errors.New("ssh: invalid ed25519 private key")
+	}
+
+	return &ed25519Signer{
+		pub: rawPublicKey{keyAlgoED25519, key.Public},
+		key: ed25519.PrivateKey(ed.Private),
+	}, nil
+}
+
+// ed25519Signer signs with an ed25519 key, which golang.org/x/crypto/ssh
+// doesn't support itself.
+type ed25519Signer struct {
+	pub rawPublicKey
+	key ed25519.PrivateKey
+}
+
+func (s *ed25519Signer) PublicKey() ssh.PublicKey {
+	return s.pub
+}
+
+func (s *ed25519Signer) Sign(rand io.Reader, data []byte) (*ssh.Signature, error) {
+	return &ssh.Signature{Format: keyAlgoED25519, Blob: ed25519.Sign(s.key, data)}, nil
+}
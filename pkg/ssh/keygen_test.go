@@ -0,0 +1,148 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/pem"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func TestGenerateKey_ECDSA(t *testing.T) {
+	kp, err := GenerateKey(KeyTypeECDSA, 384, "me@laptop")
+	assert.NoError(t, err)
+
+	signer, err := ssh.ParsePrivateKey(kp.PrivateKey)
+	assert.NoError(t, err)
+
+	pub, comment, _, _, err := ssh.ParseAuthorizedKey(kp.PublicKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "me@laptop", comment)
+	assert.Equal(t, "ecdsa-sha2-nistp384", pub.Type())
+	assert.Equal(t, signer.PublicKey().Marshal(), pub.Marshal())
+	assert.Equal(t, MD5Fingerprint(pub.Marshal()), kp.Fingerprint)
+}
+
+func TestGenerateKey_ED25519(t *testing.T) {
+	kp, err := GenerateKey(KeyTypeED25519, 0, "me@laptop")
+	assert.NoError(t, err)
+
+	fields := strings.Fields(string(kp.PublicKey))
+	if assert.Len(t, fields, 3) {
+		assert.Equal(t, []string{"ssh-ed25519", "me@laptop"}, []string{fields[0], fields[2]})
+	}
+
+	blob, err := base64.StdEncoding.DecodeString(fields[1])
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode(kp.PrivateKey)
+	if assert.NotNil(t, block) {
+		assert.Equal(t, "OPENSSH PRIVATE KEY", block.Type)
+	}
+
+	magic := []byte("openssh-key-v1\x00")
+	assert.True(t, bytes.HasPrefix(block.Bytes, magic))
+
+	var key struct {
+		Cipher, KDF, KDFOptions string
+		Keys                    uint32
+		Public, Private         []byte
+	}
+	assert.NoError(t, ssh.Unmarshal(block.Bytes[len(magic):], &key))
+	assert.Equal(t, blob, key.Public)
+	assert.Equal(t, 0, len(key.Private)%8)
+
+	var private struct {
+		Check1, Check2 uint32
+		Type           string
+		Public         []byte
+		Private        []byte
+		Comment        string
+		Pad            []byte `ssh:"rest"`
+	}
+	assert.NoError(t, ssh.Unmarshal(key.Private, &private))
+	assert.Equal(t, private.Check1, private.Check2)
+	assert.Equal(t, "me@laptop", private.Comment)
+	assert.Equal(t, []byte(ed25519.PrivateKey(private.Private).Public().(ed25519.PublicKey)), private.Public)
+	assert.True(t, bytes.HasSuffix(blob, private.Public))
+}
+
+func TestParseOpenSSHPrivateKey(t *testing.T) {
+	kp, err := GenerateKey(KeyTypeED25519, 0, "me@laptop")
+	assert.NoError(t, err)
+
+	block, _ := pem.Decode(kp.PrivateKey)
+	signer, err := parseOpenSSHPrivateKey(block.Bytes)
+	assert.NoError(t, err)
+
+	fields := strings.Fields(string(kp.PublicKey))
+	blob, _ := base64.StdEncoding.DecodeString(fields[1])
+	assert.Equal(t, blob, signer.PublicKey().Marshal())
+	assert.Equal(t, "ssh-ed25519", signer.PublicKey().Type())
+
+	sig, err := signer.Sign(nil, []byte("data"))
+	assert.NoError(t, err)
+	assert.Equal(t, "ssh-ed25519", sig.Format)
+
+	var wire struct {
+		Type string
+		Key  []byte
+	}
+	assert.NoError(t, ssh.Unmarshal(blob, &wire))
+	assert.True(t, ed25519.Verify(ed25519.PublicKey(wire.Key), []byte("data"), sig.Blob))
+}
+
+func TestParseOpenSSHPrivateKey_Unsupported(t *testing.T) {
+	encrypted := append([]byte(opensshKeyMagic), ssh.Marshal(struct {
+		Cipher, KDF, KDFOptions string
+		Keys                    uint32
+		Public, Private         []byte
+	}{"aes256-ctr", "bcrypt", "", 1, nil, nil})...)
+	_, err := parseOpenSSHPrivateKey(encrypted)
+	assert.EqualError(t, err, "encrypted OpenSSH format keys aren't supported by the internal client")
+
+	private := ssh.Marshal(struct {
+		Check1, Check2 uint32
+		Type           string
+	}{1, 1, "ssh-rsa"})
+	rsa := append([]byte(opensshKeyMagic), ssh.Marshal(struct {
+		Cipher, KDF, KDFOptions string
+		Keys                    uint32
+		Public, Private         []byte
+	}{"none", "none", "", 1, nil, private})...)
+	_, err = parseOpenSSHPrivateKey(rsa)
+	assert.Contains(t, err.Error(), "OpenSSH format ssh-rsa keys aren't supported")
+}
+
+func TestGenerateKey_Invalid(t *testing.T) {
+	for _, c := range []struct {
+		keyType string
+		bits    int
+		err     string
+	}{
+		{"dsa", 0, `unknown key type "dsa", expected rsa, ecdsa or ed25519`},
+		{KeyTypeRSA, 1024, "rsa keys must be at least 2048 bits"},
+		{KeyTypeECDSA, 128, "ecdsa keys must be 256, 384 or 521 bits"},
+		{KeyTypeED25519, 256, "ed25519 keys have a fixed size"},
+	} {
+		_, err := GenerateKey(c.keyType, c.bits, "")
+		assert.EqualError(t, err, c.err)
+	}
+}
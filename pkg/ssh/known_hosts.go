@@ -0,0 +1,244 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+var (
+	// confirmHostKey asks whether to trust a host seen for the first time.
+	// It is replaced in tests.
+	confirmHostKey = func(host string, key ssh.PublicKey) (bool, error) {
+		fmt.Fprintf(os.Stderr, "The authenticity of host '%s' can't be established.\n", host)
+		fmt.Fprintf(os.Stderr, "%s key fingerprint is %s.\n", key.Type(), fingerprint(key))
+		fmt.Fprint(os.Stderr, "Are you sure you want to continue connecting (yes/no)? ")
+
+		answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			return false, err
+		}
+
+		return strings.TrimSpace(strings.ToLower(answer)) == "yes", nil
+	}
+
+	// knownHostsMu serializes additions to known_hosts from parallel
+	// connections.
+	knownHostsMu sync.Mutex
+)
+
+// defaultKnownHostsFile is the user's OpenSSH known_hosts file.
+func defaultKnownHostsFile() string {
+	usr, err := user.Current()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(usr.HomeDir, ".ssh", "known_hosts")
+}
+
+// fingerprint formats a key's fingerprint the way OpenSSH does.
+func fingerprint(key ssh.PublicKey) string {
+	sum := sha256.Sum256(key.Marshal())
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+// knownHostsName is how a host and port are written in known_hosts.
+func knownHostsName(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	if port == "22" {
+		return host
+	}
+
+	return fmt.Sprintf("[%s]:%s", host, port)
+}
+
+// knownHosts checks host keys against a known_hosts file. Hosts seen for
+// the first time are added without confirmation if acceptNew is set, and
+// after confirmation when interactive. Otherwise they are an error. A key
+// which doesn't match the recorded one is always an error.
+type knownHosts struct {
+	path        string
+	interactive bool
+	acceptNew   bool
+}
+
+func (k *knownHosts) check(hostname string, remote net.Addr, key ssh.PublicKey) error {
+	host := knownHostsName(hostname)
+
+	known, line, err := k.lookup(host, key)
+	if err != nil || known {
+		return err
+	}
+
+	if line > 0 {
+		return fmt.Errorf("the host key for %s has changed, someone could be impersonating it. "+
+			"The new %s key fingerprint is %s. If the change is expected, remove line %d of %s",
+			host, key.Type(), fingerprint(key), line, k.path)
+	}
+
+	switch {
+	case k.acceptNew:
+	case k.interactive:
+		ok, err := confirmHostKey(host, key)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("host key verification failed for %s", host)
+		}
+	default:
+		return fmt.Errorf("host key verification failed for %s, it isn't in %s and can't be confirmed "+
+			"when not interactive. Its %s key fingerprint is %s, connect interactively once or accept new host keys",
+			host, k.path, key.Type(), fingerprint(key))
+	}
+
+	if err := k.add(host, key); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Permanently added '%s' (%s) to the list of known hosts.\n", host, key.Type())
+	return nil
+}
+
+// lookup reports whether key is recorded for host. If it isn't, line is
+// the first line recording a different key for host, or 0 if the host
+// isn't known.
+func (k *knownHosts) lookup(host string, key ssh.PublicKey) (known bool, line int, err error) {
+	b, err := ioutil.ReadFile(k.path)
+	if os.IsNotExist(err) {
+		return false, 0, nil
+	}
+	if err != nil {
+		return false, 0, err
+	}
+
+	for i, l := range bytes.Split(b, []byte("\n")) {
+		marker, hosts, hostKey, _, _, err := ssh.ParseKnownHosts(l)
+		if err != nil || !matchHosts(hosts, host) {
+			continue
+		}
+
+		same := bytes.Equal(hostKey.Marshal(), key.Marshal())
+
+		switch marker {
+		case "revoked":
+			if same {
+				return false, 0, fmt.Errorf("the host key for %s has been revoked", host)
+			}
+		case "":
+			if same {
+				return true, 0, nil
+			}
+
+			if line == 0 {
+				line = i + 1
+			}
+		}
+	}
+
+	return false, line, nil
+}
+
+func (k *knownHosts) add(host string, key ssh.PublicKey) error {
+	knownHostsMu.Lock()
+	defer knownHostsMu.Unlock()
+
+	if err := os.MkdirAll(filepath.Dir(k.path), 0700); err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(k.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(f, "%s %s", host, ssh.MarshalAuthorizedKey(key)); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// matchHosts reports whether host matches a known_hosts host list, which
+// can have wildcards, negations and hashed names.
+func matchHosts(patterns []string, host string) bool {
+	matched := false
+
+	for _, p := range patterns {
+		negated := strings.HasPrefix(p, "!")
+		if negated {
+			p = p[1:]
+		}
+
+		if !matchHost(p, host) {
+			continue
+		}
+
+		if negated {
+			return false
+		}
+		matched = true
+	}
+
+	return matched
+}
+
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "|1|") {
+		parts := strings.Split(pattern[3:], "|")
+		if len(parts) != 2 {
+			return false
+		}
+
+		salt, err := base64.StdEncoding.DecodeString(parts[0])
+		if err != nil {
+			return false
+		}
+
+		hash, err := base64.StdEncoding.DecodeString(parts[1])
+		if err != nil {
+			return false
+		}
+
+		mac := hmac.New(sha1.New, salt)
+		mac.Write([]byte(host))
+		return hmac.Equal(mac.Sum(nil), hash)
+	}
+
+	ok, err := path.Match(pattern, host)
+	return err == nil && ok
+}
@@ -0,0 +1,280 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/ssh"
+)
+
+func testKey(t *testing.T) (*rsa.PrivateKey, ssh.Signer) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	assert.NoError(t, err)
+
+	signer, err := ssh.NewSignerFromKey(key)
+	assert.NoError(t, err)
+
+	return key, signer
+}
+
+func withTempDir(t *testing.T, fn func(dir string)) {
+	dir, err := ioutil.TempDir("", "ssh")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	fn(dir)
+}
+
+func knownHostsLine(hosts string, key ssh.PublicKey) string {
+	return fmt.Sprintf("%s %s", hosts, ssh.MarshalAuthorizedKey(key))
+}
+
+func hashedHost(host string) string {
+	salt := []byte("0123456789abcdefghij")
+	mac := hmac.New(sha1.New, salt)
+	mac.Write([]byte(host))
+
+	return fmt.Sprintf("|1|%s|%s", base64.StdEncoding.EncodeToString(salt),
+		base64.StdEncoding.EncodeToString(mac.Sum(nil)))
+}
+
+func TestKnownHosts(t *testing.T) {
+	_, known := testKey(t)
+	_, other := testKey(t)
+
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "known_hosts")
+		contents := "# a comment\n" +
+			knownHostsLine("10.0.0.1,web-1", known.PublicKey()) +
+			knownHostsLine(hashedHost("[10.0.0.2]:2222"), known.PublicKey()) +
+			knownHostsLine("10.1.*,!10.1.0.9", known.PublicKey()) +
+			"@revoked " + knownHostsLine("10.0.0.4", other.PublicKey()) +
+			knownHostsLine("10.0.0.4", other.PublicKey())
+		assert.NoError(t, ioutil.WriteFile(path, []byte(contents), 0600))
+
+		k := &knownHosts{path: path}
+
+		for _, addr := range []string{"10.0.0.1:22", "[10.0.0.2]:2222", "10.1.2.3:22"} {
+			assert.NoError(t, k.check(addr, nil, known.PublicKey()), addr)
+		}
+
+		err := k.check("10.0.0.1:22", nil, other.PublicKey())
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "the host key for 10.0.0.1 has changed")
+			assert.Contains(t, err.Error(), "remove line 2 of "+path)
+		}
+
+		err = k.check("10.0.0.4:22", nil, other.PublicKey())
+		assert.EqualError(t, err, "the host key for 10.0.0.4 has been revoked")
+	})
+}
+
+func TestKnownHosts_TrustOnFirstUse(t *testing.T) {
+	_, key := testKey(t)
+
+	og := confirmHostKey
+	defer func() { confirmHostKey = og }()
+
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, ".ssh", "known_hosts")
+		k := &knownHosts{path: path, interactive: true}
+
+		var asked []string
+		confirmHostKey = func(host string, key ssh.PublicKey) (bool, error) {
+			asked = append(asked, host)
+			return host == "10.0.0.9", nil
+		}
+
+		err := k.check("10.0.0.8:22", nil, key.PublicKey())
+		assert.EqualError(t, err, "host key verification failed for 10.0.0.8")
+
+		assert.NoError(t, k.check("10.0.0.9:22", nil, key.PublicKey()))
+		assert.NoError(t, k.check("10.0.0.9:22", nil, key.PublicKey()))
+		assert.Equal(t, []string{"10.0.0.8", "10.0.0.9"}, asked)
+
+		b, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, knownHostsLine("10.0.0.9", key.PublicKey()), string(b))
+
+		// without a prompt new hosts are refused unless they are accepted.
+		k.interactive = false
+		err = k.check("10.0.0.10:22", nil, key.PublicKey())
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "host key verification failed for 10.0.0.10")
+			assert.Contains(t, err.Error(), "can't be confirmed when not interactive")
+		}
+
+		k.acceptNew = true
+		assert.NoError(t, k.check("10.0.0.10:22", nil, key.PublicKey()))
+		assert.Len(t, asked, 2)
+
+		b, err = ioutil.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, knownHostsLine("10.0.0.9", key.PublicKey())+
+			knownHostsLine("10.0.0.10", key.PublicKey()), string(b))
+	})
+}
+
+func TestLoadSigner_Encrypted(t *testing.T) {
+	key, signer := testKey(t)
+
+	og := readSecret
+	defer func() { readSecret = og }()
+
+	withTempDir(t, func(dir string) {
+		block, err := x509.EncryptPEMBlock(rand.Reader, "RSA PRIVATE KEY",
+			x509.MarshalPKCS1PrivateKey(key), []byte("secret"), x509.PEMCipherAES256)
+		assert.NoError(t, err)
+
+		path := filepath.Join(dir, "id_rsa")
+		assert.NoError(t, ioutil.WriteFile(path, pem.EncodeToMemory(block), 0600))
+
+		prompts := 0
+		readSecret = func(prompt string) ([]byte, error) {
+			prompts++
+			return []byte("secret"), nil
+		}
+
+		s, err := loadSigner(path, false)
+		assert.NoError(t, err)
+		assert.Nil(t, s)
+
+		s, err = loadSigner(path, true)
+		assert.NoError(t, err)
+		assert.Equal(t, signer.PublicKey().Marshal(), s.PublicKey().Marshal())
+		assert.Equal(t, 1, prompts)
+
+		// with the public key alongside, the passphrase is asked for when
+		// signing.
+		assert.NoError(t, ioutil.WriteFile(path+".pub", ssh.MarshalAuthorizedKey(signer.PublicKey()), 0600))
+
+		s, err = loadSigner(path, true)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, prompts)
+
+		_, err = s.Sign(rand.Reader, []byte("data"))
+		assert.NoError(t, err)
+		assert.Equal(t, 2, prompts)
+	})
+}
+
+func TestLoadIdentities(t *testing.T) {
+	key, signer := testKey(t)
+
+	withTempDir(t, func(dir string) {
+		rsaPath := filepath.Join(dir, "id_rsa")
+		block := &pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}
+		assert.NoError(t, ioutil.WriteFile(rsaPath, pem.EncodeToMemory(block), 0600))
+
+		kp, err := GenerateKey(KeyTypeED25519, 0, "")
+		assert.NoError(t, err)
+		edPath := filepath.Join(dir, "id_ed25519")
+		assert.NoError(t, ioutil.WriteFile(edPath, kp.PrivateKey, 0600))
+
+		badPath := filepath.Join(dir, "id_bad")
+		assert.NoError(t, ioutil.WriteFile(badPath, []byte("not a key"), 0600))
+
+		signers, skipped := loadIdentities([]string{rsaPath, edPath, badPath}, false)
+		assert.Len(t, signers, 2)
+		assert.Equal(t, signer.PublicKey().Marshal(), signers[0].PublicKey().Marshal())
+		assert.Equal(t, "ssh-ed25519", signers[1].PublicKey().Type())
+		assert.Len(t, skipped, 1)
+		assert.Contains(t, skipped[0], badPath+" (")
+
+		k := &skippedKeys{}
+		k.set(skipped)
+		err = k.wrap(fmt.Errorf("ssh: handshake failed: ssh: unable to authenticate, attempted methods [none publickey], no supported methods remain"))
+		assert.Contains(t, err.Error(), "skipped keys: "+badPath)
+
+		err = k.wrap(fmt.Errorf("connection reset"))
+		assert.EqualError(t, err, "connection reset")
+	})
+}
+
+func TestDial(t *testing.T) {
+	_, hostKey := testKey(t)
+	userRSA, userKey := testKey(t)
+
+	withTempDir(t, func(dir string) {
+		keyPath := filepath.Join(dir, "id_rsa")
+		b := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(userRSA)})
+		assert.NoError(t, ioutil.WriteFile(keyPath, b, 0600))
+
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.NoError(t, err)
+		defer l.Close()
+
+		config := &ssh.ServerConfig{
+			PublicKeyCallback: func(c ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+				if string(key.Marshal()) == string(userKey.PublicKey().Marshal()) {
+					return nil, nil
+				}
+				return nil, fmt.Errorf("unknown key")
+			},
+		}
+		config.AddHostKey(hostKey)
+
+		go func() {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			_, chans, reqs, err := ssh.NewServerConn(conn, config)
+			if err != nil {
+				return
+			}
+			go ssh.DiscardRequests(reqs)
+			for c := range chans {
+				_ = c.Reject(ssh.Prohibited, "no channels")
+			}
+		}()
+
+		host, port, err := net.SplitHostPort(l.Addr().String())
+		assert.NoError(t, err)
+		p, _ := strconv.Atoi(port)
+
+		r := &Runner{
+			User:    "root",
+			Host:    host,
+			KeyPath: keyPath,
+			Port:    p,
+			Options: Options{KnownHostsFile: filepath.Join(dir, "known_hosts"), AcceptNewHostKeys: true},
+		}
+
+		client, err := dial(r, false)
+		if assert.NoError(t, err) {
+			_ = client.Close()
+		}
+
+		b, err = ioutil.ReadFile(r.KnownHostsFile)
+		assert.NoError(t, err)
+		assert.Equal(t, knownHostsLine(knownHostsName(l.Addr().String()), hostKey.PublicKey()), string(b))
+	})
+}
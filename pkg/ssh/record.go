@@ -0,0 +1,228 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bryanl/doit/pkg/term"
+	"golang.org/x/crypto/ssh"
+)
+
+// Event codes in an asciicast.
+const (
+	castOutput = "o"
+	castInput  = "i"
+	castResize = "r"
+)
+
+// castHeader is the first line of an asciicast v2 file.
+type castHeader struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env,omitempty"`
+}
+
+// recording writes a terminal session in asciinema's asciicast v2 format:
+// a header line followed by a line per event with the seconds since the
+// session started, the event code and its data. Writing to the recording
+// never fails so it can't interrupt the session; the first error is
+// returned by Close.
+type recording struct {
+	mu    sync.Mutex
+	w     *bufio.Writer
+	c     io.Closer
+	start time.Time
+	now   func() time.Time
+	err   error
+}
+
+// createRecording starts recording to a new file.
+func createRecording(path string, width, height int, title string) (*recording, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if os.IsExist(err) {
+		return nil, fmt.Errorf("%s already exists", path)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec, err := newRecording(f, width, height, title, time.Now)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+func newRecording(w io.WriteCloser, width, height int, title string, now func() time.Time) (*recording, error) {
+	rec := &recording{w: bufio.NewWriter(w), c: w, start: now(), now: now}
+
+	b, err := json.Marshal(castHeader{
+		Version:   2,
+		Width:     width,
+		Height:    height,
+		Timestamp: rec.start.Unix(),
+		Title:     title,
+		Env:       map[string]string{"TERM": "xterm", "SHELL": os.Getenv("SHELL")},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := fmt.Fprintf(rec.w, "%s\n", b); err != nil {
+		return nil, err
+	}
+
+	return rec, nil
+}
+
+// event records data with the time since the recording started.
+func (r *recording) event(code, data string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+
+	elapsed := float64(r.now().Sub(r.start)/time.Microsecond) / 1e6
+	b, err := json.Marshal([]interface{}{elapsed, code, data})
+	if err == nil {
+		_, err = fmt.Fprintf(r.w, "%s\n", b)
+	}
+	r.err = err
+}
+
+// resize records the terminal changing size.
+func (r *recording) resize(width, height int) {
+	r.event(castResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+// output returns a writer which records what is written as output.
+func (r *recording) output() io.Writer {
+	return &castStream{rec: r, code: castOutput}
+}
+
+// input returns a writer which records what is written as keystrokes.
+func (r *recording) input() io.Writer {
+	return &castStream{rec: r, code: castInput}
+}
+
+// Close finishes the recording.
+func (r *recording) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.err
+	if ferr := r.w.Flush(); err == nil {
+		err = ferr
+	}
+	if cerr := r.c.Close(); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// closeRecording finishes a recording at the end of a session, when an
+// error can only be reported.
+func closeRecording(rec *recording) {
+	if err := rec.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to save the recording: %v\n", err)
+	}
+}
+
+// castStream records writes as events of one type. A multibyte character
+// split between writes is held back until it is complete, since events
+// are JSON strings.
+type castStream struct {
+	rec     *recording
+	code    string
+	pending []byte
+}
+
+func (s *castStream) Write(p []byte) (int, error) {
+	b := append(s.pending, p...)
+
+	n := len(b)
+	for i := len(b) - 1; i >= 0 && i >= len(b)-utf8.UTFMax; i-- {
+		if utf8.RuneStart(b[i]) {
+			if !utf8.FullRune(b[i:]) {
+				n = i
+			}
+			break
+		}
+	}
+
+	s.pending = append([]byte(nil), b[n:]...)
+	if n > 0 {
+		s.rec.event(s.code, string(b[:n]))
+	}
+
+	return len(p), nil
+}
+
+// terminalSize returns the size of the terminal on fd, or 80x24 if it
+// can't be found.
+func terminalSize(fd uintptr) (width, height int) {
+	ws, err := term.GetWinsize(fd)
+	if err != nil {
+		return 80, 24
+	}
+
+	return int(ws.Width), int(ws.Height)
+}
+
+// watchResize calls fn with the new size of the terminal on fd whenever
+// it changes, until stop is closed.
+func watchResize(fd uintptr, stop <-chan struct{}, fn func(width, height int)) {
+	sig := make(chan os.Signal, 1)
+	notifyResize(sig)
+
+	go func() {
+		defer signal.Stop(sig)
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-sig:
+				fn(terminalSize(fd))
+			}
+		}
+	}()
+}
+
+// windowChange tells the server a session's terminal has changed size.
+func windowChange(session *ssh.Session, width, height int) error {
+	req := struct {
+		Width, Height, PixelWidth, PixelHeight uint32
+	}{uint32(width), uint32(height), 0, 0}
+
+	_, err := session.SendRequest("window-change", false, ssh.Marshal(&req))
+	return err
+}
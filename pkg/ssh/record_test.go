@@ -0,0 +1,82 @@
+/*
+Copyright 2016 The Doctl Authors All rights reserved.
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+    http://www.apache.org/licenses/LICENSE-2.0
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ssh
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecording(t *testing.T) {
+	og := os.Getenv("SHELL")
+	defer os.Setenv("SHELL", og)
+	os.Setenv("SHELL", "/bin/bash")
+
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "session.cast")
+		f, err := os.Create(path)
+		assert.NoError(t, err)
+
+		start := time.Unix(1476835200, 0)
+		now := start
+		clock := func() time.Time { return now }
+
+		rec, err := newRecording(f, 120, 40, "root@10.0.0.1", clock)
+		assert.NoError(t, err)
+
+		out, in := rec.output(), rec.input()
+
+		now = start.Add(250 * time.Millisecond)
+		_, _ = in.Write([]byte("ls\r"))
+
+		// "é" split across writes is recorded whole.
+		now = start.Add(1500 * time.Millisecond)
+		_, _ = out.Write([]byte("caf\xc3"))
+		_, _ = out.Write([]byte("\xa9\r\n"))
+
+		now = start.Add(2*time.Second + 123456*time.Microsecond)
+		rec.resize(100, 30)
+
+		assert.NoError(t, rec.Close())
+
+		b, err := ioutil.ReadFile(path)
+		assert.NoError(t, err)
+
+		expected := []string{
+			`{"version":2,"width":120,"height":40,"timestamp":1476835200,"title":"root@10.0.0.1","env":{"SHELL":"/bin/bash","TERM":"xterm"}}`,
+			`[0.25,"i","ls\r"]`,
+			`[1.5,"o","caf"]`,
+			`[1.5,"o","é\r\n"]`,
+			`[2.123456,"r","100x30"]`,
+			``,
+		}
+		assert.Equal(t, strings.Join(expected, "\n"), string(b))
+	})
+}
+
+func TestCreateRecording_Exists(t *testing.T) {
+	withTempDir(t, func(dir string) {
+		path := filepath.Join(dir, "session.cast")
+		assert.NoError(t, ioutil.WriteFile(path, nil, 0600))
+
+		_, err := createRecording(path, 80, 24, "")
+		assert.EqualError(t, err, path+" already exists")
+	})
+}
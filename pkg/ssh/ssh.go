@@ -14,27 +14,30 @@ limitations under the License.
 package ssh
 
 import (
+	"fmt"
 	"io"
 	"os"
+	"os/signal"
 	"runtime"
+	"strings"
 
 	"github.com/bryanl/doit/pkg/runner"
 	"github.com/bryanl/doit/pkg/term"
+	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
 )
 
-func sshConnect(user string, host string, method ssh.AuthMethod) error {
-	sshc := &ssh.ClientConfig{
-		User: user,
-		Auth: []ssh.AuthMethod{method},
-	}
-	conn, err := ssh.Dial("tcp", host, sshc)
-	if err != nil {
+// sshShell starts the runner's forwards over conn and opens an
+// interactive shell, or waits for an interrupt when running in the
+// background.
+func sshShell(conn *ssh.Client, r *Runner) error {
+	if err := startForwards(conn, r); err != nil {
 		return err
 	}
-	defer func() {
-		_ = conn.Close()
-	}()
+
+	if r.Background {
+		return waitForInterrupt(conn)
+	}
 
 	session, err := conn.NewSession()
 	if err != nil {
@@ -48,10 +51,21 @@ func sshConnect(user string, host string, method ssh.AuthMethod) error {
 	session.Stderr = os.Stderr
 	session.Stdin = os.Stdin
 
-	var (
-		termWidth, termHeight int
-	)
 	fd := os.Stdin.Fd()
+	termWidth, termHeight := terminalSize(fd)
+
+	var rec *recording
+	if r.Record != "" {
+		rec, err = createRecording(r.Record, termWidth, termHeight, r.User+"@"+r.Host)
+		if err != nil {
+			return err
+		}
+		defer closeRecording(rec)
+
+		session.Stdout = io.MultiWriter(os.Stdout, rec.output())
+		session.Stderr = io.MultiWriter(os.Stderr, rec.output())
+		session.Stdin = io.TeeReader(os.Stdin, rec.input())
+	}
 
 	oldState, err := term.MakeRaw(fd)
 	if err != nil {
@@ -61,15 +75,6 @@ func sshConnect(user string, host string, method ssh.AuthMethod) error {
 		_ = term.RestoreTerminal(fd, oldState)
 	}()
 
-	winsize, err := term.GetWinsize(fd)
-	if err != nil {
-		termWidth = 80
-		termHeight = 24
-	} else {
-		termWidth = int(winsize.Width)
-		termHeight = int(winsize.Height)
-	}
-
 	modes := ssh.TerminalModes{
 		ssh.ECHO: 1,
 	}
@@ -84,6 +89,15 @@ func sshConnect(user string, host string, method ssh.AuthMethod) error {
 		return err
 	}
 
+	stop := make(chan struct{})
+	defer close(stop)
+	watchResize(fd, stop, func(width, height int) {
+		_ = windowChange(session, width, height)
+		if rec != nil {
+			rec.resize(width, height)
+		}
+	})
+
 	err = session.Wait()
 	if _, ok := err.(*ssh.ExitError); ok {
 		return nil
@@ -94,21 +108,159 @@ func sshConnect(user string, host string, method ssh.AuthMethod) error {
 	return err
 }
 
+// waitForInterrupt keeps a connection's forwards up until it is closed or
+// the user interrupts.
+func waitForInterrupt(client *ssh.Client) error {
+	fmt.Fprintln(os.Stderr, "forwarding, press ctrl-c to stop")
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	defer signal.Stop(sig)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- client.Wait()
+	}()
+
+	select {
+	case <-sig:
+		return nil
+	case err := <-done:
+		return err
+	}
+}
+
 // Runner runs ssh commands.
 type Runner struct {
 	User    string
 	Host    string
 	KeyPath string
 	Port    int
+	Options
+}
+
+// Options are optional settings for a Runner.
+type Options struct {
+	// LocalForwards and RemoteForwards are port forwards in ssh's -L and
+	// -R form, [bind_address:]port:host:hostport.
+	LocalForwards  []string
+	RemoteForwards []string
+
+	// SOCKSPort is a local port to run a SOCKS5 proxy through the host on.
+	SOCKSPort int
+
+	// Background keeps the forwards up without a shell until interrupted.
+	Background bool
+
+	// KnownHostsFile is checked for the host's key, ~/.ssh/known_hosts if
+	// empty.
+	KnownHostsFile string
+
+	// AcceptNewHostKeys adds the keys of hosts seen for the first time to
+	// known_hosts without asking. Otherwise they are confirmed when
+	// interactive, and refused when not.
+	AcceptNewHostKeys bool
+
+	// IdentityFiles are tried after KeyPath and keys in ssh-agent.
+	IdentityFiles []string
+
+	// Internal uses the internal client instead of the ssh command.
+	Internal bool
+
+	// Jump is a host to connect through, as [user@]host[:port].
+	Jump string
+
+	// Record is a file to record the interactive session to in asciicast
+	// v2 format.
+	Record string
 }
 
 var _ runner.Runner = &Runner{}
 
 // Run ssh.
 func (r *Runner) Run() error {
-	if runtime.GOOS == "windows" {
+	if r.useInternal() {
 		return runInternalSSH(r)
 	}
 
 	return runExternalSSH(r)
 }
+
+// Exec runs cmd on the remote host without a terminal. The command's
+// output is streamed to stdout and stderr, and a non-zero exit status is
+// returned as a *runner.ExitError.
+func (r *Runner) Exec(cmd string) error {
+	if r.useInternal() {
+		return execInternalSSH(r, cmd, true, os.Stdin, os.Stdout, os.Stderr)
+	}
+
+	return execExternalSSH(r, cmd)
+}
+
+// ExecOutput runs cmd on the remote host with the internal client, writing
+// its output to stdout and stderr. Nothing is read from the local stdin, so
+// it is safe to run several at once.
+func (r *Runner) ExecOutput(cmd string, stdout, stderr io.Writer) error {
+	return execInternalSSH(r, cmd, false, nil, stdout, stderr)
+}
+
+// useInternal reports whether to use the internal client. There is no ssh
+// command to use on windows.
+func (r *Runner) useInternal() bool {
+	return r.Internal || runtime.GOOS == "windows"
+}
+
+// SFTP opens an sftp session on the remote host using the internal client
+// without prompting. The returned closer closes the connection and
+// should be called after the sftp client has been closed.
+func (r *Runner) SFTP() (*sftp.Client, io.Closer, error) {
+	client, err := dial(r, false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	sc, err := sftp.NewClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, nil, err
+	}
+
+	return sc, client, nil
+}
+
+// FileExists reports whether path exists on the remote host. It always uses
+// the internal client without prompting and does not allocate a terminal,
+// so it is safe to call from non-interactive code.
+func (r *Runner) FileExists(path string) (bool, error) {
+	client, err := dial(r, false)
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
+
+	session, err := client.NewSession()
+	if err != nil {
+		return false, err
+	}
+	defer func() {
+		_ = session.Close()
+	}()
+
+	err = session.Run("test -e " + shellQuote(path))
+	if err == nil {
+		return true, nil
+	}
+
+	if ee, ok := err.(*ssh.ExitError); ok && ee.ExitStatus() == 1 {
+		return false, nil
+	}
+
+	return false, err
+}
+
+// shellQuote quotes s so it is passed to the remote shell as a single word.
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
@@ -14,17 +14,118 @@ limitations under the License.
 package ssh
 
 import (
+	"io"
 	"os"
 	"os/exec"
 	"strconv"
+	"syscall"
+
+	"github.com/bryanl/doit/pkg/runner"
+	"github.com/bryanl/doit/pkg/term"
+	"github.com/kr/pty"
 )
 
 func runExternalSSH(r *Runner) error {
+	args := externalSSHArgs(r)
+	if r.Background {
+		args = append([]string{"-N"}, args...)
+	}
+
+	cmd := exec.Command("ssh", args...)
+	if r.Record != "" {
+		return runRecordedSSH(r, cmd)
+	}
+
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+
+	return cmd.Run()
+}
+
+// runRecordedSSH runs ssh on a pseudo-terminal so the session passes
+// through, and can be recorded, on its way to and from the real terminal.
+func runRecordedSSH(r *Runner, cmd *exec.Cmd) error {
+	fd := os.Stdin.Fd()
+	width, height := terminalSize(fd)
+
+	rec, err := createRecording(r.Record, width, height, r.User+"@"+r.Host)
+	if err != nil {
+		return err
+	}
+	defer closeRecording(rec)
+
+	ptmx, err := pty.Start(cmd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = ptmx.Close()
+	}()
+
+	_ = term.SetWinsize(ptmx.Fd(), &term.Winsize{Width: uint16(width), Height: uint16(height)})
+
+	oldState, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = term.RestoreTerminal(fd, oldState)
+	}()
+
+	stop := make(chan struct{})
+	defer close(stop)
+	watchResize(fd, stop, func(width, height int) {
+		_ = term.SetWinsize(ptmx.Fd(), &term.Winsize{Width: uint16(width), Height: uint16(height)})
+		rec.resize(width, height)
+	})
+
+	go func() {
+		_, _ = io.Copy(ptmx, io.TeeReader(os.Stdin, rec.input()))
+	}()
+
+	// reading fails once ssh exits and the terminal is closed.
+	_, _ = io.Copy(io.MultiWriter(os.Stdout, rec.output()), ptmx)
+
+	return cmd.Wait()
+}
+
+func execExternalSSH(r *Runner, command string) error {
+	args := append(externalSSHArgs(r), "--", command)
+	cmd := exec.Command("ssh", args...)
+
+	cmd.Stderr = os.Stderr
+	cmd.Stdout = os.Stdout
+	cmd.Stdin = os.Stdin
+
+	err := cmd.Run()
+	if ee, ok := err.(*exec.ExitError); ok {
+		if ws, ok := ee.Sys().(syscall.WaitStatus); ok {
+			return &runner.ExitError{Status: ws.ExitStatus()}
+		}
+	}
+
+	return err
+}
+
+func externalSSHArgs(r *Runner) []string {
 	args := []string{}
 	if r.KeyPath != "" {
 		args = append(args, "-i", r.KeyPath)
 	}
 
+	for _, f := range r.IdentityFiles {
+		args = append(args, "-i", f)
+	}
+
+	if r.KnownHostsFile != "" {
+		args = append(args, "-o", "UserKnownHostsFile="+r.KnownHostsFile)
+	}
+
+	if r.AcceptNewHostKeys {
+		args = append(args, "-o", "StrictHostKeyChecking=accept-new")
+	}
+
 	sshHost := r.Host
 	if r.User != "" {
 		sshHost = r.User + "@" + sshHost
@@ -34,13 +135,21 @@ func runExternalSSH(r *Runner) error {
 		args = append(args, "-p", strconv.Itoa(r.Port))
 	}
 
-	args = append(args, sshHost)
+	for _, f := range r.LocalForwards {
+		args = append(args, "-L", f)
+	}
 
-	cmd := exec.Command("ssh", args...)
+	for _, f := range r.RemoteForwards {
+		args = append(args, "-R", f)
+	}
 
-	cmd.Stderr = os.Stderr
-	cmd.Stdout = os.Stdout
-	cmd.Stdin = os.Stdin
+	if r.SOCKSPort > 0 {
+		args = append(args, "-D", strconv.Itoa(r.SOCKSPort))
+	}
 
-	return cmd.Run()
+	if r.Jump != "" {
+		args = append(args, "-J", r.Jump)
+	}
+
+	return append(args, sshHost)
 }
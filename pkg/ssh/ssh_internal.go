@@ -15,44 +15,172 @@ package ssh
 
 import (
 	"fmt"
-	"io/ioutil"
-	"os"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/bryanl/doit/pkg/runner"
 	"golang.org/x/crypto/ssh"
-	"golang.org/x/crypto/ssh/terminal"
 )
 
 func runInternalSSH(r *Runner) error {
-	sshHost := fmt.Sprintf("%s:%d", r.Host, r.Port)
+	client, err := dial(r, true)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = client.Close()
+	}()
 
-	// Key Auth
-	key, err := ioutil.ReadFile(r.KeyPath)
+	return sshShell(client, r)
+}
+
+func execInternalSSH(r *Runner, cmd string, interactive bool, stdin io.Reader, stdout, stderr io.Writer) error {
+	client, err := dial(r, interactive)
 	if err != nil {
 		return err
 	}
-	privateKey, err := ssh.ParsePrivateKey(key)
+	defer func() {
+		_ = client.Close()
+	}()
+
+	session, err := client.NewSession()
 	if err != nil {
 		return err
 	}
+	defer func() {
+		_ = session.Close()
+	}()
+
+	session.Stdout = stdout
+	session.Stderr = stderr
+	session.Stdin = stdin
+
+	err = session.Run(cmd)
+	if ee, ok := err.(*ssh.ExitError); ok {
+		return &runner.ExitError{Status: ee.ExitStatus()}
+	}
+
+	return err
+}
+
+// dialTimeout is how long to wait for a TCP connection to the ssh server.
+const dialTimeout = 10 * time.Second
 
-	if err := sshConnect(r.User, sshHost, ssh.PublicKeys(privateKey)); err != nil {
-		// Password Auth if Key Auth Fails
-		fd := os.Stdin.Fd()
-		state, err := terminal.MakeRaw(int(fd))
+// dial connects to the runner's host, through its jump host if it has
+// one, checking host keys against known_hosts. When interactive,
+// passphrases, passwords and whether to trust a new host can be asked for.
+func dial(r *Runner, interactive bool) (*ssh.Client, error) {
+	addr := net.JoinHostPort(r.Host, strconv.Itoa(r.Port))
+
+	if r.Jump == "" {
+		conn, err := net.DialTimeout("tcp", addr, dialTimeout)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		defer func() {
-			_ = terminal.Restore(int(fd), state)
-		}()
-		t := terminal.NewTerminal(os.Stdout, ">")
-		password, err := t.ReadPassword("Password: ")
-		if err != nil {
-			return err
+
+		return newClient(r, conn, addr, interactive)
+	}
+
+	jump, err := dial(jumpRunner(r), interactive)
+	if err != nil {
+		return nil, fmt.Errorf("unable to connect to jump host %s: %v", r.Jump, err)
+	}
+
+	conn, err := jump.Dial("tcp", addr)
+	if err != nil {
+		_ = jump.Close()
+		return nil, err
+	}
+
+	client, err := newClient(r, conn, addr, interactive)
+	if err != nil {
+		_ = jump.Close()
+		return nil, err
+	}
+
+	go func() {
+		_ = client.Wait()
+		_ = jump.Close()
+	}()
+
+	return client, nil
+}
+
+// IsConnectionError reports whether err is a failure to reach the host or
+// a connection which was dropped, which can pass if tried again, rather
+// than a problem with keys, authentication or the host's key.
+func IsConnectionError(err error) bool {
+	if _, ok := err.(net.Error); ok {
+		return true
+	}
+
+	// handshake errors only keep the message of the error which caused them.
+	msg := err.Error()
+	for _, s := range []string{"EOF", "connection refused", "connection reset", "i/o timeout", "no route to host"} {
+		if strings.HasSuffix(msg, s) {
+			return true
 		}
-		if err := sshConnect(r.User, sshHost, ssh.Password(string(password))); err != nil {
-			return err
+	}
+
+	return false
+}
+
+// newClient starts an ssh connection to addr over conn.
+func newClient(r *Runner, conn net.Conn, addr string, interactive bool) (*ssh.Client, error) {
+	skipped := &skippedKeys{}
+	methods, agentConn := authMethods(r, interactive, skipped)
+	defer func() {
+		_ = agentConn.Close()
+	}()
+
+	knownHostsFile := r.KnownHostsFile
+	if knownHostsFile == "" {
+		knownHostsFile = defaultKnownHostsFile()
+	}
+
+	hostKeys := &knownHosts{path: knownHostsFile, interactive: interactive, acceptNew: r.AcceptNewHostKeys}
+	sshc := &ssh.ClientConfig{
+		User:            r.User,
+		Auth:            methods,
+		HostKeyCallback: hostKeys.check,
+	}
+
+	c, chans, reqs, err := ssh.NewClientConn(conn, addr, sshc)
+	if err != nil {
+		_ = conn.Close()
+		return nil, skipped.wrap(err)
+	}
+
+	return ssh.NewClient(c, chans, reqs), nil
+}
+
+// jumpRunner is a runner for r's jump host, given as [user@]host[:port].
+// It uses r's user and keys unless the jump host says otherwise.
+func jumpRunner(r *Runner) *Runner {
+	jr := &Runner{
+		User:    r.User,
+		Host:    r.Jump,
+		KeyPath: r.KeyPath,
+		Port:    22,
+		Options: Options{
+			KnownHostsFile:    r.KnownHostsFile,
+			AcceptNewHostKeys: r.AcceptNewHostKeys,
+			IdentityFiles:     r.IdentityFiles,
+		},
+	}
+
+	if i := strings.LastIndex(jr.Host, "@"); i >= 0 {
+		jr.User, jr.Host = jr.Host[:i], jr.Host[i+1:]
+	}
+
+	if host, port, err := net.SplitHostPort(jr.Host); err == nil {
+		if p, err := strconv.Atoi(port); err == nil {
+			jr.Host, jr.Port = host, p
 		}
 	}
-	return err
+
+	return jr
 }
@@ -4,9 +4,20 @@ package units
 
 import (
 	"fmt"
+	"regexp"
+	"strconv"
 	"time"
 )
 
+var (
+	durationRegex = regexp.MustCompile(`^(\d+)([dwy])$`)
+	durationMap   = map[string]time.Duration{
+		"d": 24 * time.Hour,
+		"w": 7 * 24 * time.Hour,
+		"y": 365 * 24 * time.Hour,
+	}
+)
+
 // HumanDuration returns a human-readable approximation of a duration
 // (eg. "About a minute", "4 hours ago", etc.).
 func HumanDuration(d time.Duration) string {
@@ -31,3 +42,24 @@ func HumanDuration(d time.Duration) string {
 	}
 	return fmt.Sprintf("%d years", int(d.Hours())/24/365)
 }
+
+// ParseDuration parses a human-readable duration. Whole numbers of days,
+// weeks or years can be given with the "d", "w" and "y" units (eg. "30d",
+// "2w"), and anything time.ParseDuration accepts is accepted too.
+func ParseDuration(s string) (time.Duration, error) {
+	if matches := durationRegex.FindStringSubmatch(s); len(matches) == 3 {
+		n, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return 0, err
+		}
+
+		return time.Duration(n) * durationMap[matches[2]], nil
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration: '%s'", s)
+	}
+
+	return d, nil
+}
@@ -44,3 +44,28 @@ func TestHumanDuration(t *testing.T) {
 	assertEquals(t, "2 years", HumanDuration(24*month+2*week))
 	assertEquals(t, "3 years", HumanDuration(3*year+2*month))
 }
+
+func TestParseDuration(t *testing.T) {
+	day := 24 * time.Hour
+
+	cases := map[string]time.Duration{
+		"30d":   30 * day,
+		"2w":    14 * day,
+		"1y":    365 * day,
+		"12h":   12 * time.Hour,
+		"90m":   90 * time.Minute,
+		"1h30m": 90 * time.Minute,
+	}
+	for in, expected := range cases {
+		d, err := ParseDuration(in)
+		if err != nil || d != expected {
+			t.Errorf("ParseDuration(\"%s\") -> expected '%v' but got '%v' with error '%v'", in, expected, d, err)
+		}
+	}
+
+	for _, in := range []string{"", "d", "30", "-3d", "3 days", "1.5d"} {
+		if _, err := ParseDuration(in); err == nil {
+			t.Errorf("ParseDuration(\"%s\") -> expected error", in)
+		}
+	}
+}
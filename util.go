@@ -26,6 +26,7 @@ type TokenSource struct {
 // MockRunner is an implemenation of Runner for mocking.
 type MockRunner struct {
 	Err error
+	Cmd string
 }
 
 var _ runner.Runner = &MockRunner{}
@@ -35,6 +36,12 @@ func (tr *MockRunner) Run() error {
 	return tr.Err
 }
 
+// Exec mock runs a command.
+func (tr *MockRunner) Exec(cmd string) error {
+	tr.Cmd = cmd
+	return tr.Err
+}
+
 // Token returns an oauth token.
 func (t *TokenSource) Token() (*oauth2.Token, error) {
 	return &oauth2.Token{
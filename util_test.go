@@ -22,7 +22,9 @@ import (
 
 func TestMockRunner(t *testing.T) {
 	e := fmt.Errorf("an error")
-	mr := MockRunner{e}
+	mr := MockRunner{Err: e}
 
 	assert.Equal(t, e, mr.Run())
+	assert.Equal(t, e, mr.Exec("uptime"))
+	assert.Equal(t, "uptime", mr.Cmd)
 }